@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/n-ulricksen/gbemu/internal/gb"
+)
+
+var (
+	flagListen   string
+	flagConnect  string
+	flagNetwork  string
+	flagDuration time.Duration
+)
+
+func main() {
+	setupFlags()
+	args := flag.Args()
+
+	if len(args) != 1 || (flagListen == "") == (flagConnect == "") {
+		printUsage()
+		os.Exit(2)
+	}
+	romPath := args[0]
+
+	conn, err := connect()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("linked to the other console")
+
+	gameboy, err := gb.New(romPath, false, gb.WithLink(conn))
+	if err != nil {
+		conn.Close()
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	frames := run(gameboy)
+	if err := gameboy.Close(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("ran %s for %d frames\n", romPath, frames)
+}
+
+// connect waits for, or connects to, the other console
+func connect() (net.Conn, error) {
+	if flagListen != "" {
+		fmt.Printf("waiting for the other console on %s %s\n", flagNetwork, flagListen)
+		return gb.ListenLink(flagNetwork, flagListen)
+	}
+	return gb.DialLink(flagNetwork, flagConnect)
+}
+
+// run runs the console for the emulated duration given by the -d flag, or
+// until interrupted, and returns the number of frames run
+func run(gameboy *gb.GameBoy) int {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	frame := time.Second * gb.DOTS_PER_FRAME / gb.CPU_FREQUENCY
+
+	gameboy.PowerOn()
+	frames := 0
+	for flagDuration == 0 || time.Duration(frames)*frame < flagDuration {
+		select {
+		case <-interrupt:
+			return frames
+		default:
+		}
+		gameboy.RunFrame()
+		frames++
+	}
+	return frames
+}
+
+func setupFlags() {
+	flag.StringVar(&flagListen, "listen", "",
+		"Address to wait for the other console on, e.g. /tmp/gbemu.sock or 127.0.0.1:7777")
+	flag.StringVar(&flagConnect, "connect", "",
+		"Address of the other console, waiting with -listen")
+	flag.StringVar(&flagNetwork, "network", "tcp",
+		"Network of the link addresses: tcp or unix")
+	flag.DurationVar(&flagDuration, "d", 0,
+		"Duration of emulated time to run for (default: until interrupted)")
+	flag.Parse()
+}
+
+func printUsage() {
+	fmt.Println("usage: ./gblink (-listen address | -connect address) [-network tcp|unix] [-d 60s] <rom.gb>")
+}
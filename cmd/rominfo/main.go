@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/n-ulricksen/gbemu/internal/gb"
+)
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) == 0 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, romPath := range args {
+		cart, err := gb.LoadCartridge(romPath)
+		if err != nil {
+			fmt.Println(err)
+			failed = true
+			continue
+		}
+
+		fmt.Printf("%s:\n", romPath)
+		fmt.Println(cart)
+		if err := cart.VerifyGlobalChecksum(); err != nil {
+			fmt.Printf("warning: %s\n\n", err)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println("usage: ./rominfo <rom.gb> [rom.gb ...]")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n-ulricksen/gbemu/internal/gb"
+)
+
+var (
+	flagOutput   string
+	flagDuration time.Duration
+	flagRate     int
+	flagChannels string
+	flagSong     int
+)
+
+func main() {
+	setupFlags()
+	args := flag.Args()
+
+	if len(args) != 1 {
+		printUsage()
+		os.Exit(2)
+	}
+	romPath := args[0]
+
+	var opts []gb.Option
+	if flagChannels != "" {
+		channels, err := parseChannels(flagChannels)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		opts = append(opts, gb.WithAudioChannels(channels...))
+	}
+
+	output := flagOutput
+	if output == "" {
+		output = strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".wav"
+	}
+
+	record := recordROM
+	if strings.EqualFold(filepath.Ext(romPath), ".gbs") {
+		record = recordGBS
+	}
+	if err := record(romPath, output, opts); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("recorded %s of %s to %s\n", flagDuration, romPath, output)
+}
+
+// recordROM records the audio of the game ROM at the given path, writing the
+// game's save file once done
+func recordROM(romPath, output string, opts []gb.Option) (err error) {
+	gameboy, err := gb.New(romPath, false, opts...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := gameboy.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return gameboy.RecordWAV(output, flagDuration, flagRate)
+}
+
+// recordGBS records a song from the GBS file at the given path, by default
+// the file's first song
+func recordGBS(gbsPath, output string, opts []gb.Option) error {
+	gbs, err := gb.LoadGBS(gbsPath)
+	if err != nil {
+		return err
+	}
+	fmt.Print(gbs)
+
+	song := flagSong
+	if song == 0 {
+		song = gbs.Header.FirstSong
+	}
+	return gb.NewGBSPlayer(gbs, opts...).RecordWAV(output, song, flagDuration, flagRate)
+}
+
+// parseChannels parses a comma separated list of sound channel numbers
+func parseChannels(list string) ([]int, error) {
+	var channels []int
+	for _, field := range strings.Split(list, ",") {
+		ch, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || ch < 1 || ch > 4 {
+			return nil, fmt.Errorf("invalid sound channel %q, expected 1-4", field)
+		}
+		channels = append(channels, ch)
+	}
+	return channels, nil
+}
+
+func setupFlags() {
+	flag.StringVar(&flagOutput, "o", "",
+		"Output WAV file (default: the ROM path with a .wav extension)")
+	flag.DurationVar(&flagDuration, "d", 60*time.Second,
+		"Duration of emulated time to record")
+	flag.IntVar(&flagRate, "rate", gb.DEFAULT_SAMPLE_RATE,
+		"Sample rate, in Hz")
+	flag.StringVar(&flagChannels, "channels", "",
+		"Comma separated sound channels to record, e.g. 3 or 1,2 (default: all)")
+	flag.IntVar(&flagSong, "song", 0,
+		"Song to record from a .gbs file, numbered from 1 (default: the file's first song)")
+	flag.Parse()
+}
+
+func printUsage() {
+	fmt.Println("usage: ./wavrecord [-o out.wav] [-d 60s] [-rate 44100] [-channels 1,2,3,4] [-song n] <rom.gb | music.gbs>")
+}
@@ -0,0 +1,307 @@
+package gb
+
+import "math"
+
+// NR52 bits
+const (
+	NR52_POWER = 1 << 7 // APU on/off, bits 0-3 report which channels are on
+)
+
+// NRx4 bits, shared by all channels
+const (
+	NRX4_LENGTH_ENABLE = 1 << 6
+	NRX4_TRIGGER       = 1 << 7
+)
+
+// DEFAULT_SAMPLE_RATE is the sample rate used when none is given, in Hz
+const DEFAULT_SAMPLE_RATE = 44100
+
+// apuReadMasks holds the bits of each register from NR10 to NR51 which always
+// read as 1, as they are unused or write-only
+var apuReadMasks = [IO_NR52 - IO_NR10]byte{
+	0x80, 0x3F, 0x00, 0xFF, 0xBF, // NR10-NR14
+	0xFF, 0x3F, 0x00, 0xFF, 0xBF, // NR20-NR24
+	0x7F, 0xFF, 0x9F, 0xFF, 0xBF, // NR30-NR34
+	0xFF, 0xFF, 0x00, 0x00, 0xBF, // NR40-NR44
+	0x00, 0x00, // NR50-NR51
+}
+
+// AudioSink receives the sound produced by the APU
+type AudioSink interface {
+	// WriteSample is called with each stereo sample, at the sample rate the
+	// sink was attached with. Samples range from -1 to 1.
+	WriteSample(left, right float32)
+}
+
+// apu is the Game Boy's audio processing unit. It has two square wave
+// channels, the first with a frequency sweep, a channel playing the 4-bit
+// samples in wave RAM, and a noise channel. A frame sequencer, stepped at
+// 512 Hz by the timer's divider, clocks the channels' length counters,
+// envelopes and sweep. Each channel's DAC output is panned by NR51 and scaled
+// by the master volume in NR50.
+//
+// reference: https://gbdev.io/pandocs/Audio.html
+type apu struct {
+	power bool
+	regs  [IO_NR52 - IO_NR10]byte // NR10-NR51, as last written
+
+	ch1   square
+	sweep sweep
+	ch2   square
+	ch3   wave
+	ch4   noise
+
+	fsStep int // next frame sequencer step, 0-7
+
+	channels    byte // channels mixed into the output, bit 0 for channel 1
+	sink        AudioSink
+	sampleRate  int
+	sampleClock int     // T-cycles times the sample rate, since the last sample
+	charge      float32 // high-pass filter capacitor charge factor per sample
+	capacitor   [2]float32
+}
+
+// newAPU returns a powered-on APU with no audio sink
+func newAPU() *apu {
+	a := &apu{power: true, channels: 0x0F}
+	a.ch1.length.max = 64
+	a.ch2.length.max = 64
+	a.ch3.length.max = 256
+	a.ch4.length.max = 64
+	return a
+}
+
+// setSink sets the sink receiving samples at the given rate, in Hz
+func (a *apu) setSink(sink AudioSink, sampleRate int) {
+	if sampleRate <= 0 {
+		sampleRate = DEFAULT_SAMPLE_RATE
+	}
+	a.sink = sink
+	a.sampleRate = sampleRate
+	a.sampleClock = 0
+	// The output capacitor keeps 0.999958 of its charge every T-cycle
+	a.charge = float32(math.Pow(0.999958, float64(CPU_FREQUENCY)/float64(sampleRate)))
+}
+
+// tick advances the APU by the given number of machine cycles
+func (a *apu) tick(cycles int) {
+	dots := cycles * 4
+	if a.power {
+		a.ch1.step(dots)
+		a.ch2.step(dots)
+		a.ch3.step(dots)
+		a.ch4.step(dots)
+	}
+
+	if a.sink == nil {
+		return
+	}
+	a.sampleClock += dots * a.sampleRate
+	for a.sampleClock >= CPU_FREQUENCY {
+		a.sampleClock -= CPU_FREQUENCY
+		a.sink.WriteSample(a.sample())
+	}
+}
+
+// clockDIV is called by the timer on each falling edge of DIV bit 4, at
+// 512 Hz, stepping the frame sequencer while powered on
+func (a *apu) clockDIV() {
+	if a.power {
+		a.stepFrameSequencer()
+	}
+}
+
+// stepFrameSequencer clocks the length counters on even steps, the sweep on
+// steps 2 and 6, and the envelopes on step 7
+func (a *apu) stepFrameSequencer() {
+	if a.fsStep%2 == 0 {
+		a.ch1.clockLength()
+		a.ch2.clockLength()
+		a.ch3.clockLength()
+		a.ch4.clockLength()
+	}
+	if a.fsStep == 2 || a.fsStep == 6 {
+		a.sweep.clock(&a.ch1)
+	}
+	if a.fsStep == 7 {
+		a.ch1.envelope.clock()
+		a.ch2.envelope.clock()
+		a.ch4.envelope.clock()
+	}
+	a.fsStep = (a.fsStep + 1) % 8
+}
+
+// lengthClockNext returns whether the frame sequencer's next step clocks the
+// length counters
+func (a *apu) lengthClockNext() bool {
+	return a.fsStep%2 == 0
+}
+
+// sample returns the current stereo output, after the high-pass filter
+func (a *apu) sample() (left, right float32) {
+	left, right = a.mix()
+	left = a.highPass(0, left)
+	right = a.highPass(1, right)
+	return left, right
+}
+
+// mix returns the sum of the channels' DAC outputs panned to each side, scaled
+// by the master volume
+func (a *apu) mix() (left, right float32) {
+	if !a.power {
+		return 0, 0
+	}
+
+	channels := [4]*channel{&a.ch1.channel, &a.ch2.channel, &a.ch3.channel, &a.ch4.channel}
+	outputs := [4]byte{a.ch1.output(), a.ch2.output(), a.ch3.output(), a.ch4.output()}
+	nr51 := a.regs[IO_NR51-IO_NR10]
+	for i, ch := range channels {
+		if !ch.dac || a.channels&(1<<i) == 0 {
+			continue
+		}
+		// The DAC maps digital 0 to 15 onto analog 1 to -1
+		v := 1 - float32(outputs[i])/7.5
+		if nr51&(1<<(i+4)) > 0 {
+			left += v
+		}
+		if nr51&(1<<i) > 0 {
+			right += v
+		}
+	}
+
+	nr50 := a.regs[IO_NR50-IO_NR10]
+	left *= float32(nr50>>4&0x07+1) / 8 / 4
+	right *= float32(nr50&0x07+1) / 8 / 4
+	return left, right
+}
+
+// highPass removes the DC offset from one side's output, as the capacitor on
+// the console's audio output does
+func (a *apu) highPass(side int, in float32) float32 {
+	out := in - a.capacitor[side]
+	a.capacitor[side] = in - out*a.charge
+	return out
+}
+
+// setPower powers the APU on or off. Powering off clears every register, and
+// they ignore writes until powered back on. Wave RAM is unaffected.
+func (a *apu) setPower(on bool) {
+	if on == a.power {
+		return
+	}
+	a.power = on
+
+	if !on {
+		a.regs = [IO_NR52 - IO_NR10]byte{}
+		a.ch1 = square{channel: a.ch1.powerOff()}
+		a.ch2 = square{channel: a.ch2.powerOff()}
+		a.ch3 = wave{channel: a.ch3.powerOff(), ram: a.ch3.ram}
+		a.ch4 = noise{channel: a.ch4.powerOff()}
+		a.sweep = sweep{}
+		return
+	}
+	a.fsStep = 0
+}
+
+// read reads from the sound register or wave RAM at the given address
+func (a *apu) read(addr uint16) byte {
+	switch {
+	case addr == IO_NR52:
+		status := byte(0x70)
+		if a.power {
+			status |= NR52_POWER
+		}
+		for i, on := range []bool{a.ch1.on, a.ch2.on, a.ch3.on, a.ch4.on} {
+			if on {
+				status |= 1 << i
+			}
+		}
+		return status
+	case addr >= IO_NR10 && addr < IO_NR52:
+		return a.regs[addr-IO_NR10] | apuReadMasks[addr-IO_NR10]
+	case addr >= WAVE_RAM_START && addr <= WAVE_RAM_END:
+		return a.ch3.readRAM(addr)
+	}
+	return 0xFF
+}
+
+// write writes to the sound register or wave RAM at the given address
+func (a *apu) write(addr uint16, data byte) {
+	switch {
+	case addr == IO_NR52:
+		a.setPower(data&NR52_POWER > 0)
+		return
+	case addr >= WAVE_RAM_START && addr <= WAVE_RAM_END:
+		a.ch3.writeRAM(addr, data)
+		return
+	case addr < IO_NR10 || addr > IO_NR52:
+		return
+	}
+
+	if !a.power {
+		// Only the length counters can be loaded while powered off
+		switch addr {
+		case IO_NR11:
+			a.ch1.length.load(int(data & 0x3F))
+		case IO_NR21:
+			a.ch2.length.load(int(data & 0x3F))
+		case IO_NR31:
+			a.ch3.length.load(int(data))
+		case IO_NR41:
+			a.ch4.length.load(int(data & 0x3F))
+		}
+		return
+	}
+
+	a.regs[addr-IO_NR10] = data
+	switch addr {
+	case IO_NR10:
+		a.sweep.write(&a.ch1, data)
+	case IO_NR11:
+		a.ch1.writeDutyLength(data)
+	case IO_NR12:
+		a.ch1.writeEnvelope(data)
+	case IO_NR13:
+		a.ch1.period = a.ch1.period&0x700 | uint16(data)
+	case IO_NR14:
+		a.ch1.period = a.ch1.period&0xFF | uint16(data&0x07)<<8
+		if a.ch1.writeControl(data, a.lengthClockNext()) {
+			a.ch1.trigger()
+			a.sweep.trigger(&a.ch1)
+		}
+	case IO_NR21:
+		a.ch2.writeDutyLength(data)
+	case IO_NR22:
+		a.ch2.writeEnvelope(data)
+	case IO_NR23:
+		a.ch2.period = a.ch2.period&0x700 | uint16(data)
+	case IO_NR24:
+		a.ch2.period = a.ch2.period&0xFF | uint16(data&0x07)<<8
+		if a.ch2.writeControl(data, a.lengthClockNext()) {
+			a.ch2.trigger()
+		}
+	case IO_NR30:
+		a.ch3.setDAC(data&0x80 > 0)
+	case IO_NR31:
+		a.ch3.length.load(int(data))
+	case IO_NR32:
+		a.ch3.level = data >> 5 & 0x03
+	case IO_NR33:
+		a.ch3.period = a.ch3.period&0x700 | uint16(data)
+	case IO_NR34:
+		a.ch3.period = a.ch3.period&0xFF | uint16(data&0x07)<<8
+		if a.ch3.writeControl(data, a.lengthClockNext()) {
+			a.ch3.trigger()
+		}
+	case IO_NR41:
+		a.ch4.length.load(int(data & 0x3F))
+	case IO_NR42:
+		a.ch4.writeEnvelope(data)
+	case IO_NR43:
+		a.ch4.nr43 = data
+	case IO_NR44:
+		if a.ch4.writeControl(data, a.lengthClockNext()) {
+			a.ch4.trigger()
+		}
+	}
+}
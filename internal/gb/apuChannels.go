@@ -0,0 +1,377 @@
+package gb
+
+// dutyPatterns holds the 8-step waveform of each square channel duty cycle,
+// played from the most significant bit
+var dutyPatterns = [4]byte{
+	0b00000001, // 12.5%
+	0b10000001, // 25%
+	0b10000111, // 50%
+	0b01111110, // 75%
+}
+
+// noiseDivisors maps NR43's clock divider to the noise channel's base period,
+// in T-cycles
+var noiseDivisors = [8]int{8, 16, 32, 48, 64, 80, 96, 112}
+
+// lengthCounter silences its channel once a number of frame sequencer length
+// clocks have elapsed, if enabled
+type lengthCounter struct {
+	max     int // 64, or 256 for the wave channel
+	counter int
+	enabled bool
+}
+
+// load sets the counter from the length written to NRx1
+func (l *lengthCounter) load(length int) {
+	l.counter = l.max - length
+}
+
+// clock decrements the counter if enabled, returning whether it expired
+func (l *lengthCounter) clock() bool {
+	if !l.enabled || l.counter == 0 {
+		return false
+	}
+	l.counter--
+	return l.counter == 0
+}
+
+// channel holds the state shared by all sound channels
+type channel struct {
+	on     bool // channel enabled, as reported by NR52
+	dac    bool // DAC enabled, the channel is silent and can't be enabled if not
+	length lengthCounter
+}
+
+// setDAC turns the channel's DAC on or off. Turning it off disables the
+// channel.
+func (c *channel) setDAC(on bool) {
+	c.dac = on
+	if !on {
+		c.on = false
+	}
+}
+
+// clockLength clocks the length counter, disabling the channel when it expires
+func (c *channel) clockLength() {
+	if c.length.clock() {
+		c.on = false
+	}
+}
+
+// writeControl handles the length enable and trigger bits of a write to NRx4,
+// given whether the frame sequencer's next step clocks the length counters,
+// and returns whether the channel was triggered.
+//
+// Enabling the length counter while the next step doesn't clock it clocks it
+// once, as does reloading an expired counter on trigger in the same case.
+func (c *channel) writeControl(data byte, lengthClockNext bool) bool {
+	wasEnabled := c.length.enabled
+	c.length.enabled = data&NRX4_LENGTH_ENABLE > 0
+	trigger := data&NRX4_TRIGGER > 0
+
+	if !lengthClockNext && !wasEnabled && c.length.clock() && !trigger {
+		c.on = false
+	}
+
+	if trigger {
+		if c.length.counter == 0 {
+			c.length.counter = c.length.max
+			if !lengthClockNext {
+				c.length.clock()
+			}
+		}
+		c.on = c.dac
+	}
+	return trigger
+}
+
+// powerOff returns the channel's state after the APU is powered off. Only the
+// length counter's value is kept.
+func (c *channel) powerOff() channel {
+	return channel{length: lengthCounter{max: c.length.max, counter: c.length.counter}}
+}
+
+// envelope periodically raises or lowers a channel's volume
+type envelope struct {
+	nrx2 byte // NRx2: initial volume, direction and period
+
+	volume   byte
+	period   int // clocks between volume changes, 0 to disable
+	increase bool
+	timer    int
+}
+
+// trigger restarts the envelope from the settings in NRx2
+func (e *envelope) trigger() {
+	e.volume = e.nrx2 >> 4
+	e.increase = e.nrx2&0x08 > 0
+	e.period = int(e.nrx2 & 0x07)
+	e.timer = e.period
+}
+
+// clock counts down to the next volume change, stopping at 0 or 15
+func (e *envelope) clock() {
+	if e.period == 0 {
+		return
+	}
+	e.timer--
+	if e.timer > 0 {
+		return
+	}
+	e.timer = e.period
+
+	if e.increase && e.volume < 15 {
+		e.volume++
+	} else if !e.increase && e.volume > 0 {
+		e.volume--
+	}
+}
+
+// square is a square wave channel, with one of 4 duty cycles
+type square struct {
+	channel
+	envelope envelope
+
+	duty     byte   // NRx1 bits 6-7, see dutyPatterns
+	dutyStep byte   // position in the duty pattern, 0-7
+	period   uint16 // 11-bit period value, from NRx3 and NRx4
+	timer    int    // T-cycles until the next duty step
+}
+
+// writeDutyLength handles a write to NRx1
+func (s *square) writeDutyLength(data byte) {
+	s.duty = data >> 6
+	s.length.load(int(data & 0x3F))
+}
+
+// writeEnvelope handles a write to NRx2
+func (s *square) writeEnvelope(data byte) {
+	s.envelope.nrx2 = data
+	s.setDAC(data&0xF8 > 0)
+}
+
+// trigger restarts the channel
+func (s *square) trigger() {
+	s.timer = (2048 - int(s.period)) * 4
+	s.envelope.trigger()
+}
+
+// step advances the duty position by the given number of T-cycles
+func (s *square) step(dots int) {
+	s.timer -= dots
+	for s.timer <= 0 {
+		s.timer += (2048 - int(s.period)) * 4
+		s.dutyStep = (s.dutyStep + 1) & 0x07
+	}
+}
+
+// output returns the channel's current digital output, from 0 to 15
+func (s *square) output() byte {
+	if !s.on || dutyPatterns[s.duty]>>(7-s.dutyStep)&1 == 0 {
+		return 0
+	}
+	return s.envelope.volume
+}
+
+// sweep periodically raises or lowers channel 1's period
+type sweep struct {
+	nr10 byte // NR10: pace, direction and step
+
+	shadow  uint16 // period the next change is calculated from
+	timer   int
+	enabled bool
+	negated bool // a decrease was calculated since the last trigger
+}
+
+// pace returns the number of sweep clocks between period changes
+func (s *sweep) pace() int {
+	return int(s.nr10 >> 4 & 0x07)
+}
+
+// shift returns the step of each period change
+func (s *sweep) shift() byte {
+	return s.nr10 & 0x07
+}
+
+// reload restarts the sweep timer. A pace of 0 counts as 8.
+func (s *sweep) reload() {
+	s.timer = s.pace()
+	if s.timer == 0 {
+		s.timer = 8
+	}
+}
+
+// write handles a write to NR10. Switching from decreasing to increasing after
+// a decrease has been calculated disables the channel.
+func (s *sweep) write(ch *square, data byte) {
+	if s.negated && data&0x08 == 0 {
+		ch.on = false
+	}
+	s.nr10 = data
+}
+
+// trigger restarts the sweep from the channel's period, immediately checking
+// for overflow if the step is not 0
+func (s *sweep) trigger(ch *square) {
+	s.shadow = ch.period
+	s.reload()
+	s.enabled = s.pace() != 0 || s.shift() != 0
+	s.negated = false
+	if s.shift() != 0 {
+		s.calculate(ch)
+	}
+}
+
+// calculate returns the next period, disabling the channel if it overflows
+// 11 bits
+func (s *sweep) calculate(ch *square) uint16 {
+	delta := s.shadow >> s.shift()
+	period := s.shadow + delta
+	if s.nr10&0x08 > 0 {
+		period = s.shadow - delta
+		s.negated = true
+	}
+	if period > 0x7FF {
+		ch.on = false
+	}
+	return period
+}
+
+// clock counts down to the next period change. The new period is checked for
+// overflow a second time after it is applied.
+func (s *sweep) clock(ch *square) {
+	s.timer--
+	if s.timer > 0 {
+		return
+	}
+	s.reload()
+
+	if !s.enabled || s.pace() == 0 {
+		return
+	}
+	period := s.calculate(ch)
+	if period <= 0x7FF && s.shift() != 0 {
+		s.shadow = period
+		ch.period = period
+		s.calculate(ch)
+	}
+}
+
+// wave is the channel playing the 32 4-bit samples stored in wave RAM
+type wave struct {
+	channel
+	ram [WAVE_RAM_END - WAVE_RAM_START + 1]byte
+
+	level    byte   // NR32 bits 5-6: mute, 100%, 50% or 25%
+	period   uint16 // 11-bit period value, from NR33 and NR34
+	timer    int    // T-cycles until the next sample
+	position byte   // sample being played, 0-31
+	sample   byte   // last sample read from wave RAM
+}
+
+// trigger restarts playback from the first sample. The sample buffer is not
+// refilled until the position next advances.
+func (w *wave) trigger() {
+	w.timer = (2048 - int(w.period)) * 2
+	w.position = 0
+}
+
+// step advances the sample position by the given number of T-cycles
+func (w *wave) step(dots int) {
+	if !w.on {
+		return
+	}
+	w.timer -= dots
+	for w.timer <= 0 {
+		w.timer += (2048 - int(w.period)) * 2
+		w.position = (w.position + 1) & 0x1F
+		w.sample = w.ram[w.position/2]
+		if w.position%2 == 0 {
+			w.sample >>= 4
+		}
+		w.sample &= 0x0F
+	}
+}
+
+// output returns the channel's current digital output, from 0 to 15
+func (w *wave) output() byte {
+	if !w.on || w.level == 0 {
+		return 0
+	}
+	return w.sample >> (w.level - 1)
+}
+
+// readRAM reads from wave RAM. While the channel is on, every address reads
+// the byte being played.
+func (w *wave) readRAM(addr uint16) byte {
+	if w.on {
+		return w.ram[w.position/2]
+	}
+	return w.ram[addr-WAVE_RAM_START]
+}
+
+// writeRAM writes to wave RAM. While the channel is on, writes go to the byte
+// being played.
+func (w *wave) writeRAM(addr uint16, data byte) {
+	if w.on {
+		w.ram[w.position/2] = data
+		return
+	}
+	w.ram[addr-WAVE_RAM_START] = data
+}
+
+// noise is the channel outputting pseudo-random bits from a linear feedback
+// shift register
+type noise struct {
+	channel
+	envelope envelope
+
+	nr43  byte   // NR43: clock shift, LFSR width and clock divider
+	lfsr  uint16 // 15-bit linear feedback shift register
+	timer int    // T-cycles until the next LFSR shift
+}
+
+// writeEnvelope handles a write to NR42
+func (n *noise) writeEnvelope(data byte) {
+	n.envelope.nrx2 = data
+	n.setDAC(data&0xF8 > 0)
+}
+
+// period returns the number of T-cycles between LFSR shifts
+func (n *noise) period() int {
+	return noiseDivisors[n.nr43&0x07] << (n.nr43 >> 4)
+}
+
+// trigger restarts the channel with every LFSR bit set
+func (n *noise) trigger() {
+	n.lfsr = 0x7FFF
+	n.timer = n.period()
+	n.envelope.trigger()
+}
+
+// step shifts the LFSR once per period elapsed in the given number of
+// T-cycles. Clock shifts of 14 and 15 stop the LFSR.
+func (n *noise) step(dots int) {
+	if !n.on || n.nr43>>4 >= 14 {
+		return
+	}
+	n.timer -= dots
+	for n.timer <= 0 {
+		n.timer += n.period()
+
+		bit := (n.lfsr ^ n.lfsr>>1) & 1
+		n.lfsr = n.lfsr>>1 | bit<<14
+		if n.nr43&0x08 > 0 {
+			// 7-bit mode also feeds back into bit 6
+			n.lfsr = n.lfsr&^(1<<6) | bit<<6
+		}
+	}
+}
+
+// output returns the channel's current digital output, from 0 to 15
+func (n *noise) output() byte {
+	if !n.on || n.lfsr&1 > 0 {
+		return 0
+	}
+	return n.envelope.volume
+}
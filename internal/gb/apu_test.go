@@ -0,0 +1,158 @@
+package gb
+
+import "testing"
+
+// sampleRecorder is an AudioSink keeping every sample written to it
+type sampleRecorder struct {
+	left, right []float32
+}
+
+func (r *sampleRecorder) WriteSample(left, right float32) {
+	r.left = append(r.left, left)
+	r.right = append(r.right, right)
+}
+
+// channelOn returns whether NR52 reports the given channel (1-4) as on
+func channelOn(gb *GameBoy, ch int) bool {
+	return gb.cpuRead(IO_NR52)&(1<<(ch-1)) > 0
+}
+
+// TestAPURegisterReads checks the bits of each sound register which read as 1
+func TestAPURegisterReads(t *testing.T) {
+	gb := newTestGameBoy(t)
+
+	for addr := uint16(IO_NR10); addr < IO_NR52; addr++ {
+		gb.cpuWrite(addr, 0x00)
+	}
+	for addr := uint16(IO_NR10); addr < IO_NR52; addr++ {
+		if got, want := gb.cpuRead(addr), apuReadMasks[addr-IO_NR10]; got != want {
+			t.Errorf("read %#04x = %#02x, want %#02x", addr, got, want)
+		}
+	}
+	for addr := uint16(IO_NR52 + 1); addr < WAVE_RAM_START; addr++ {
+		if got := gb.cpuRead(addr); got != 0xFF {
+			t.Errorf("read unused %#04x = %#02x, want 0xff", addr, got)
+		}
+	}
+	// Channels are disabled along with their DAC
+	if got := gb.cpuRead(IO_NR52); got != 0xF0 {
+		t.Errorf("NR52 = %#02x, want 0xf0", got)
+	}
+}
+
+// TestAPULength checks that a channel with its length counter enabled is
+// disabled once the length expires
+func TestAPULength(t *testing.T) {
+	gb := newTestGameBoy(t)
+
+	gb.cpuWrite(IO_NR22, 0xF0)
+	gb.cpuWrite(IO_NR21, 62) // 2 length clocks
+	gb.cpuWrite(IO_NR24, NRX4_TRIGGER|NRX4_LENGTH_ENABLE)
+	if !channelOn(gb, 2) {
+		t.Fatal("channel 2 not on after trigger")
+	}
+
+	// Length counters are clocked at 256 Hz
+	gb.tick(3 * CYCLES_PER_SECOND / 256)
+	if channelOn(gb, 2) {
+		t.Error("channel 2 still on after its length expired")
+	}
+
+	// Without the length counter enabled, the channel plays indefinitely
+	gb.cpuWrite(IO_NR21, 62)
+	gb.cpuWrite(IO_NR24, NRX4_TRIGGER)
+	gb.tick(CYCLES_PER_SECOND)
+	if !channelOn(gb, 2) {
+		t.Error("channel 2 disabled with length counter disabled")
+	}
+}
+
+// TestAPUSweepOverflow checks that channel 1 is disabled when its sweep would
+// raise the period past 11 bits
+func TestAPUSweepOverflow(t *testing.T) {
+	gb := newTestGameBoy(t)
+	gb.cpuWrite(IO_NR12, 0xF0)
+	gb.cpuWrite(IO_NR10, 0x11) // Pace 1, increasing by period/2
+
+	// Overflow is checked on trigger
+	gb.cpuWrite(IO_NR13, 0x00)
+	gb.cpuWrite(IO_NR14, NRX4_TRIGGER|0x07)
+	if channelOn(gb, 1) {
+		t.Error("channel 1 on after triggering with an overflowing sweep")
+	}
+
+	// 0x500 sweeps to 0x780, and the following 0xB40 overflows
+	gb.cpuWrite(IO_NR14, NRX4_TRIGGER|0x05)
+	if !channelOn(gb, 1) {
+		t.Fatal("channel 1 not on after trigger")
+	}
+	// The sweep is clocked at 128 Hz
+	gb.tick(2 * CYCLES_PER_SECOND / 128)
+	if channelOn(gb, 1) {
+		t.Error("channel 1 still on after its sweep overflowed")
+	}
+}
+
+// TestAPUPowerOff checks that powering off the APU clears its registers and
+// ignores writes, but keeps wave RAM
+func TestAPUPowerOff(t *testing.T) {
+	gb := newTestGameBoy(t)
+	gb.cpuWrite(IO_NR50, 0x77)
+	gb.cpuWrite(WAVE_RAM_START, 0x12)
+
+	gb.cpuWrite(IO_NR52, 0x00)
+	if got := gb.cpuRead(IO_NR52); got != 0x70 {
+		t.Errorf("NR52 = %#02x after power off, want 0x70", got)
+	}
+	if got := gb.cpuRead(IO_NR50); got != 0x00 {
+		t.Errorf("NR50 = %#02x after power off, want 0x00", got)
+	}
+	gb.cpuWrite(IO_NR50, 0x77)
+	if got := gb.cpuRead(IO_NR50); got != 0x00 {
+		t.Errorf("NR50 = %#02x after write while off, want 0x00", got)
+	}
+	if got := gb.cpuRead(WAVE_RAM_START); got != 0x12 {
+		t.Errorf("wave RAM = %#02x after power off, want 0x12", got)
+	}
+
+	gb.cpuWrite(IO_NR52, NR52_POWER)
+	gb.cpuWrite(IO_NR50, 0x77)
+	if got := gb.cpuRead(IO_NR50); got != 0x77 {
+		t.Errorf("NR50 = %#02x after power on, want 0x77", got)
+	}
+}
+
+// TestAPUSamples checks that samples are produced at the sink's rate, and
+// panned by NR51
+func TestAPUSamples(t *testing.T) {
+	const rate = 48000
+	sink := new(sampleRecorder)
+	gb := newTestGameBoyFromRom(t, newTestRom(), WithAudio(sink, rate))
+
+	gb.cpuWrite(IO_NR12, 0x00) // Silence channel 1
+	gb.cpuWrite(IO_NR51, 0x20) // Channel 2 left only
+	gb.cpuWrite(IO_NR21, 0x80) // 50% duty
+	gb.cpuWrite(IO_NR22, 0xF0)
+	gb.cpuWrite(IO_NR23, 0x00)
+	gb.cpuWrite(IO_NR24, NRX4_TRIGGER|0x07)
+	sink.left, sink.right = nil, nil
+
+	for i := 0; i < CYCLES_PER_SECOND; i += 4 {
+		gb.tick(4)
+	}
+	if len(sink.left) != rate {
+		t.Errorf("got %d samples in 1 second, want %d", len(sink.left), rate)
+	}
+
+	var high, low bool
+	for i := range sink.left {
+		if sink.right[i] != 0 {
+			t.Fatalf("right sample %d = %v, want 0", i, sink.right[i])
+		}
+		high = high || sink.left[i] > 0.1
+		low = low || sink.left[i] < -0.1
+	}
+	if !high || !low {
+		t.Error("left channel does not play a square wave")
+	}
+}
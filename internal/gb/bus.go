@@ -6,19 +6,84 @@ func (gb *GameBoy) attachCPU(cpu *CPU) {
 	gb.Cpu.bus = gb
 }
 
+// attachMMU attaches the given MMU to the Game Boy's bus
+func (gb *GameBoy) attachMMU(mmu *mmu) {
+	gb.mmu = mmu
+	gb.mmu.sched = gb.sched
+}
+
+// attachPPU attaches the given PPU to the Game Boy's bus, along with the OAM
+// DMA controller copying into its OAM. The PPU is clocked by the scheduler.
+func (gb *GameBoy) attachPPU(ppu *ppu) {
+	gb.ppu = ppu
+	gb.mmu.ppu = ppu
+	gb.mmu.dma = newDMA(gb.mmu.readBus, &ppu.oam)
+	gb.sched.attach(EVENT_PPU, ppu.tick, ppu.next)
+}
+
+// attachTimer attaches the given timer to the Game Boy's bus, clocked by the
+// scheduler
+func (gb *GameBoy) attachTimer(timer *timer) {
+	gb.mmu.timer = timer
+	gb.sched.attach(EVENT_TIMER, func(dots int) {
+		timer.tick(dots / 4)
+	}, func() (int, bool) {
+		cycles, ok := timer.next()
+		return cycles * 4, ok
+	})
+}
+
+// attachJoypad attaches the given joypad to the Game Boy's bus, polled by the
+// scheduler. A button press in a selected row wakes the CPU from STOP.
+func (gb *GameBoy) attachJoypad(joypad *joypad) {
+	gb.mmu.joypad = joypad
+	gb.sched.attach(EVENT_JOYPAD, func(dots int) {
+		if joypad.update() {
+			gb.Cpu.stopped = false
+		}
+	}, joypad.next)
+}
+
+// attachSerial attaches the given serial port to the Game Boy's bus, clocked
+// by the scheduler
+func (gb *GameBoy) attachSerial(serial *serial) {
+	gb.mmu.serial = serial
+	gb.sched.attach(EVENT_SERIAL, func(dots int) {
+		serial.tick(dots / 4)
+	}, func() (int, bool) {
+		cycles, ok := serial.next()
+		return cycles * 4, ok
+	})
+}
+
+// attachAPU attaches the given APU to the Game Boy's bus, its frame sequencer
+// clocked by the timer
+func (gb *GameBoy) attachAPU(apu *apu) {
+	gb.mmu.apu = apu
+	gb.mmu.timer.clockAPU = apu.clockDIV
+	gb.sched.sync(EVENT_TIMER)
+}
+
+// tick advances the console's time by the given number of machine cycles,
+// firing the scheduler's events due in that time. The APU and OAM DMA are
+// ticked on every cycle instead: the APU's channels and sample output run
+// continuously while it is on, and a DMA transfer copies a byte on every
+// cycle while it is active (and costs nothing otherwise).
+func (gb *GameBoy) tick(cycles int) {
+	gb.sched.advance(cycles * 4)
+	gb.mmu.apu.tick(cycles)
+	gb.syncLink()
+	gb.mmu.dma.tick(cycles)
+}
+
 // cpuRead allows the CPU to read from the system bus at the given memory
 // address
-// TODO: limit access
 func (gb *GameBoy) cpuRead(addr uint16) byte {
-	addr &= 0xFFFF
-	data := gb.CartRom[addr]
-	return data
+	return gb.mmu.read(addr)
 }
 
 // cpuWrite allows the CPU to write data to the system bus at the given memory
 // address
-// TODO: limit access
 func (gb *GameBoy) cpuWrite(addr uint16, data byte) {
-	addr &= 0xFFFF
-	gb.CartRom[addr] = data
+	gb.mmu.write(addr, data)
 }
@@ -0,0 +1,542 @@
+package gb
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Cartridge header fields, found at 0x0100-0x014F in every cartridge ROM
+//
+//	reference: https://gbdev.io/pandocs/The_Cartridge_Header.html
+const (
+	HEADER_TITLE_START           = 0x0134 // 16B (11B on newer cartridges)
+	HEADER_TITLE_END             = 0x0143
+	HEADER_MANUFACTURER_START    = 0x013F // 4B
+	HEADER_MANUFACTURER_END      = 0x0142
+	HEADER_CGB_FLAG              = 0x0143
+	HEADER_NEW_LICENSEE_START    = 0x0144 // 2B
+	HEADER_NEW_LICENSEE_END      = 0x0145
+	HEADER_SGB_FLAG              = 0x0146
+	HEADER_CARTRIDGE_TYPE        = 0x0147
+	HEADER_ROM_SIZE              = 0x0148
+	HEADER_RAM_SIZE              = 0x0149
+	HEADER_DESTINATION_CODE      = 0x014A
+	HEADER_OLD_LICENSEE          = 0x014B
+	HEADER_VERSION               = 0x014C
+	HEADER_CHECKSUM              = 0x014D
+	HEADER_GLOBAL_CHECKSUM_START = 0x014E // 2B, big endian
+	HEADER_GLOBAL_CHECKSUM_END   = 0x014F
+)
+
+// Cartridge represents a Game Boy game cartridge: its ROM image and the
+// metadata found in its header
+type Cartridge struct {
+	Header CartridgeHeader
+
+	rom []byte
+}
+
+// CartridgeHeader is the metadata found in a cartridge's header
+type CartridgeHeader struct {
+	Title            string
+	ManufacturerCode string
+	CGBFlag          byte
+	SGBFlag          byte
+	CartridgeType    byte
+	ROMSize          int // bytes
+	RAMSize          int // bytes
+	DestinationCode  byte
+	OldLicenseeCode  byte
+	NewLicenseeCode  string
+	Version          byte
+	HeaderChecksum   byte
+	GlobalChecksum   uint16
+}
+
+// cartridgeType describes the hardware found in a cartridge
+type cartridgeType struct {
+	name    string
+	ram     bool // External RAM
+	battery bool // Battery-backed RAM or RTC
+	timer   bool // Real-time clock
+	rumble  bool // Rumble motor
+}
+
+// cartridgeTypes is the cartridge type lookup table, mapping the cartridge type
+// header byte (0x147) to the hardware found in the cartridge
+var cartridgeTypes = map[byte]cartridgeType{
+	0x00: {"ROM ONLY", false, false, false, false},
+	0x01: {"MBC1", false, false, false, false},
+	0x02: {"MBC1+RAM", true, false, false, false},
+	0x03: {"MBC1+RAM+BATTERY", true, true, false, false},
+	0x05: {"MBC2", false, false, false, false},
+	0x06: {"MBC2+BATTERY", false, true, false, false},
+	0x08: {"ROM+RAM", true, false, false, false},
+	0x09: {"ROM+RAM+BATTERY", true, true, false, false},
+	0x0B: {"MMM01", false, false, false, false},
+	0x0C: {"MMM01+RAM", true, false, false, false},
+	0x0D: {"MMM01+RAM+BATTERY", true, true, false, false},
+	0x0F: {"MBC3+TIMER+BATTERY", false, true, true, false},
+	0x10: {"MBC3+TIMER+RAM+BATTERY", true, true, true, false},
+	0x11: {"MBC3", false, false, false, false},
+	0x12: {"MBC3+RAM", true, false, false, false},
+	0x13: {"MBC3+RAM+BATTERY", true, true, false, false},
+	0x19: {"MBC5", false, false, false, false},
+	0x1A: {"MBC5+RAM", true, false, false, false},
+	0x1B: {"MBC5+RAM+BATTERY", true, true, false, false},
+	0x1C: {"MBC5+RUMBLE", false, false, false, true},
+	0x1D: {"MBC5+RUMBLE+RAM", true, false, false, true},
+	0x1E: {"MBC5+RUMBLE+RAM+BATTERY", true, true, false, true},
+	0x20: {"MBC6", true, true, false, false},
+	0x22: {"MBC7+SENSOR+RUMBLE+RAM+BATTERY", true, true, false, true},
+	0xFC: {"POCKET CAMERA", true, true, false, false},
+	0xFD: {"BANDAI TAMA5", true, true, true, false},
+	0xFE: {"HuC3", true, true, true, false},
+	0xFF: {"HuC1+RAM+BATTERY", true, true, false, false},
+}
+
+// romSizes maps the ROM size header byte (0x148) to the ROM size in bytes
+var romSizes = map[byte]int{
+	0x00: 32 * 1024,
+	0x01: 64 * 1024,
+	0x02: 128 * 1024,
+	0x03: 256 * 1024,
+	0x04: 512 * 1024,
+	0x05: 1024 * 1024,
+	0x06: 2 * 1024 * 1024,
+	0x07: 4 * 1024 * 1024,
+	0x08: 8 * 1024 * 1024,
+	0x52: 1152 * 1024,
+	0x53: 1280 * 1024,
+	0x54: 1536 * 1024,
+}
+
+// ramSizes maps the RAM size header byte (0x149) to the RAM size in bytes
+var ramSizes = map[byte]int{
+	0x00: 0,
+	0x01: 2 * 1024, // unofficial, listed in various unofficial docs
+	0x02: 8 * 1024,
+	0x03: 32 * 1024,
+	0x04: 128 * 1024,
+	0x05: 64 * 1024,
+}
+
+// newLicensees maps the new licensee code (0x144-0x145) to the publisher's name
+var newLicensees = map[string]string{
+	"00": "None",
+	"01": "Nintendo R&D1",
+	"08": "Capcom",
+	"13": "Electronic Arts",
+	"18": "Hudson Soft",
+	"19": "b-ai",
+	"20": "kss",
+	"22": "pow",
+	"24": "PCM Complete",
+	"25": "san-x",
+	"28": "Kemco Japan",
+	"29": "seta",
+	"30": "Viacom",
+	"31": "Nintendo",
+	"32": "Bandai",
+	"33": "Ocean/Acclaim",
+	"34": "Konami",
+	"35": "Hector",
+	"37": "Taito",
+	"38": "Hudson",
+	"39": "Banpresto",
+	"41": "Ubi Soft",
+	"42": "Atlus",
+	"44": "Malibu",
+	"46": "angel",
+	"47": "Bullet-Proof",
+	"49": "irem",
+	"50": "Absolute",
+	"51": "Acclaim",
+	"52": "Activision",
+	"53": "American sammy",
+	"54": "Konami",
+	"55": "Hi tech entertainment",
+	"56": "LJN",
+	"57": "Matchbox",
+	"58": "Mattel",
+	"59": "Milton Bradley",
+	"60": "Titus",
+	"61": "Virgin",
+	"64": "LucasArts",
+	"67": "Ocean",
+	"69": "Electronic Arts",
+	"70": "Infogrames",
+	"71": "Interplay",
+	"72": "Broderbund",
+	"73": "sculptured",
+	"75": "sci",
+	"78": "THQ",
+	"79": "Accolade",
+	"80": "misawa",
+	"83": "lozc",
+	"86": "Tokuma Shoten Intermedia",
+	"87": "Tsukuda Original",
+	"91": "Chunsoft",
+	"92": "Video system",
+	"93": "Ocean/Acclaim",
+	"95": "Varie",
+	"96": "Yonezawa/s'pal",
+	"97": "Kaneko",
+	"99": "Pack in soft",
+	"A4": "Konami (Yu-Gi-Oh!)",
+}
+
+// oldLicensees maps the old licensee code (0x14B) to the publisher's name. Code
+// 0x33 indicates the new licensee code is used instead.
+var oldLicensees = map[byte]string{
+	0x00: "None",
+	0x01: "Nintendo",
+	0x08: "Capcom",
+	0x09: "Hot-B",
+	0x0A: "Jaleco",
+	0x0B: "Coconuts Japan",
+	0x0C: "Elite Systems",
+	0x13: "Electronic Arts",
+	0x18: "Hudson Soft",
+	0x19: "ITC Entertainment",
+	0x1A: "Yanoman",
+	0x1D: "Japan Clary",
+	0x1F: "Virgin",
+	0x24: "PCM Complete",
+	0x25: "San-X",
+	0x28: "Kotobuki Systems",
+	0x29: "Seta",
+	0x30: "Infogrames",
+	0x31: "Nintendo",
+	0x32: "Bandai",
+	0x34: "Konami",
+	0x35: "HectorSoft",
+	0x38: "Capcom",
+	0x39: "Banpresto",
+	0x3C: "Entertainment i",
+	0x3E: "Gremlin",
+	0x41: "Ubi Soft",
+	0x42: "Atlus",
+	0x44: "Malibu",
+	0x46: "Angel",
+	0x47: "Spectrum Holoby",
+	0x49: "Irem",
+	0x4A: "Virgin",
+	0x4D: "Malibu",
+	0x4F: "U.S. Gold",
+	0x50: "Absolute",
+	0x51: "Acclaim",
+	0x52: "Activision",
+	0x53: "American Sammy",
+	0x54: "GameTek",
+	0x55: "Park Place",
+	0x56: "LJN",
+	0x57: "Matchbox",
+	0x59: "Milton Bradley",
+	0x5A: "Mindscape",
+	0x5B: "Romstar",
+	0x5C: "Naxat Soft",
+	0x5D: "Tradewest",
+	0x60: "Titus",
+	0x61: "Virgin",
+	0x67: "Ocean",
+	0x69: "Electronic Arts",
+	0x6E: "Elite Systems",
+	0x6F: "Electro Brain",
+	0x70: "Infogrames",
+	0x71: "Interplay",
+	0x72: "Broderbund",
+	0x73: "Sculptered Soft",
+	0x75: "The Sales Curve",
+	0x78: "THQ",
+	0x79: "Accolade",
+	0x7A: "Triffix Entertainment",
+	0x7C: "Microprose",
+	0x7F: "Kemco",
+	0x80: "Misawa Entertainment",
+	0x83: "Lozc",
+	0x86: "Tokuma Shoten Intermedia",
+	0x8B: "Bullet-Proof Software",
+	0x8C: "Vic Tokai",
+	0x8E: "Ape",
+	0x8F: "I'Max",
+	0x91: "Chunsoft",
+	0x92: "Video System",
+	0x93: "Tsubaraya Productions",
+	0x95: "Varie",
+	0x96: "Yonezawa/S'Pal",
+	0x97: "Kaneko",
+	0x99: "Arc",
+	0x9A: "Nihon Bussan",
+	0x9B: "Tecmo",
+	0x9C: "Imagineer",
+	0x9D: "Banpresto",
+	0x9F: "Nova",
+	0xA1: "Hori Electric",
+	0xA2: "Bandai",
+	0xA4: "Konami",
+	0xA6: "Kawada",
+	0xA7: "Takara",
+	0xA9: "Technos Japan",
+	0xAA: "Broderbund",
+	0xAC: "Toei Animation",
+	0xAD: "Toho",
+	0xAF: "Namco",
+	0xB0: "Acclaim",
+	0xB1: "ASCII or Nexsoft",
+	0xB2: "Bandai",
+	0xB4: "Square Enix",
+	0xB6: "HAL Laboratory",
+	0xB7: "SNK",
+	0xB9: "Pony Canyon",
+	0xBA: "Culture Brain",
+	0xBB: "Sunsoft",
+	0xBD: "Sony Imagesoft",
+	0xBF: "Sammy",
+	0xC0: "Taito",
+	0xC2: "Kemco",
+	0xC3: "Squaresoft",
+	0xC4: "Tokuma Shoten Intermedia",
+	0xC5: "Data East",
+	0xC6: "Tonkinhouse",
+	0xC8: "Koei",
+	0xC9: "UFL",
+	0xCA: "Ultra",
+	0xCB: "Vap",
+	0xCC: "Use Corporation",
+	0xCD: "Meldac",
+	0xCE: "Pony Canyon",
+	0xCF: "Angel",
+	0xD0: "Taito",
+	0xD1: "Sofel",
+	0xD2: "Quest",
+	0xD3: "Sigma Enterprises",
+	0xD4: "ASK Kodansha",
+	0xD6: "Naxat Soft",
+	0xD7: "Copya System",
+	0xD9: "Banpresto",
+	0xDA: "Tomy",
+	0xDB: "LJN",
+	0xDD: "NCS",
+	0xDE: "Human",
+	0xDF: "Altron",
+	0xE0: "Jaleco",
+	0xE1: "Towa Chiki",
+	0xE2: "Yutaka",
+	0xE3: "Varie",
+	0xE5: "Epcoh",
+	0xE7: "Athena",
+	0xE8: "Asmik ACE Entertainment",
+	0xE9: "Natsume",
+	0xEA: "King Records",
+	0xEB: "Atlus",
+	0xEC: "Epic/Sony Records",
+	0xEE: "IGS",
+	0xF0: "A Wave",
+	0xF3: "Extreme Entertainment",
+	0xFF: "LJN",
+}
+
+// LoadCartridge reads the ROM file at the given path, and returns the
+// cartridge it contains
+func LoadCartridge(romPath string) (*Cartridge, error) {
+	rom, err := os.ReadFile(romPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ROM: %w", err)
+	}
+
+	cart, err := NewCartridge(rom)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", romPath, err)
+	}
+	return cart, nil
+}
+
+// NewCartridge parses the header of the given ROM image, and returns the
+// cartridge it describes. An error is returned if the header is malformed, if
+// the ROM image is not the size the header declares (e.g. a truncated or
+// overdumped file), or if the header checksum does not match (the boot ROM
+// refuses to run these cartridges).
+func NewCartridge(rom []byte) (*Cartridge, error) {
+	if len(rom) <= CARTRIDGE_HEADER_END {
+		return nil, fmt.Errorf("%w (%d bytes)", errRomTooSmall, len(rom))
+	}
+
+	header := CartridgeHeader{
+		CGBFlag:         rom[HEADER_CGB_FLAG],
+		SGBFlag:         rom[HEADER_SGB_FLAG],
+		CartridgeType:   rom[HEADER_CARTRIDGE_TYPE],
+		DestinationCode: rom[HEADER_DESTINATION_CODE],
+		OldLicenseeCode: rom[HEADER_OLD_LICENSEE],
+		NewLicenseeCode: string(rom[HEADER_NEW_LICENSEE_START : HEADER_NEW_LICENSEE_END+1]),
+		Version:         rom[HEADER_VERSION],
+		HeaderChecksum:  rom[HEADER_CHECKSUM],
+		GlobalChecksum: u16(
+			rom[HEADER_GLOBAL_CHECKSUM_END],
+			rom[HEADER_GLOBAL_CHECKSUM_START],
+		),
+	}
+	header.Title, header.ManufacturerCode = parseTitle(rom)
+
+	var ok bool
+	if header.ROMSize, ok = romSizes[rom[HEADER_ROM_SIZE]]; !ok {
+		return nil, fmt.Errorf("%w: %#02x", errUnknownRomSize, rom[HEADER_ROM_SIZE])
+	}
+	if len(rom) != header.ROMSize {
+		return nil, fmt.Errorf("%w: header declares %d bytes, image has %d",
+			errRomSizeMismatch, header.ROMSize, len(rom))
+	}
+	if header.RAMSize, ok = ramSizes[rom[HEADER_RAM_SIZE]]; !ok {
+		return nil, fmt.Errorf("%w: %#02x", errUnknownRamSize, rom[HEADER_RAM_SIZE])
+	}
+
+	if checksum := headerChecksum(rom); checksum != header.HeaderChecksum {
+		return nil, fmt.Errorf("%w: header contains %#02x, computed %#02x",
+			errHeaderChecksum, header.HeaderChecksum, checksum)
+	}
+
+	return &Cartridge{Header: header, rom: rom}, nil
+}
+
+// parseTitle returns the title and manufacturer code found in the given ROM's
+// header. Newer cartridges use the last 5 bytes of the title for the
+// manufacturer code and CGB flag.
+func parseTitle(rom []byte) (title, manufacturer string) {
+	end := HEADER_TITLE_END
+	if rom[HEADER_CGB_FLAG]&0x80 > 0 {
+		end = HEADER_CGB_FLAG - 1
+
+		code := rom[HEADER_MANUFACTURER_START : HEADER_MANUFACTURER_END+1]
+		if isManufacturerCode(code) {
+			end = HEADER_MANUFACTURER_START - 1
+			manufacturer = string(code)
+		}
+	}
+
+	title = string(rom[HEADER_TITLE_START : end+1])
+	if i := strings.IndexByte(title, 0x00); i >= 0 {
+		title = title[:i]
+	}
+	return title, manufacturer
+}
+
+// isManufacturerCode returns whether the given bytes form a manufacturer code,
+// which consists of 4 uppercase letters or digits
+func isManufacturerCode(code []byte) bool {
+	for _, c := range code {
+		if !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// headerChecksum calculates the checksum of the header bytes 0x134-0x14C, as
+// verified by the boot ROM
+func headerChecksum(rom []byte) byte {
+	var checksum byte
+	for addr := HEADER_TITLE_START; addr < HEADER_CHECKSUM; addr++ {
+		checksum = checksum - rom[addr] - 1
+	}
+	return checksum
+}
+
+// globalChecksum calculates the sum of all bytes in the ROM, excluding the
+// global checksum bytes themselves
+func globalChecksum(rom []byte) uint16 {
+	var checksum uint16
+	for addr, data := range rom {
+		if addr == HEADER_GLOBAL_CHECKSUM_START || addr == HEADER_GLOBAL_CHECKSUM_END {
+			continue
+		}
+		checksum += uint16(data)
+	}
+	return checksum
+}
+
+// VerifyGlobalChecksum returns an error if the cartridge's global checksum
+// does not match its ROM. The Game Boy does not verify this checksum, so many
+// homebrew and test ROMs leave it incorrect.
+func (c *Cartridge) VerifyGlobalChecksum() error {
+	if checksum := globalChecksum(c.rom); checksum != c.Header.GlobalChecksum {
+		return fmt.Errorf("%w: header contains %#04x, computed %#04x",
+			errGlobalChecksum, c.Header.GlobalChecksum, checksum)
+	}
+	return nil
+}
+
+// TypeName returns the name of the hardware described by the cartridge type
+func (h *CartridgeHeader) TypeName() string {
+	if t, ok := cartridgeTypes[h.CartridgeType]; ok {
+		return t.name
+	}
+	return "UNKNOWN"
+}
+
+// HasBattery returns whether the cartridge keeps its RAM or real-time clock
+// powered by a battery while the console is off
+func (h *CartridgeHeader) HasBattery() bool {
+	return cartridgeTypes[h.CartridgeType].battery
+}
+
+// Licensee returns the name of the cartridge's publisher
+func (h *CartridgeHeader) Licensee() string {
+	if h.OldLicenseeCode == 0x33 {
+		if name, ok := newLicensees[h.NewLicenseeCode]; ok {
+			return name
+		}
+		return fmt.Sprintf("Unknown (%q)", h.NewLicenseeCode)
+	}
+
+	if name, ok := oldLicensees[h.OldLicenseeCode]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%#02x)", h.OldLicenseeCode)
+}
+
+// String returns the cartridge's metadata, formatted as a table
+func (c *Cartridge) String() string {
+	h := c.Header
+
+	cgb := "No"
+	switch h.CGBFlag {
+	case 0x80:
+		cgb = "Supported"
+	case 0xC0:
+		cgb = "Required"
+	}
+	sgb := "No"
+	if h.SGBFlag == 0x03 {
+		sgb = "Supported"
+	}
+	destination := "Japan"
+	if h.DestinationCode > 0 {
+		destination = "Overseas"
+	}
+	globalChecksum := "OK"
+	if err := c.VerifyGlobalChecksum(); err != nil {
+		globalChecksum = "BAD"
+	}
+
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Title:\t%s\n", h.Title)
+	if h.ManufacturerCode != "" {
+		fmt.Fprintf(tw, "Manufacturer:\t%s\n", h.ManufacturerCode)
+	}
+	fmt.Fprintf(tw, "Licensee:\t%s\n", h.Licensee())
+	fmt.Fprintf(tw, "Type:\t%s (%#02x)\n", h.TypeName(), h.CartridgeType)
+	fmt.Fprintf(tw, "ROM size:\t%d KiB\n", h.ROMSize/1024)
+	fmt.Fprintf(tw, "RAM size:\t%d KiB\n", h.RAMSize/1024)
+	fmt.Fprintf(tw, "CGB:\t%s\n", cgb)
+	fmt.Fprintf(tw, "SGB:\t%s\n", sgb)
+	fmt.Fprintf(tw, "Destination:\t%s\n", destination)
+	fmt.Fprintf(tw, "Version:\t%d\n", h.Version)
+	fmt.Fprintf(tw, "Header checksum:\t%#02x (OK)\n", h.HeaderChecksum)
+	fmt.Fprintf(tw, "Global checksum:\t%#04x (%s)\n", h.GlobalChecksum, globalChecksum)
+	tw.Flush()
+
+	return sb.String()
+}
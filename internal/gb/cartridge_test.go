@@ -0,0 +1,64 @@
+package gb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestCartridgeHeader checks the header parsed from the 'cpu_instrs.gb' test
+// ROM
+func TestCartridgeHeader(t *testing.T) {
+	cart, err := LoadCartridge("../../test/cpu_instrs/cpu_instrs.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := cart.Header
+	if h.Title != "CPU_INSTRS" {
+		t.Errorf("title = %q, want %q", h.Title, "CPU_INSTRS")
+	}
+	if h.TypeName() != "MBC1" {
+		t.Errorf("type = %s, want MBC1", h.TypeName())
+	}
+	if h.ROMSize != 64*1024 || h.RAMSize != 0 {
+		t.Errorf("ROM/RAM size = %d/%d, want 65536/0", h.ROMSize, h.RAMSize)
+	}
+	if h.CGBFlag != 0x80 {
+		t.Errorf("CGB flag = %#02x, want 0x80", h.CGBFlag)
+	}
+
+	// This ROM's global checksum is known to be incorrect
+	if err := cart.VerifyGlobalChecksum(); !errors.Is(err, errGlobalChecksum) {
+		t.Errorf("VerifyGlobalChecksum() = %v, want %v", err, errGlobalChecksum)
+	}
+}
+
+// TestCartridgeHeaderErrors checks that malformed headers are rejected
+func TestCartridgeHeaderErrors(t *testing.T) {
+	rom, err := os.ReadFile("../../test/cpu_instrs/individual/01-special.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewCartridge(rom[:0x100]); !errors.Is(err, errRomTooSmall) {
+		t.Errorf("truncated ROM: err = %v, want %v", err, errRomTooSmall)
+	}
+
+	badChecksum := append([]byte(nil), rom...)
+	badChecksum[HEADER_CHECKSUM]++
+	if _, err := NewCartridge(badChecksum); !errors.Is(err, errHeaderChecksum) {
+		t.Errorf("bad header checksum: err = %v, want %v", err, errHeaderChecksum)
+	}
+
+	badRomSize := append([]byte(nil), rom...)
+	badRomSize[HEADER_ROM_SIZE] = 0x10
+	badRomSize[HEADER_CHECKSUM] = headerChecksum(badRomSize)
+	if _, err := NewCartridge(badRomSize); !errors.Is(err, errUnknownRomSize) {
+		t.Errorf("bad ROM size: err = %v, want %v", err, errUnknownRomSize)
+	}
+
+	if _, err := NewCartridge(rom[:len(rom)/2]); !errors.Is(err, errRomSizeMismatch) {
+		t.Errorf("ROM smaller than its header declares: err = %v, want %v", err, errRomSizeMismatch)
+	}
+}
@@ -0,0 +1,37 @@
+package gb
+
+import "time"
+
+const (
+	CPU_FREQUENCY     = 4194304           // Hz (T-cycles per second)
+	CYCLES_PER_SECOND = CPU_FREQUENCY / 4 // machine cycles per second
+)
+
+// Clock is a source of the current time, used by cartridge real-time clocks.
+// Front-ends and tests may provide their own Clock to control how cartridge
+// time passes.
+type Clock interface {
+	Now() time.Time
+}
+
+// emulatedClock is the default Clock. It starts at the wall clock time when
+// the console is created, and advances with emulated CPU time, so cartridge
+// time runs at the emulation speed.
+type emulatedClock struct {
+	start time.Time
+	cpu   *CPU
+}
+
+// newEmulatedClock returns a Clock advancing with the given CPU's cycles
+func newEmulatedClock(cpu *CPU) *emulatedClock {
+	return &emulatedClock{start: time.Now(), cpu: cpu}
+}
+
+// Now returns the start time plus the emulated time elapsed since
+func (c *emulatedClock) Now() time.Time {
+	secs := c.cpu.cycles / CYCLES_PER_SECOND
+	rem := c.cpu.cycles % CYCLES_PER_SECOND
+	elapsed := time.Duration(secs)*time.Second +
+		time.Duration(rem)*time.Second/CYCLES_PER_SECOND
+	return c.start.Add(elapsed)
+}
@@ -12,12 +12,21 @@ type CPU struct {
 	SP uint16 // Stack pointer
 	PC uint16 // Program counter
 
-	IME     bool // Interrupt master enable flag
-	halted  bool // Used to pause CPU execution until an interrupt occurs
-	stopped bool // Used to put the CPU into low power standby mode
+	nextPC   uint16 // Address of the next instruction, changed by jumps
+	branched bool   // Set when a conditional instruction takes its branch
+
+	IME       bool // Interrupt master enable flag
+	imePended bool // Set by EI, IME is set after the following instruction
+	halted    bool // Used to pause CPU execution until an interrupt occurs
+	haltBug   bool // Set when HALT fails to increment PC (see 'cpu.halt()')
+	stopped   bool // Used to put the CPU into low power standby mode
+	locked    bool // Set by illegal opcodes, hangs the CPU until power off
 
 	bus *GameBoy // 16-bit address, 8-bit data bus
 
+	instructions       [INSTRUCTION_COUNT]inst // instruction lookup table, used during the decode stage
+	prefixInstructions [INSTRUCTION_COUNT]inst // $CB-prefixed instruction lookup table
+
 	cycles int
 }
 
@@ -30,52 +39,125 @@ func newCPU() *CPU {
 		HL: register{name: "HL", hiReg: newReg8Bit("H"), loReg: newReg8Bit("L")},
 	}
 	cpu.setupInstructionLookup()
+	cpu.setupPrefixInstructionLookup()
 
 	return cpu
 }
 
 // execNextInst fetches the opcode at the current program counter and executes
-// the appropriate CPU instruction
+// the appropriate CPU instruction. The rest of the system is advanced as each
+// machine cycle of the instruction is executed.
 func (cpu *CPU) execNextInst() {
+	cpu.step()
+}
+
+// step executes the next CPU instruction, services an interrupt, or idles for
+// one machine cycle while halted or stopped
+func (cpu *CPU) step() {
+	if cpu.locked || cpu.stopped {
+		cpu.idle()
+		return
+	}
+
+	// interrupts
+	if cpu.handleInterrupts() {
+		return
+	}
+	if cpu.halted {
+		cpu.idle()
+		return
+	}
+
 	// fetch
+	start := cpu.cycles
 	op := cpu.read(cpu.PC)
 
 	// log
 	cpu.logInstruction()
 
+	// The HALT bug causes the byte following HALT to be read twice. Moving PC
+	// back one byte causes the opcode to also be read as the first operand.
+	if cpu.haltBug {
+		cpu.haltBug = false
+		cpu.PC--
+	}
+
 	// decode & execute
-	cpu.decodeAndExecute(op)
+	enableIME := cpu.imePended
+	cpu.decodeAndExecute(op, start)
+
+	// EI takes effect after the instruction following it. A DI in between
+	// cancels it.
+	if enableIME && cpu.imePended {
+		cpu.imePended = false
+		cpu.IME = true
+	}
 }
 
-// decodeAndExecude decodes the given opcode and executes its CPU instruction
-func (cpu *CPU) decodeAndExecute(op byte) {
-	inst := instructions[op]
+// decodeAndExecude decodes the given opcode, fetched at the given cycle count,
+// and executes its CPU instruction. Instructions read their operands relative
+// to PC, and jump by changing nextPC, which otherwise points to the following
+// instruction.
+func (cpu *CPU) decodeAndExecute(op byte, start int) {
+	inst := cpu.instructions[op]
+	if op == 0xCB {
+		inst = cpu.prefixInstructions[cpu.read(cpu.PC+1)]
+	}
 	if inst.exec == nil {
 		cpu.bus.logger.Fatalf("unimplemented op: %02X %#08b\n", op, op)
 	}
 
+	cpu.nextPC = cpu.PC + inst.length
+	cpu.branched = false
 	inst.exec()
 
-	cpu.PC += inst.length
-	cpu.cycles += inst.cycles
+	cpu.PC = cpu.nextPC
+	cycles := inst.cycles
+	if cpu.branched {
+		cycles = inst.taken
+	}
+
+	// Each memory access took 1 machine cycle. The instruction's remaining
+	// internal cycles, e.g. for 16-bit arithmetic, are spent at its end.
+	if remaining := cycles - (cpu.cycles - start); remaining > 0 {
+		cpu.tick(remaining)
+	}
 }
 
 // logInstruction logs the disassembly for the current CPU instruction
 func (cpu *CPU) logInstruction() {
 	if cpu.bus.debugMode {
-		logMsg := cpu.bus.disassembly[cpu.PC] + fmt.Sprintf("\t\t%d", cpu.cycles)
+		logMsg := cpu.bus.disassembleInst(cpu.PC) + fmt.Sprintf("\t\t%d", cpu.cycles)
 		cpu.bus.log(logMsg)
 	}
 }
 
-// read reads 1 byte from the system bus at the given address
+// tick advances the CPU, and the rest of the system along with it, by the
+// given number of machine cycles
+func (cpu *CPU) tick(cycles int) {
+	cpu.cycles += cycles
+	cpu.bus.tick(cycles)
+}
+
+// idle spends 1 machine cycle without accessing memory
+func (cpu *CPU) idle() {
+	cpu.tick(1)
+}
+
+// read reads 1 byte from the system bus at the given address, taking 1 machine
+// cycle. The rest of the system sees the access at the start of the cycle.
 func (cpu *CPU) read(addr uint16) byte {
-	return cpu.bus.cpuRead(addr)
+	data := cpu.bus.cpuRead(addr)
+	cpu.tick(1)
+	return data
 }
 
-// write writes 1 byte of data to the system bus at the given address
+// write writes 1 byte of data to the system bus at the given address, taking
+// 1 machine cycle. The rest of the system sees the access at the start of the
+// cycle.
 func (cpu *CPU) write(addr uint16, data byte) {
 	cpu.bus.cpuWrite(addr, data)
+	cpu.tick(1)
 }
 
 // readWord reads 2 bytes from the system bus at the given address (little
@@ -86,6 +168,13 @@ func (cpu *CPU) readWord(addr uint16) uint16 {
 	return (hi << 8) | lo
 }
 
+// writeWord writes 2 bytes of data to the system bus at the given address
+// (little endian order)
+func (cpu *CPU) writeWord(addr uint16, data uint16) {
+	cpu.write(addr, byte(data))
+	cpu.write(addr+1, byte(data>>8))
+}
+
 // stackPush pushes a byte of data to the stack
 func (cpu *CPU) stackPush(data byte) {
 	cpu.SP--
@@ -1,11 +1,309 @@
 package gb
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
-// TestCpuInstrs runs the third-party 'cpu_instrs.gb' test package in an
-// emulated SM83 CPU
+// testRomCycleLimit is the number of machine cycles a test ROM is allowed to
+// run before it is considered hung
+const testRomCycleLimit = 100_000_000
+
+// newTestRom returns a 32KB ROM-only cartridge image with the given program at
+// the entry point
+func newTestRom(program ...byte) []byte {
+	rom := make([]byte, 0x8000)
+	copy(rom[ENTRY_POINT:], program)
+	rom[HEADER_CHECKSUM] = headerChecksum(rom)
+	return rom
+}
+
+// newTestGameBoy returns a powered-on GameBoy running a 32KB ROM with the
+// given program at the entry point
+func newTestGameBoy(t *testing.T, program ...byte) *GameBoy {
+	t.Helper()
+	return newTestGameBoyFromRom(t, newTestRom(program...))
+}
+
+// newTestGameBoyFromRom returns a powered-on GameBoy running the given ROM
+func newTestGameBoyFromRom(t *testing.T, rom []byte, opts ...Option) *GameBoy {
+	t.Helper()
+
+	romPath := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gb, err := New(romPath, false, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gb.initPowerUpSequence()
+	return gb
+}
+
+// runTestRom runs the test ROM at the given path until it reports a result
+// over the serial port, and returns everything it printed
+func runTestRom(t *testing.T, romPath string) string {
+	t.Helper()
+
+	peer := new(CapturePeer)
+	gb, err := New(romPath, false, WithLinkPeer(peer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gb.initPowerUpSequence()
+
+	printed := 0
+	for gb.Cpu.cycles < testRomCycleLimit {
+		gb.Cpu.execNextInst()
+		if peer.Len() == printed {
+			continue
+		}
+		printed = peer.Len()
+
+		out := peer.String()
+		if strings.Contains(out, "Passed") || strings.Contains(out, "Failed") {
+			break
+		}
+	}
+	return peer.String()
+}
+
+// TestCpuInstrs runs the third-party 'cpu_instrs' test ROMs in an emulated
+// SM83 CPU
 func TestCpuInstrs(t *testing.T) {
-	filepath := "../../test/cpu_instrs/cpu_instrs.gb"
-	gb := New(filepath, true)
-	gb.Start()
+	roms := []string{
+		"01-special.gb",
+		"02-interrupts.gb",
+		"03-op sp,hl.gb",
+		"04-op r,imm.gb",
+		"05-op rp.gb",
+		"06-ld r,r.gb",
+		"07-jr,jp,call,ret,rst.gb",
+		"08-misc instrs.gb",
+		"09-op r,r.gb",
+		"10-bit ops.gb",
+		"11-op a,(hl).gb",
+	}
+
+	for _, rom := range roms {
+		t.Run(rom, func(t *testing.T) {
+			out := runTestRom(t, "../../test/cpu_instrs/individual/"+rom)
+			if !strings.Contains(out, "Passed") {
+				t.Errorf("%s did not pass:\n%s", rom, out)
+			}
+		})
+	}
+}
+
+// TestCpuInstrsCombined runs all the 'cpu_instrs' tests from a single ROM,
+// which also depends on the timer and MBC1
+func TestCpuInstrsCombined(t *testing.T) {
+	out := runTestRom(t, "../../test/cpu_instrs/cpu_instrs.gb")
+	if !strings.Contains(out, "Passed") {
+		t.Errorf("cpu_instrs.gb did not pass:\n%s", out)
+	}
+}
+
+// TestPrefixInstructions checks the timing of $CB-prefixed instructions, and
+// their (HL) operand variants
+func TestPrefixInstructions(t *testing.T) {
+	for _, c := range []struct {
+		op     byte
+		name   string
+		cycles int
+	}{
+		{0x00, "RLC", 2},
+		{0x06, "RLC", 4},
+		{0x37, "SWAP", 2},
+		{0x3E, "SRL", 4},
+		{0x47, "BIT", 2},
+		{0x7E, "BIT", 3},
+		{0x86, "RES", 4},
+		{0xFE, "SET", 4},
+		{0xFF, "SET", 2},
+	} {
+		gb := newTestGameBoy(t, 0xCB, c.op)
+		gb.Cpu.HL.set(INTERNAL_RAM_START)
+
+		if name := gb.Cpu.prefixInstructions[c.op].name; name != c.name {
+			t.Errorf("CB %02X name = %s, want %s", c.op, name, c.name)
+		}
+		gb.Cpu.execNextInst()
+		if got := gb.Cpu.cycles; got != c.cycles {
+			t.Errorf("CB %02X took %d cycles, want %d", c.op, got, c.cycles)
+		}
+		if gb.Cpu.PC != ENTRY_POINT+2 {
+			t.Errorf("CB %02X: PC = %#04x, want %#04x", c.op, gb.Cpu.PC, ENTRY_POINT+2)
+		}
+	}
+
+	// (HL) operands are written back, except by BIT
+	gb := newTestGameBoy(t, 0xCB, 0xFE, 0xCB, 0x46) // SET 7,(HL); BIT 0,(HL)
+	gb.Cpu.HL.set(INTERNAL_RAM_START)
+	gb.Cpu.execNextInst()
+	gb.Cpu.execNextInst()
+	if got := gb.cpuRead(INTERNAL_RAM_START); got != 0x80 {
+		t.Errorf("(HL) = %#02x after SET 7,(HL), want 0x80", got)
+	}
+	if !gb.Cpu.getFlag(FLAG_Z) {
+		t.Error("BIT 0,(HL) did not set Z")
+	}
+}
+
+// TestBranches checks the next PC and timing of jumps, calls and returns, with
+// their branches taken and not taken
+func TestBranches(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		program []byte
+		zero    bool // Z flag
+		pc      uint16
+		cycles  int
+		pushed  uint16 // return address pushed, if not 0
+	}{
+		{"JR NZ taken", []byte{0x20, 0x10}, false, 0x0112, 3, 0},
+		{"JR NZ not taken", []byte{0x20, 0x10}, true, 0x0102, 2, 0},
+		{"JR backwards", []byte{0x18, 0x80}, false, 0x0082, 3, 0},
+		{"JP Z taken", []byte{0xCA, 0x34, 0x12}, true, 0x1234, 4, 0},
+		{"JP Z not taken", []byte{0xCA, 0x34, 0x12}, false, 0x0103, 3, 0},
+		{"JP HL", []byte{0xE9}, false, 0xC000, 1, 0},
+		{"CALL NZ taken", []byte{0xC4, 0x34, 0x12}, false, 0x1234, 6, 0x0103},
+		{"CALL NZ not taken", []byte{0xC4, 0x34, 0x12}, true, 0x0103, 3, 0},
+		{"RST 0x28", []byte{0xEF}, false, 0x0028, 4, 0x0101},
+		{"RET Z not taken", []byte{0xC8}, false, 0x0101, 2, 0},
+	} {
+		gb := newTestGameBoy(t, c.program...)
+		gb.Cpu.HL.set(0xC000)
+		gb.Cpu.setFlag(FLAG_Z, c.zero)
+
+		gb.Cpu.execNextInst()
+		if gb.Cpu.PC != c.pc {
+			t.Errorf("%s: PC = %#04x, want %#04x", c.name, gb.Cpu.PC, c.pc)
+		}
+		if gb.Cpu.cycles != c.cycles {
+			t.Errorf("%s: took %d cycles, want %d", c.name, gb.Cpu.cycles, c.cycles)
+		}
+		if c.pushed != 0 {
+			if got := gb.Cpu.pop(); got != c.pushed {
+				t.Errorf("%s: pushed %#04x, want %#04x", c.name, got, c.pushed)
+			}
+		}
+	}
+
+	// RET C taken returns to the address on the stack
+	gb := newTestGameBoy(t, 0xD8) // RET C
+	gb.Cpu.push(0x4321)
+	gb.Cpu.setFlag(FLAG_C, true)
+	start := gb.Cpu.cycles
+	gb.Cpu.execNextInst()
+	if cycles := gb.Cpu.cycles - start; gb.Cpu.PC != 0x4321 || cycles != 5 {
+		t.Errorf("RET C taken: PC = %#04x in %d cycles, want 0x4321 in 5", gb.Cpu.PC, cycles)
+	}
+}
+
+// TestJumpPageCrossing checks that JR carries into the high byte of PC, and
+// that RST pushes the full address of the next instruction
+func TestJumpPageCrossing(t *testing.T) {
+	rom := newTestRom(0xC3, 0xF0, 0x01)          // JP 0x01F0
+	copy(rom[0x01F0:], []byte{0x18, 0x10})       // JR +16
+	copy(rom[0x0202:], []byte{0xC3, 0xFF, 0x02}) // JP 0x02FF
+	rom[0x02FF] = 0xFF                           // RST 0x38
+	rom[HEADER_CHECKSUM] = headerChecksum(rom)
+	gb := newTestGameBoyFromRom(t, rom)
+
+	for _, want := range []uint16{0x01F0, 0x0202, 0x02FF, 0x0038} {
+		gb.Cpu.execNextInst()
+		if gb.Cpu.PC != want {
+			t.Fatalf("PC = %#04x, want %#04x", gb.Cpu.PC, want)
+		}
+	}
+	if got := gb.Cpu.pop(); got != 0x0300 {
+		t.Errorf("RST pushed %#04x, want 0x0300", got)
+	}
+}
+
+// TestMemoryTiming checks the machine cycle each instruction accesses memory
+// on, using the timer to observe the access. Writes reset DIV's internal
+// counter, which then counts 4 per remaining cycle of the instruction.
+func TestMemoryTiming(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		program []byte
+		counter uint16 // internal counter after the instruction
+	}{
+		{"LD (HL),n", []byte{0x36, 0x00}, 4},
+		{"INC (HL)", []byte{0x34}, 4},
+		{"LD (nn),A", []byte{0xEA, 0x04, 0xFF}, 4},
+		{"PUSH BC", []byte{0xC5}, 4},
+		{"CALL nn", []byte{0xCD, 0x00, 0x02}, 4},
+		{"RST 0x00", []byte{0xC7}, 4},
+		{"LD (nn),SP", []byte{0x08, 0x03, 0xFF}, 4},
+	} {
+		gb := newTestGameBoy(t, c.program...)
+		gb.Cpu.HL.set(IO_DIV)
+		gb.Cpu.SP = IO_DIV + 2 // Pushes write TIMA, then DIV
+
+		gb.Cpu.execNextInst()
+		gb.sched.sync(EVENT_TIMER)
+		if got := gb.mmu.timer.counter; got != c.counter {
+			t.Errorf("%s: counter = %d after writing DIV, want %d", c.name, got, c.counter)
+		}
+	}
+
+	// Reads see TIMA incremented on the cycles before them. TIMA increments
+	// every 4 cycles at 262144 Hz, on the 4th cycle from a counter of 0, or the
+	// 3rd from 4.
+	for _, c := range []struct {
+		name    string
+		program []byte
+		counter uint16
+		tima    byte
+	}{
+		{"LD A,(nn) on cycle 4", []byte{0xFA, 0x05, 0xFF}, 4, 1},
+		{"LD A,(nn) before cycle 4", []byte{0xFA, 0x05, 0xFF}, 0, 0},
+		{"LD A,(HL) on cycle 2", []byte{0x7E}, 12, 1},
+		{"LD A,(HL) before cycle 2", []byte{0x7E}, 8, 0},
+	} {
+		gb := newTestGameBoy(t, c.program...)
+		gb.Cpu.HL.set(IO_TIMA)
+		gb.cpuWrite(IO_TAC, TAC_ENABLE|0x01)
+		gb.cpuWrite(IO_TIMA, 0)
+		gb.mmu.timer.counter = c.counter
+
+		gb.Cpu.execNextInst()
+		if got := gb.Cpu.AF.getHi(); got != c.tima {
+			t.Errorf("%s: read TIMA = %d, want %d", c.name, got, c.tima)
+		}
+	}
+}
+
+// TestInstructionCycles checks that every instruction takes the number of
+// cycles in the instruction table, with its memory accesses included
+func TestInstructionCycles(t *testing.T) {
+	gb := newTestGameBoy(t)
+	for op, inst := range gb.Cpu.instructions {
+		if op == 0x10 || op == 0x76 || op == 0xCB { // STOP, HALT and prefix
+			continue
+		}
+
+		// Run from WRAM, operands pointing into WRAM
+		pc := uint16(INTERNAL_RAM_START + 0x100)
+		gb.cpuWrite(pc, byte(op))
+		gb.cpuWrite(pc+1, 0x00)
+		gb.cpuWrite(pc+2, 0xC0)
+		gb.Cpu.PC = pc
+		gb.Cpu.HL.set(INTERNAL_RAM_START)
+		gb.Cpu.SP = HRAM_END
+		gb.Cpu.IME, gb.Cpu.imePended, gb.Cpu.locked = false, false, false
+
+		start := gb.Cpu.cycles
+		gb.Cpu.execNextInst()
+		if got := gb.Cpu.cycles - start; got != inst.cycles && got != inst.taken {
+			t.Errorf("%02X %s took %d cycles, want %d", op, inst.name, got, inst.cycles)
+		}
+	}
 }
@@ -6,453 +6,782 @@ import (
 
 const lineTemplate string = "[%#04x]:\t%s\t%s "
 
-// disassemble disassembles the system's loaded cartridge ROM between addresses
-// 'start' and 'end', and stores the result in the 'gb.disassembly'
+// disassembleInst disassembles the instruction found in memory at the given
+// address
 //
 // see 'instruction.go' or https://gbdev.io/gb-opcodes/optables/ for details
-func (gb *GameBoy) disassemble(start uint16, end uint16) error {
-	if int(end) >= len(gb.CartRom) {
-		return errAddrOutOfRange
-	}
-
-	disassembly := make([]string, len(gb.CartRom))
-
-	addr := start
-	for {
-		op := gb.CartRom[addr]
-		op1 := gb.CartRom[addr+1]
-		op2 := gb.CartRom[addr+2]
-		word := gb.Cpu.readWord(addr + 1) // next word
-		inst := instructions[op]
-
-		opString := fmt.Sprintf("%02X", op)
-		if inst.length > 1 {
-			opString += fmt.Sprintf(" %02X", op1)
-		}
-		if inst.length > 2 {
-			opString += fmt.Sprintf(" %02X", op2)
-		}
-
-		if op == 0xCB {
-			// Prefix instruction
-			inst.name = getPrefixInstructionName(op1)
-		}
-
-		msg := fmt.Sprintf(lineTemplate, addr, opString, inst.name)
-
-		switch op {
-		case 0x00:
-			// NOP
-		case 0x01:
-			// LD BC,nn
-			msg += fmt.Sprintf("BC,0x%04X", word)
-		case 0x02:
-			// LD (BC),A
-			msg += "(BC),A"
-		case 0x03:
-			// INC BC
-			msg += "BC"
-		case 0x05:
-			// DEC B
-			msg += "B"
-		case 0x06:
-			// LD B,n
-			msg += fmt.Sprintf("B,0x%02X", op1)
-		case 0x07:
-			// RLCA
-		case 0x0A:
-			// LD A,(BC)
-			msg += "A,(BC)"
-		case 0x0E:
-			// LD C,n
-			msg += fmt.Sprintf("B,0x%02X", op1)
-		case 0x0F:
-			// RRCA
-		case 0x10:
-			// STOP
-		case 0x11:
-			// LD DE,nn
-			msg += fmt.Sprintf("DE,0x%04X", word)
-		case 0x12:
-			// LD (DE),A
-			msg += "(DE),A"
-		case 0x13:
-			// INC DE
-			msg += "DE"
-		case 0x18:
-			// JR e
-			msg += fmt.Sprintf("0x%02X", op1)
-		case 0x1A:
-			// LD A,(DE)
-			msg += "A,(DE)"
-		case 0x1B:
-			// DEC DE
-			msg += "DE"
-		case 0x1C:
-			// INC E
-			msg += "E"
-		case 0x1D:
-			// DEC E
-			msg += "E"
-		case 0x1E:
-			// LD E,n
-			msg += fmt.Sprintf("E,0x%02X", op1)
-		case 0x1F:
-			// RRA
-		case 0x20:
-			// JR NZ,e
-			msg += fmt.Sprintf("NZ,0x%02X", op1)
-		case 0x21:
-			// LD HL,nn
-			msg += fmt.Sprintf("HL,0x%04X", word)
-		case 0x22:
-			// LD (HL+),A
-			msg += "(HL+),A"
-		case 0x23:
-			// INC HL
-			msg += "HL"
-		case 0x24:
-			// INC H
-			msg += "H"
-		case 0x25:
-			// DEC H
-			msg += "H"
-		case 0x26:
-			// LD H,n
-			msg += fmt.Sprintf("H,0x%02X", op1)
-		case 0x28:
-			// JR Z,e
-			msg += fmt.Sprintf("Z,0x%02X", op1)
-		case 0x29:
-			// ADD HL,HL
-			msg += "HL,HL"
-		case 0x2A:
-			// LD A,(HL+)
-			msg += "A,(HL+)"
-		case 0x2C:
-			// INC L
-			msg += "L"
-		case 0x2D:
-			// DEC L
-			msg += "L"
-		case 0x2F:
-			// CPL
-		case 0x30:
-			// JR NC,e
-			msg += fmt.Sprintf("NC,0x%02X", op1)
-		case 0x31:
-			// LD SP,nn
-			msg += fmt.Sprintf("SP,0x%04X", word)
-		case 0x32:
-			// LD (HL-),A
-			msg += "(HL-),A"
-		case 0x35:
-			// DEC (HL)
-			msg += "(HL)"
-		case 0x36:
-			// LD (HL),n
-			msg += fmt.Sprintf("(HL),0x%02X", op1)
-		case 0x38:
-			// JR C,e
-			msg += fmt.Sprintf("C,0x%02X", op1)
-		case 0x3A:
-			// LD A,(HL-)
-			msg += "A,(HL-)"
-		case 0x3C:
-			// INC A
-			msg += "A"
-		case 0x3D:
-			// DEC A
-			msg += "A"
-		case 0x3E:
-			// LD A,n
-			msg += fmt.Sprintf("A,0x%02X", op1)
-		case 0x46:
-			// LD B,(HL)
-			msg += "B,(HL)"
-		case 0x47:
-			// LD B,A
-			msg += "B,A"
-		case 0x4E:
-			// LD C,(HL)
-			msg += "C,(HL)"
-		case 0x4F:
-			// LD C,A
-			msg += "C,A"
-		case 0x56:
-			// LD D,(HL)
-			msg += "D,(HL)"
-		case 0x57:
-			// LD D,A
-			msg += "D,A"
-		case 0x5D:
-			// LD E,L
-			msg += "E,L"
-		case 0x5F:
-			// LD E,A
-			msg += "E,A"
-		case 0x60:
-			// LD H,B
-			msg += "H,B"
-		case 0x62:
-			// LD H,D
-			msg += "H,D"
-		case 0x63:
-			// LD H,E
-			msg += "H,E"
-		case 0x66:
-			// LD H,(HL)
-			msg += "H,(HL)"
-		case 0x67:
-			// LD H,A
-			msg += "H,A"
-		case 0x69:
-			// LD L,C
-			msg += "L,C"
-		case 0x6B:
-			// LD L,E
-			msg += "L,E"
-		case 0x6C:
-			// LD L,H
-			msg += "L,H"
-		case 0x6E:
-			// LD L,(HL)
-			msg += "L,(HL)"
-		case 0x6F:
-			// LD L,A
-			msg += "L,A"
-		case 0x70:
-			// LD (HL),B
-			msg += "(HL),B"
-		case 0x71:
-			// LD (HL),C
-			msg += "(HL),C"
-		case 0x72:
-			// LD (HL),D
-			msg += "(HL),D"
-		case 0x73:
-			// LD (HL),E
-			msg += "(HL),E"
-		case 0x74:
-			// LD (HL),H
-			msg += "(HL),H"
-		case 0x75:
-			// LD (HL),L
-			msg += "(HL),L"
-		case 0x76:
-			// HALT
-		case 0x77:
-			// LD (HL),A
-			msg += "(HL),A"
-		case 0x78:
-			// LD A,B
-			msg += "A,B"
-		case 0x79:
-			// LD A,C
-			msg += "A,C"
-		case 0x7A:
-			// LD A,D
-			msg += "A,D"
-		case 0x7B:
-			// LD A,E
-			msg += "A,E"
-		case 0x7C:
-			// LD A,H
-			msg += "A,H"
-		case 0x7D:
-			// LD A,L
-			msg += "A,L"
-		case 0x7E:
-			// LD A,(HL)
-			msg += "A,(HL)"
-		case 0x80:
-			// ADD A,B
-			msg += "A,B"
-		case 0x8E:
-			// ADC A,(HL)
-			msg += "A,(HL)"
-		case 0x93:
-			// SUB E
-			msg += "E"
-		case 0xA3:
-			// AND E
-			msg += "E"
-		case 0xA9:
-			// XOR C
-			msg += "C"
-		case 0xAE:
-			// XOR (HL)
-			msg += "(HL)"
-		case 0xB1:
-			// OR C
-			msg += "C"
-		case 0xB6:
-			// OR (HL)
-			msg += "(HL)"
-		case 0xC0:
-			// RET NZ
-			msg += "NZ"
-		case 0xC1:
-			// POP BC
-			msg += "BC"
-		case 0xC3:
-			// JP nn
-			msg += fmt.Sprintf("0x%04X", word)
-		case 0xC4:
-			// CALL NZ,nn
-			msg += fmt.Sprintf("NZ,0x%04X", word)
-		case 0xC5:
-			// PUSH BC
-			msg += "BC"
-		case 0xC6:
-			// ADD A,n
-			msg += fmt.Sprintf("A,0x%02X", op1)
-		case 0xC8:
-			// RET Z
-			msg += "Z"
-		case 0xC9:
-			// RET
-		case 0xCA:
-			// JP Z,nn
-			msg += fmt.Sprintf("Z,0x%04X", word)
-		case 0xCB:
-			// Prefix instructions
-			var reg string
-			switch op1 % 8 {
-			case 0:
-				reg = "B"
-			case 1:
-				reg = "C"
-			case 2:
-				reg = "D"
-			case 3:
-				reg = "E"
-			case 4:
-				reg = "H"
-			case 5:
-				reg = "L"
-			case 6:
-				reg = "(HL)"
-			case 7:
-				reg = "A"
-			}
+func (gb *GameBoy) disassembleInst(addr uint16) string {
+	op := gb.mmu.read(addr)
+	op1 := gb.mmu.read(addr + 1)
+	op2 := gb.mmu.read(addr + 2)
+	word := u16(op1, op2) // next word
+	inst := gb.Cpu.instructions[op]
 
-			if op1 <= 0x3F {
-				msg += fmt.Sprintf("%s", reg)
-			} else {
-				// Determine bit
-				b := int((op1 / 8) % 8)
-				bit := fmt.Sprintf("%d", b)
-
-				msg += fmt.Sprintf("%s,%s", bit, reg)
-			}
-		case 0xCD:
-			// CALL nn
-			msg += fmt.Sprintf("(0x%04X)", word)
-		case 0xCE:
-			// ADC A,n
-			msg += fmt.Sprintf("A,0x%02X", op1)
-		case 0xD0:
-			// RET NC
-			msg += "NC"
-		case 0xD1:
-			// POP DE
-			msg += "DE"
-		case 0xD2:
-			// JP NC,nn
-			msg += fmt.Sprintf("NC,0x%04X", word)
-		case 0xD5:
-			// PUSH DE
-			msg += "DE"
-		case 0xD6:
-			// SUB n
-			msg += fmt.Sprintf("0x%02X", op1)
-		case 0xE0:
-			// LDH (n),A
-			msg += fmt.Sprintf("(0xFF%02X),A", op1)
-		case 0xE1:
-			// POP HL
-			msg += "HL"
-		case 0xE5:
-			// PUSH HL
-			msg += "HL"
-		case 0xE6:
-			// AND n
-			msg += fmt.Sprintf("0x%02X", op1)
-		case 0xEA:
-			// LD (nn),A
-			msg += fmt.Sprintf("(0x%04X),A", word)
-		case 0xEE:
-			// XOR n
-			msg += fmt.Sprintf("0x%02X", op1)
-		case 0xF0:
-			// LDH A,(n)
-			msg += fmt.Sprintf("A,(0xFF%02X)", op1)
-		case 0xF1:
-			// POP AF
-			msg += "AF"
-		case 0xF3:
-			// DI
-		case 0xF5:
-			// PUSH AF
-			msg += "AF"
-		case 0xF9:
-			// LD SP,HL
-			msg += "SP,HL"
-		case 0xFA:
-			// LD A,(nn)
-			msg += fmt.Sprintf("A,(0x%04X)", word)
-		case 0xFE:
-			// CP n
-			msg += fmt.Sprintf("0x%02X", op1)
-		case 0xFF:
-			// RST 0x38
-			msg += "0x38"
-		}
-
-		disassembly[addr] = msg
-
-		addr++
-		if addr >= end {
-			break
-		}
+	opString := fmt.Sprintf("%02X", op)
+	if inst.length > 1 {
+		opString += fmt.Sprintf(" %02X", op1)
+	}
+	if inst.length > 2 {
+		opString += fmt.Sprintf(" %02X", op2)
 	}
 
-	gb.disassembly = disassembly
-	return nil
-}
+	if op == 0xCB {
+		// Prefix instruction
+		inst = gb.Cpu.prefixInstructions[op1]
+	}
 
-func getPrefixInstructionName(op byte) string {
-	var name string
+	msg := fmt.Sprintf(lineTemplate, addr, opString, inst.name)
 
-	if op <= 0x3F {
-		switch op / 8 {
+	switch op {
+	case 0x00:
+		// NOP
+	case 0x01:
+		// LD BC,nn
+		msg += fmt.Sprintf("BC,0x%04X", word)
+	case 0x02:
+		// LD (BC),A
+		msg += "(BC),A"
+	case 0x03:
+		// INC BC
+		msg += "BC"
+	case 0x04:
+		// INC B
+		msg += "B"
+	case 0x05:
+		// DEC B
+		msg += "B"
+	case 0x06:
+		// LD B,n
+		msg += fmt.Sprintf("B,0x%02X", op1)
+	case 0x07:
+		// RLCA
+	case 0x08:
+		// LD (nn),SP
+		msg += fmt.Sprintf("(0x%04X)", word) + ",SP"
+	case 0x09:
+		// ADD HL,BC
+		msg += "HL,BC"
+	case 0x0A:
+		// LD A,(BC)
+		msg += "A,(BC)"
+	case 0x0B:
+		// DEC BC
+		msg += "BC"
+	case 0x0C:
+		// INC C
+		msg += "C"
+	case 0x0D:
+		// DEC C
+		msg += "C"
+	case 0x0E:
+		// LD C,n
+		msg += fmt.Sprintf("C,0x%02X", op1)
+	case 0x0F:
+		// RRCA
+	case 0x10:
+		// STOP n
+	case 0x11:
+		// LD DE,nn
+		msg += fmt.Sprintf("DE,0x%04X", word)
+	case 0x12:
+		// LD (DE),A
+		msg += "(DE),A"
+	case 0x13:
+		// INC DE
+		msg += "DE"
+	case 0x14:
+		// INC D
+		msg += "D"
+	case 0x15:
+		// DEC D
+		msg += "D"
+	case 0x16:
+		// LD D,n
+		msg += fmt.Sprintf("D,0x%02X", op1)
+	case 0x17:
+		// RLA
+	case 0x18:
+		// JR e
+		msg += fmt.Sprintf("0x%02X", op1)
+	case 0x19:
+		// ADD HL,DE
+		msg += "HL,DE"
+	case 0x1A:
+		// LD A,(DE)
+		msg += "A,(DE)"
+	case 0x1B:
+		// DEC DE
+		msg += "DE"
+	case 0x1C:
+		// INC E
+		msg += "E"
+	case 0x1D:
+		// DEC E
+		msg += "E"
+	case 0x1E:
+		// LD E,n
+		msg += fmt.Sprintf("E,0x%02X", op1)
+	case 0x1F:
+		// RRA
+	case 0x20:
+		// JR NZ,e
+		msg += fmt.Sprintf("NZ,0x%02X", op1)
+	case 0x21:
+		// LD HL,nn
+		msg += fmt.Sprintf("HL,0x%04X", word)
+	case 0x22:
+		// LD (HL+),A
+		msg += "(HL+),A"
+	case 0x23:
+		// INC HL
+		msg += "HL"
+	case 0x24:
+		// INC H
+		msg += "H"
+	case 0x25:
+		// DEC H
+		msg += "H"
+	case 0x26:
+		// LD H,n
+		msg += fmt.Sprintf("H,0x%02X", op1)
+	case 0x27:
+		// DAA
+	case 0x28:
+		// JR Z,e
+		msg += fmt.Sprintf("Z,0x%02X", op1)
+	case 0x29:
+		// ADD HL,HL
+		msg += "HL,HL"
+	case 0x2A:
+		// LD A,(HL+)
+		msg += "A,(HL+)"
+	case 0x2B:
+		// DEC HL
+		msg += "HL"
+	case 0x2C:
+		// INC L
+		msg += "L"
+	case 0x2D:
+		// DEC L
+		msg += "L"
+	case 0x2E:
+		// LD L,n
+		msg += fmt.Sprintf("L,0x%02X", op1)
+	case 0x2F:
+		// CPL
+	case 0x30:
+		// JR NC,e
+		msg += fmt.Sprintf("NC,0x%02X", op1)
+	case 0x31:
+		// LD SP,nn
+		msg += fmt.Sprintf("SP,0x%04X", word)
+	case 0x32:
+		// LD (HL-),A
+		msg += "(HL-),A"
+	case 0x33:
+		// INC SP
+		msg += "SP"
+	case 0x34:
+		// INC (HL)
+		msg += "(HL)"
+	case 0x35:
+		// DEC (HL)
+		msg += "(HL)"
+	case 0x36:
+		// LD (HL),n
+		msg += fmt.Sprintf("(HL),0x%02X", op1)
+	case 0x37:
+		// SCF
+	case 0x38:
+		// JR C,e
+		msg += fmt.Sprintf("C,0x%02X", op1)
+	case 0x39:
+		// ADD HL,SP
+		msg += "HL,SP"
+	case 0x3A:
+		// LD A,(HL-)
+		msg += "A,(HL-)"
+	case 0x3B:
+		// DEC SP
+		msg += "SP"
+	case 0x3C:
+		// INC A
+		msg += "A"
+	case 0x3D:
+		// DEC A
+		msg += "A"
+	case 0x3E:
+		// LD A,n
+		msg += fmt.Sprintf("A,0x%02X", op1)
+	case 0x3F:
+		// CCF
+	case 0x40:
+		// LD B,B
+		msg += "B,B"
+	case 0x41:
+		// LD B,C
+		msg += "B,C"
+	case 0x42:
+		// LD B,D
+		msg += "B,D"
+	case 0x43:
+		// LD B,E
+		msg += "B,E"
+	case 0x44:
+		// LD B,H
+		msg += "B,H"
+	case 0x45:
+		// LD B,L
+		msg += "B,L"
+	case 0x46:
+		// LD B,(HL)
+		msg += "B,(HL)"
+	case 0x47:
+		// LD B,A
+		msg += "B,A"
+	case 0x48:
+		// LD C,B
+		msg += "C,B"
+	case 0x49:
+		// LD C,C
+		msg += "C,C"
+	case 0x4A:
+		// LD C,D
+		msg += "C,D"
+	case 0x4B:
+		// LD C,E
+		msg += "C,E"
+	case 0x4C:
+		// LD C,H
+		msg += "C,H"
+	case 0x4D:
+		// LD C,L
+		msg += "C,L"
+	case 0x4E:
+		// LD C,(HL)
+		msg += "C,(HL)"
+	case 0x4F:
+		// LD C,A
+		msg += "C,A"
+	case 0x50:
+		// LD D,B
+		msg += "D,B"
+	case 0x51:
+		// LD D,C
+		msg += "D,C"
+	case 0x52:
+		// LD D,D
+		msg += "D,D"
+	case 0x53:
+		// LD D,E
+		msg += "D,E"
+	case 0x54:
+		// LD D,H
+		msg += "D,H"
+	case 0x55:
+		// LD D,L
+		msg += "D,L"
+	case 0x56:
+		// LD D,(HL)
+		msg += "D,(HL)"
+	case 0x57:
+		// LD D,A
+		msg += "D,A"
+	case 0x58:
+		// LD E,B
+		msg += "E,B"
+	case 0x59:
+		// LD E,C
+		msg += "E,C"
+	case 0x5A:
+		// LD E,D
+		msg += "E,D"
+	case 0x5B:
+		// LD E,E
+		msg += "E,E"
+	case 0x5C:
+		// LD E,H
+		msg += "E,H"
+	case 0x5D:
+		// LD E,L
+		msg += "E,L"
+	case 0x5E:
+		// LD E,(HL)
+		msg += "E,(HL)"
+	case 0x5F:
+		// LD E,A
+		msg += "E,A"
+	case 0x60:
+		// LD H,B
+		msg += "H,B"
+	case 0x61:
+		// LD H,C
+		msg += "H,C"
+	case 0x62:
+		// LD H,D
+		msg += "H,D"
+	case 0x63:
+		// LD H,E
+		msg += "H,E"
+	case 0x64:
+		// LD H,H
+		msg += "H,H"
+	case 0x65:
+		// LD H,L
+		msg += "H,L"
+	case 0x66:
+		// LD H,(HL)
+		msg += "H,(HL)"
+	case 0x67:
+		// LD H,A
+		msg += "H,A"
+	case 0x68:
+		// LD L,B
+		msg += "L,B"
+	case 0x69:
+		// LD L,C
+		msg += "L,C"
+	case 0x6A:
+		// LD L,D
+		msg += "L,D"
+	case 0x6B:
+		// LD L,E
+		msg += "L,E"
+	case 0x6C:
+		// LD L,H
+		msg += "L,H"
+	case 0x6D:
+		// LD L,L
+		msg += "L,L"
+	case 0x6E:
+		// LD L,(HL)
+		msg += "L,(HL)"
+	case 0x6F:
+		// LD L,A
+		msg += "L,A"
+	case 0x70:
+		// LD (HL),B
+		msg += "(HL),B"
+	case 0x71:
+		// LD (HL),C
+		msg += "(HL),C"
+	case 0x72:
+		// LD (HL),D
+		msg += "(HL),D"
+	case 0x73:
+		// LD (HL),E
+		msg += "(HL),E"
+	case 0x74:
+		// LD (HL),H
+		msg += "(HL),H"
+	case 0x75:
+		// LD (HL),L
+		msg += "(HL),L"
+	case 0x76:
+		// HALT
+	case 0x77:
+		// LD (HL),A
+		msg += "(HL),A"
+	case 0x78:
+		// LD A,B
+		msg += "A,B"
+	case 0x79:
+		// LD A,C
+		msg += "A,C"
+	case 0x7A:
+		// LD A,D
+		msg += "A,D"
+	case 0x7B:
+		// LD A,E
+		msg += "A,E"
+	case 0x7C:
+		// LD A,H
+		msg += "A,H"
+	case 0x7D:
+		// LD A,L
+		msg += "A,L"
+	case 0x7E:
+		// LD A,(HL)
+		msg += "A,(HL)"
+	case 0x7F:
+		// LD A,A
+		msg += "A,A"
+	case 0x80:
+		// ADD A,B
+		msg += "A,B"
+	case 0x81:
+		// ADD A,C
+		msg += "A,C"
+	case 0x82:
+		// ADD A,D
+		msg += "A,D"
+	case 0x83:
+		// ADD A,E
+		msg += "A,E"
+	case 0x84:
+		// ADD A,H
+		msg += "A,H"
+	case 0x85:
+		// ADD A,L
+		msg += "A,L"
+	case 0x86:
+		// ADD A,(HL)
+		msg += "A,(HL)"
+	case 0x87:
+		// ADD A,A
+		msg += "A,A"
+	case 0x88:
+		// ADC A,B
+		msg += "A,B"
+	case 0x89:
+		// ADC A,C
+		msg += "A,C"
+	case 0x8A:
+		// ADC A,D
+		msg += "A,D"
+	case 0x8B:
+		// ADC A,E
+		msg += "A,E"
+	case 0x8C:
+		// ADC A,H
+		msg += "A,H"
+	case 0x8D:
+		// ADC A,L
+		msg += "A,L"
+	case 0x8E:
+		// ADC A,(HL)
+		msg += "A,(HL)"
+	case 0x8F:
+		// ADC A,A
+		msg += "A,A"
+	case 0x90:
+		// SUB B
+		msg += "B"
+	case 0x91:
+		// SUB C
+		msg += "C"
+	case 0x92:
+		// SUB D
+		msg += "D"
+	case 0x93:
+		// SUB E
+		msg += "E"
+	case 0x94:
+		// SUB H
+		msg += "H"
+	case 0x95:
+		// SUB L
+		msg += "L"
+	case 0x96:
+		// SUB (HL)
+		msg += "(HL)"
+	case 0x97:
+		// SUB A
+		msg += "A"
+	case 0x98:
+		// SBC A,B
+		msg += "A,B"
+	case 0x99:
+		// SBC A,C
+		msg += "A,C"
+	case 0x9A:
+		// SBC A,D
+		msg += "A,D"
+	case 0x9B:
+		// SBC A,E
+		msg += "A,E"
+	case 0x9C:
+		// SBC A,H
+		msg += "A,H"
+	case 0x9D:
+		// SBC A,L
+		msg += "A,L"
+	case 0x9E:
+		// SBC A,(HL)
+		msg += "A,(HL)"
+	case 0x9F:
+		// SBC A,A
+		msg += "A,A"
+	case 0xA0:
+		// AND B
+		msg += "B"
+	case 0xA1:
+		// AND C
+		msg += "C"
+	case 0xA2:
+		// AND D
+		msg += "D"
+	case 0xA3:
+		// AND E
+		msg += "E"
+	case 0xA4:
+		// AND H
+		msg += "H"
+	case 0xA5:
+		// AND L
+		msg += "L"
+	case 0xA6:
+		// AND (HL)
+		msg += "(HL)"
+	case 0xA7:
+		// AND A
+		msg += "A"
+	case 0xA8:
+		// XOR B
+		msg += "B"
+	case 0xA9:
+		// XOR C
+		msg += "C"
+	case 0xAA:
+		// XOR D
+		msg += "D"
+	case 0xAB:
+		// XOR E
+		msg += "E"
+	case 0xAC:
+		// XOR H
+		msg += "H"
+	case 0xAD:
+		// XOR L
+		msg += "L"
+	case 0xAE:
+		// XOR (HL)
+		msg += "(HL)"
+	case 0xAF:
+		// XOR A
+		msg += "A"
+	case 0xB0:
+		// OR B
+		msg += "B"
+	case 0xB1:
+		// OR C
+		msg += "C"
+	case 0xB2:
+		// OR D
+		msg += "D"
+	case 0xB3:
+		// OR E
+		msg += "E"
+	case 0xB4:
+		// OR H
+		msg += "H"
+	case 0xB5:
+		// OR L
+		msg += "L"
+	case 0xB6:
+		// OR (HL)
+		msg += "(HL)"
+	case 0xB7:
+		// OR A
+		msg += "A"
+	case 0xB8:
+		// CP B
+		msg += "B"
+	case 0xB9:
+		// CP C
+		msg += "C"
+	case 0xBA:
+		// CP D
+		msg += "D"
+	case 0xBB:
+		// CP E
+		msg += "E"
+	case 0xBC:
+		// CP H
+		msg += "H"
+	case 0xBD:
+		// CP L
+		msg += "L"
+	case 0xBE:
+		// CP (HL)
+		msg += "(HL)"
+	case 0xBF:
+		// CP A
+		msg += "A"
+	case 0xC0:
+		// RET NZ
+		msg += "NZ"
+	case 0xC1:
+		// POP BC
+		msg += "BC"
+	case 0xC2:
+		// JP NZ,nn
+		msg += fmt.Sprintf("NZ,0x%04X", word)
+	case 0xC3:
+		// JP nn
+		msg += fmt.Sprintf("0x%04X", word)
+	case 0xC4:
+		// CALL NZ,nn
+		msg += fmt.Sprintf("NZ,0x%04X", word)
+	case 0xC5:
+		// PUSH BC
+		msg += "BC"
+	case 0xC6:
+		// ADD A,n
+		msg += fmt.Sprintf("A,0x%02X", op1)
+	case 0xC7:
+		// RST 0x00
+		msg += "0x00"
+	case 0xC8:
+		// RET Z
+		msg += "Z"
+	case 0xC9:
+		// RET
+	case 0xCA:
+		// JP Z,nn
+		msg += fmt.Sprintf("Z,0x%04X", word)
+	case 0xCB:
+		// Prefix instructions
+		var reg string
+		switch op1 % 8 {
 		case 0:
-			name = "RLC"
+			reg = "B"
 		case 1:
-			name = "RRC"
+			reg = "C"
 		case 2:
-			name = "RL"
+			reg = "D"
 		case 3:
-			name = "RR"
+			reg = "E"
 		case 4:
-			name = "SLA"
+			reg = "H"
 		case 5:
-			name = "SRA"
+			reg = "L"
 		case 6:
-			name = "SWAP"
+			reg = "(HL)"
 		case 7:
-			name = "SRL"
+			reg = "A"
 		}
-	} else {
-		if op >= 0x40 && op <= 0x7F {
-			name = "BIT"
-		} else if op >= 0x80 && op <= 0xBF {
-			name = "RES"
-		} else if op >= 0xC0 && op <= 0xFF {
-			name = "SET"
+
+		if op1 <= 0x3F {
+			msg += fmt.Sprintf("%s", reg)
+		} else {
+			// Determine bit
+			b := int((op1 / 8) % 8)
+			bit := fmt.Sprintf("%d", b)
+
+			msg += fmt.Sprintf("%s,%s", bit, reg)
 		}
+	case 0xCC:
+		// CALL Z,nn
+		msg += fmt.Sprintf("Z,0x%04X", word)
+	case 0xCD:
+		// CALL nn
+		msg += fmt.Sprintf("(0x%04X)", word)
+	case 0xCE:
+		// ADC A,n
+		msg += fmt.Sprintf("A,0x%02X", op1)
+	case 0xCF:
+		// RST 0x08
+		msg += "0x08"
+	case 0xD0:
+		// RET NC
+		msg += "NC"
+	case 0xD1:
+		// POP DE
+		msg += "DE"
+	case 0xD2:
+		// JP NC,nn
+		msg += fmt.Sprintf("NC,0x%04X", word)
+	case 0xD4:
+		// CALL NC,nn
+		msg += fmt.Sprintf("NC,0x%04X", word)
+	case 0xD5:
+		// PUSH DE
+		msg += "DE"
+	case 0xD6:
+		// SUB n
+		msg += fmt.Sprintf("0x%02X", op1)
+	case 0xD7:
+		// RST 0x10
+		msg += "0x10"
+	case 0xD8:
+		// RET C
+		msg += "C"
+	case 0xD9:
+		// RETI
+	case 0xDA:
+		// JP C,nn
+		msg += fmt.Sprintf("C,0x%04X", word)
+	case 0xDC:
+		// CALL C,nn
+		msg += fmt.Sprintf("C,0x%04X", word)
+	case 0xDE:
+		// SBC A,n
+		msg += fmt.Sprintf("A,0x%02X", op1)
+	case 0xDF:
+		// RST 0x18
+		msg += "0x18"
+	case 0xE0:
+		// LDH (n),A
+		msg += fmt.Sprintf("(0xFF%02X),A", op1)
+	case 0xE1:
+		// POP HL
+		msg += "HL"
+	case 0xE2:
+		// LD (C),A
+		msg += "(C),A"
+	case 0xE5:
+		// PUSH HL
+		msg += "HL"
+	case 0xE6:
+		// AND n
+		msg += fmt.Sprintf("0x%02X", op1)
+	case 0xE7:
+		// RST 0x20
+		msg += "0x20"
+	case 0xE8:
+		// ADD SP,e
+		msg += fmt.Sprintf("SP,0x%02X", op1)
+	case 0xE9:
+		// JP HL
+		msg += "HL"
+	case 0xEA:
+		// LD (nn),A
+		msg += fmt.Sprintf("(0x%04X),A", word)
+	case 0xEE:
+		// XOR n
+		msg += fmt.Sprintf("0x%02X", op1)
+	case 0xEF:
+		// RST 0x28
+		msg += "0x28"
+	case 0xF0:
+		// LDH A,(n)
+		msg += fmt.Sprintf("A,(0xFF%02X)", op1)
+	case 0xF1:
+		// POP AF
+		msg += "AF"
+	case 0xF2:
+		// LD A,(C)
+		msg += "A,(C)"
+	case 0xF3:
+		// DI
+	case 0xF5:
+		// PUSH AF
+		msg += "AF"
+	case 0xF6:
+		// OR n
+		msg += fmt.Sprintf("0x%02X", op1)
+	case 0xF7:
+		// RST 0x30
+		msg += "0x30"
+	case 0xF8:
+		// LD HL,SP+e
+		msg += fmt.Sprintf("HL,SP+0x%02X", op1)
+	case 0xF9:
+		// LD SP,HL
+		msg += "SP,HL"
+	case 0xFA:
+		// LD A,(nn)
+		msg += fmt.Sprintf("A,(0x%04X)", word)
+	case 0xFB:
+		// EI
+	case 0xFE:
+		// CP n
+		msg += fmt.Sprintf("0x%02X", op1)
+	case 0xFF:
+		// RST 0x38
+		msg += "0x38"
 	}
 
-	return name
+	return msg
 }
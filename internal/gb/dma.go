@@ -0,0 +1,86 @@
+package gb
+
+// DMA_LENGTH is the number of bytes copied by an OAM DMA transfer, one per
+// machine cycle
+const DMA_LENGTH = OAM_END - OAM_START + 1
+
+// DMA_START_DELAY is the number of machine cycles between writing the DMA
+// register and the transfer starting
+const DMA_START_DELAY = 1
+
+// dma is the OAM DMA controller. Writing a value to the DMA register copies
+// 160 bytes from 'value << 8' to OAM, one byte per machine cycle. While a
+// transfer is active, the DMA controller owns the memory buses: the CPU can
+// only reach the IO registers and HRAM, and its reads from anywhere else see
+// the byte being transferred.
+//
+// reference: https://gbdev.io/pandocs/OAM_DMA_Transfer.html
+type dma struct {
+	value byte // DMA register, last value written
+
+	active bool   // whether a transfer is in progress
+	source uint16 // start address of the active transfer
+	index  int    // next byte of the active transfer
+	data   byte   // last byte transferred
+
+	delay int // machine cycles until a requested transfer starts, or 0
+
+	read func(addr uint16) byte // reads the source, bypassing DMA blocking
+	oam  *[DMA_LENGTH]byte
+}
+
+// newDMA returns a DMA controller copying to the given OAM, reading the
+// source with the given function
+func newDMA(read func(addr uint16) byte, oam *[DMA_LENGTH]byte) *dma {
+	return &dma{value: 0xFF, read: read, oam: oam}
+}
+
+// start requests a transfer from the given page. A transfer already in
+// progress continues until the new one starts.
+func (d *dma) start(page byte) {
+	d.value = page
+	// Devices are ticked after the CPU's write, so include its cycle
+	d.delay = DMA_START_DELAY + 1
+}
+
+// tick advances the DMA controller by the given number of machine cycles
+func (d *dma) tick(cycles int) {
+	for ; cycles > 0; cycles-- {
+		if d.active {
+			addr := d.source + uint16(d.index)
+			// Echo RAM is the only source above 0xDFFF
+			if addr >= ECHO_RAM_START {
+				addr -= ECHO_RAM_START - INTERNAL_RAM_START
+			}
+			d.data = d.read(addr)
+			d.oam[d.index] = d.data
+			d.index++
+			d.active = d.index < DMA_LENGTH
+		}
+
+		if d.delay > 0 {
+			d.delay--
+			if d.delay == 0 {
+				d.active = true
+				d.source = uint16(d.value) << 8
+				d.index = 0
+			}
+		}
+	}
+}
+
+// blocks returns whether CPU accesses to the given address are blocked by an
+// active transfer
+func (d *dma) blocks(addr uint16) bool {
+	return d.active && addr < IO_REGISTERS_START
+}
+
+// conflict returns the value read by the CPU from the given blocked address.
+// OAM is busy being written, and reads 0xFF. Everywhere else, the CPU sees the
+// byte on the bus being transferred.
+func (d *dma) conflict(addr uint16) byte {
+	if addr >= OAM_START {
+		return 0xFF
+	}
+	return d.data
+}
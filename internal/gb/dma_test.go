@@ -0,0 +1,71 @@
+package gb
+
+import "testing"
+
+// TestDMA checks that OAM DMA copies 160 bytes to OAM, blocking the CPU from
+// everything but the IO registers and HRAM while active
+func TestDMA(t *testing.T) {
+	gb := newTestGameBoy(t)
+	gb.cpuWrite(IO_LCDC, 0x00) // leave OAM accessible to the CPU
+	for i := 0; i < DMA_LENGTH; i++ {
+		gb.cpuWrite(0xC000+uint16(i), byte(i))
+	}
+	gb.cpuWrite(HRAM_BEGIN, 0x42)
+
+	gb.cpuWrite(IO_DMA, 0xC0)
+	gb.tick(DMA_START_DELAY + 1)
+	if got := gb.cpuRead(IO_DMA); got != 0xC0 {
+		t.Errorf("DMA read %#02x, want 0xc0", got)
+	}
+
+	gb.tick(10)
+	if got := gb.cpuRead(0xC0FF); got != 9 {
+		t.Errorf("WRAM read %#02x during DMA, want the transferred byte 0x09", got)
+	}
+	if got := gb.cpuRead(OAM_START); got != 0xFF {
+		t.Errorf("OAM read %#02x during DMA, want 0xff", got)
+	}
+	if got := gb.cpuRead(HRAM_BEGIN); got != 0x42 {
+		t.Errorf("HRAM read %#02x during DMA, want 0x42", got)
+	}
+	gb.cpuWrite(0xC000, 0xAA)
+
+	gb.tick(DMA_LENGTH - 10)
+	for i := 0; i < DMA_LENGTH; i++ {
+		if got := gb.cpuRead(OAM_START + uint16(i)); got != byte(i) {
+			t.Fatalf("OAM[%d] = %#02x after DMA, want %#02x", i, got, byte(i))
+		}
+	}
+	if got := gb.cpuRead(0xC000); got != 0x00 {
+		t.Errorf("WRAM written during DMA: read %#02x, want 0x00", got)
+	}
+}
+
+// TestDMARestart checks that writing DMA during a transfer restarts it from
+// the new source, with the old transfer continuing until the new one starts
+func TestDMARestart(t *testing.T) {
+	gb := newTestGameBoy(t)
+	gb.cpuWrite(IO_LCDC, 0x00)
+	for i := 0; i < DMA_LENGTH; i++ {
+		gb.cpuWrite(0xC000+uint16(i), 0x11)
+		gb.cpuWrite(0xD000+uint16(i), 0x22)
+	}
+
+	gb.cpuWrite(IO_DMA, 0xC0)
+	gb.tick(DMA_START_DELAY + 1 + 50)
+	gb.cpuWrite(IO_DMA, 0xD0)
+	gb.tick(DMA_START_DELAY + 1)
+	if !gb.mmu.dma.active || gb.mmu.dma.index != 0 {
+		t.Fatal("DMA did not restart")
+	}
+	if gb.ppu.oam[51] != 0x11 {
+		t.Errorf("OAM[51] = %#02x, want 0x11 from the old transfer", gb.ppu.oam[51])
+	}
+
+	gb.tick(DMA_LENGTH)
+	for i, got := range gb.ppu.oam {
+		if got != 0x22 {
+			t.Fatalf("OAM[%d] = %#02x after restarted DMA, want 0x22", i, got)
+		}
+	}
+}
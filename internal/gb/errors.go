@@ -2,5 +2,15 @@ package gb
 
 import "errors"
 
-var errGameFilepathNotFound = errors.New("File not found using given path")
-var errAddrOutOfRange = errors.New("Address out of range")
+var errRomTooSmall = errors.New("ROM is too small to contain a cartridge header")
+var errUnknownRomSize = errors.New("Unknown ROM size in cartridge header")
+var errRomSizeMismatch = errors.New("ROM size does not match cartridge header")
+var errUnknownRamSize = errors.New("Unknown RAM size in cartridge header")
+var errHeaderChecksum = errors.New("Cartridge header checksum mismatch")
+var errGlobalChecksum = errors.New("Cartridge global checksum mismatch")
+var errUnsupportedCartridge = errors.New("Unsupported cartridge type")
+var errRTCSaveSize = errors.New("Invalid real-time clock save size")
+var errSaveSize = errors.New("Save file size does not match cartridge RAM")
+var errGBSHeader = errors.New("Invalid GBS file header")
+var errGBSLoadAddress = errors.New("GBS load address overlaps the player")
+var errGBSSong = errors.New("GBS song number out of range")
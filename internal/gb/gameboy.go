@@ -2,8 +2,9 @@ package gb
 
 import (
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"sort"
 	"text/tabwriter"
 )
 
@@ -14,8 +15,36 @@ type GameBoy struct {
 	// Sharp SM83 CPU
 	Cpu *CPU
 
-	// Cartridge ROM
-	CartRom []byte
+	// Inserted game cartridge
+	Cartridge *Cartridge
+
+	// Memory management unit
+	mmu *mmu
+
+	// Pixel processing unit
+	ppu *ppu
+
+	// Keeps time, and clocks the devices with events scheduled
+	sched *scheduler
+
+	// Hardware model, setting the state left by the boot ROM
+	model Model
+
+	// Time source for cartridge real-time clocks
+	clock Clock
+
+	// Battery-backed cartridge RAM save file, the data last written to it,
+	// and the cycle count it was last flushed at
+	savePath  string
+	savedData []byte
+	lastFlush int
+
+	// Link cable connection to another console, or nil
+	link *linkPort
+
+	// Cartridge rumble motor state, and the function notified of changes
+	rumbling      bool
+	rumbleHandler func(on bool)
 
 	// Internal variables
 	isRunning bool
@@ -24,54 +53,236 @@ type GameBoy struct {
 	logger *log.Logger
 	tw     *tabwriter.Writer
 
-	debugMode  bool
-	debugState *debugState
+	debugMode bool
+}
+
+// Option configures a GameBoy created by 'New'
+type Option func(*GameBoy)
+
+// WithModel sets the hardware model emulated. The CPU and hardware registers
+// start in the state left by its boot ROM. By default, a DMG is emulated.
+func WithModel(model Model) Option {
+	return func(gb *GameBoy) {
+		gb.model = model
+	}
+}
+
+// WithClock sets the time source used by cartridge real-time clocks. By
+// default, cartridge time advances with emulated time.
+func WithClock(clock Clock) Option {
+	return func(gb *GameBoy) {
+		gb.clock = clock
+	}
+}
+
+// WithRumbleHandler sets a function called whenever the cartridge's rumble
+// motor is switched on or off
+func WithRumbleHandler(handler func(on bool)) Option {
+	return func(gb *GameBoy) {
+		gb.rumbleHandler = handler
+	}
+}
+
+// WithPixelFIFO selects the fetcher/pixel FIFO renderer, which models mode 3
+// one dot at a time. It is slower than the default scanline renderer, but
+// accurate for games and test ROMs relying on mode 3 timing or mid-line
+// register writes.
+func WithPixelFIFO() Option {
+	return func(gb *GameBoy) {
+		gb.ppu.fifo = newPixelFIFO(gb.ppu)
+	}
+}
+
+// WithInput sets the source of button presses. By default, no buttons are
+// ever pressed.
+func WithInput(input Input) Option {
+	return func(gb *GameBoy) {
+		gb.mmu.joypad.input = input
+	}
+}
+
+// WithAudio sends the sound produced by the APU to the given sink, as stereo
+// samples at the given rate in Hz. By default, no samples are produced.
+func WithAudio(sink AudioSink, sampleRate int) Option {
+	return func(gb *GameBoy) {
+		gb.mmu.apu.setSink(sink, sampleRate)
+	}
+}
+
+// WithAudioChannels mutes every sound channel but the given ones, numbered 1
+// to 4, in the audio output. Muted channels are still emulated.
+func WithAudioChannels(channels ...int) Option {
+	return func(gb *GameBoy) {
+		gb.mmu.apu.channels = 0
+		for _, ch := range channels {
+			if ch >= 1 && ch <= 4 {
+				gb.mmu.apu.channels |= 1 << (ch - 1)
+			}
+		}
+	}
+}
 
-	disassembly []string
+// WithLinkPeer connects the given peer to the link port. By default, the link
+// port is disconnected.
+func WithLinkPeer(peer LinkPeer) Option {
+	return func(gb *GameBoy) {
+		gb.mmu.serial.peer = peer
+	}
+}
+
+// WithLink connects the link port to another console over the given
+// connection, which may be a Unix socket or TCP connection to another process,
+// or one end of a net.Pipe shared with a console in the same process. The two
+// consoles run in lockstep (see 'linkPort'). The connection is closed when
+// the console stops.
+func WithLink(conn io.ReadWriteCloser) Option {
+	return func(gb *GameBoy) {
+		gb.link = newLinkPort(gb.mmu.serial, conn)
+		gb.mmu.serial.peer = gb.link
+	}
 }
 
-// debugState stores data relevant to CPU debugging
-type debugState struct {
-	iosb byte // IO serial bus
-	iosc byte // IO serial control
+// New creates and returns a GameBoy instance with the cartridge ROM at the
+// given path inserted, ready to start by running its 'Start' method. If the
+// cartridge has battery-backed RAM, it is loaded from the ROM's ".sav" file.
+func New(romPath string, debug bool, opts ...Option) (*GameBoy, error) {
+	gb := newGameBoy(debug, opts...)
+
+	cart, err := LoadCartridge(romPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := gb.insertCartridge(cart); err != nil {
+		return nil, err
+	}
+	gb.savePath = savePath(romPath)
+	if err := gb.loadSave(); err != nil {
+		return nil, err
+	}
+
+	if debug {
+		gb.log(cart.String())
+	}
+
+	return gb, nil
 }
 
-// New creates and returns a GameBoy instance, ready to start by running its
-// 'Start' method
-func New(romPath string, debug bool) *GameBoy {
+// newGameBoy returns a GameBoy with all of its devices attached, configured
+// by the given options, and no cartridge inserted
+func newGameBoy(debug bool, opts ...Option) *GameBoy {
 	logger := log.Default()
 	logger.SetFlags(0)
 	tw := tabwriter.NewWriter(logger.Writer(), 12, 4, 2, ' ', 0)
 
 	gb := &GameBoy{
-		logger:     logger,
-		tw:         tw,
-		debugMode:  debug,
-		debugState: new(debugState),
+		logger:    logger,
+		tw:        tw,
+		debugMode: debug,
+		sched:     newScheduler(),
 	}
 	cpu := newCPU()
 	gb.attachCPU(cpu)
+	gb.attachMMU(newMMU())
+	gb.attachPPU(newPPU(gb.requestInterrupt))
+	gb.attachTimer(newTimer(gb.requestInterrupt))
+	gb.attachJoypad(newJoypad(gb.requestInterrupt))
+	gb.attachSerial(newSerial(gb.requestInterrupt))
+	gb.attachAPU(newAPU())
+	gb.clock = newEmulatedClock(cpu)
 
-	// TODO: load ROM, insert cartridge
-	gb.insertCartridge(romPath)
-	gb.disassemble(0x0000, 0xFFFF)
-
+	for _, opt := range opts {
+		opt(gb)
+	}
 	return gb
 }
 
 // Start "powers on" the GameBoy console. This will run the power-up sequence,
-// and begin CPU execution.
+// and run frames until 'Stop' is called. Battery-backed cartridge RAM is
+// periodically written to the save file, and the console is closed before
+// returning.
 func (gb *GameBoy) Start() {
-	gb.initPowerUpSequence()
+	gb.PowerOn()
 
 	for gb.isRunning {
-		gb.Cpu.execNextInst()
+		gb.RunFrame()
+	}
+
+	if err := gb.Close(); err != nil {
+		gb.logger.Println(err)
+	}
+}
+
+// PowerOn runs the power-up sequence, leaving the console ready to be driven
+// by 'Step', 'RunCycles' or 'RunFrame'. Consoles driven this way must be
+// closed with 'Close' once done with, or the last changes to their save file
+// are lost.
+func (gb *GameBoy) PowerOn() {
+	gb.initPowerUpSequence()
+}
+
+// Close writes battery-backed cartridge RAM to the save file, and closes the
+// link cable connection
+func (gb *GameBoy) Close() error {
+	err := gb.flushSave()
+	gb.disconnectLink()
+	return err
+}
+
+// Step runs the CPU for one instruction, or one machine cycle while halted or
+// stopped, with the rest of the console advancing alongside it. Returns the
+// number of T-cycles taken. Call 'Close' once done stepping.
+func (gb *GameBoy) Step() int {
+	start := gb.sched.now
+	gb.step()
+	return gb.sched.now - start
+}
+
+// RunCycles runs the console for at least the given number of T-cycles,
+// stopping at the end of an instruction. Returns the number of T-cycles run.
+// Call 'Close' once done running.
+func (gb *GameBoy) RunCycles(cycles int) int {
+	start := gb.sched.now
+	for gb.sched.now-start < cycles {
+		gb.step()
+	}
+	return gb.sched.now - start
+}
 
-		// DEBUG
-		// if gb.debugMode {
-		// 	gb.logIOSerialTransfers()
-		// }
+// RunFrame runs the console until the PPU completes a frame, and returns it.
+// While the LCD is off, no frames are completed, so it returns after running
+// for as long as a frame takes instead. Call 'Close' once done running.
+func (gb *GameBoy) RunFrame() *Frame {
+	start, frames := gb.sched.now, gb.ppu.frames
+	for gb.ppu.frames == frames {
+		if !gb.ppu.enabled() && gb.sched.now-start >= DOTS_PER_FRAME {
+			break
+		}
+		gb.step()
 	}
+	return gb.Frame()
+}
+
+// step runs the CPU for one step, writing battery-backed cartridge RAM to the
+// save file every SAVE_FLUSH_INTERVAL
+func (gb *GameBoy) step() {
+	gb.Cpu.execNextInst()
+
+	if gb.Cpu.cycles-gb.lastFlush >= SAVE_FLUSH_INTERVAL {
+		if err := gb.flushSave(); err != nil {
+			gb.logger.Println(err)
+		}
+	}
+}
+
+// Frame returns the most recently completed LCD frame
+func (gb *GameBoy) Frame() *Frame {
+	return &gb.ppu.output
+}
+
+// Stop "powers off" the GameBoy console, ending CPU execution started by
+// 'Start'
+func (gb *GameBoy) Stop() {
+	gb.isRunning = false
 }
 
 // log logs a message to the GameBoy's logger
@@ -80,52 +291,77 @@ func (gb *GameBoy) log(msg string) {
 	gb.tw.Flush()
 }
 
-// logIOSerialTransfers logs write to memory at `IO_SB` or `IO_SC`
-func (gb *GameBoy) logIOSerialTransfers() {
-	iosb := gb.CartRom[IO_SB]
-	iosc := gb.CartRom[IO_SC]
-
-	if gb.debugState.iosb != iosb {
-		gb.debugState.iosb = iosb
-		gb.logger.Printf("[DEBUG_IO] IO_SB (%#04x): %#02x\n", IO_SB, iosb)
-	}
-	if gb.debugState.iosc != iosc {
-		gb.debugState.iosc = iosc
-		gb.logger.Printf("[DEBUG_IO] IO_SC (%#04x): %#02x\n", IO_SC, iosc)
+// insertCartridge inserts the given cartridge, mapping its ROM and RAM into
+// the CPU address space through its memory bank controller
+func (gb *GameBoy) insertCartridge(cart *Cartridge) error {
+	mbc, err := newMBC(cart, gb.clock, gb.setRumble)
+	if err != nil {
+		return err
 	}
+
+	gb.Cartridge = cart
+	gb.mmu.cart = mbc
+	return nil
 }
 
-// TODO: accept a cartridge type, extract loading the file from this method
-func (gb *GameBoy) insertCartridge(romPath string) error {
-	cartRom, err := os.ReadFile(romPath)
-	if err != nil {
-		return errGameFilepathNotFound
+// Rumbling returns whether the cartridge's rumble motor is on
+func (gb *GameBoy) Rumbling() bool {
+	return gb.rumbling
+}
+
+// setRumble sets the state of the cartridge's rumble motor, notifying the
+// rumble handler of changes
+func (gb *GameBoy) setRumble(on bool) {
+	if on == gb.rumbling {
+		return
 	}
 
-	// XXX: for now, copy the entire file into memory
-	gb.CartRom = make([]byte, len(cartRom))
-	for i, data := range cartRom {
-		gb.CartRom[i] = data
+	gb.rumbling = on
+	if gb.rumbleHandler != nil {
+		gb.rumbleHandler(on)
 	}
-	return nil
 }
 
-// initPowerUpSequence performs the DMG boot sequence, leaving the CPU ready to
-// begin executing the loaded game ROM
+// initPowerUpSequence performs the boot sequence of the console's model,
+// leaving the CPU ready to begin executing the loaded game ROM
 // 	reference: https://gbdev.io/pandocs/Power_Up_Sequence.html
 func (gb *GameBoy) initPowerUpSequence() {
 	// CPU
-	gb.Cpu.AF.setHi(0x01)
-	gb.Cpu.AF.setLo(0b10000000) // TODO: calculate bits 4 and 5 (see ref.)
-	gb.Cpu.BC.set(0x0013)
-	gb.Cpu.DE.set(0x00D8)
-	gb.Cpu.HL.set(0x014D)
+	regs := powerUpRegisters(gb.model, gb.Cartridge)
+	gb.Cpu.AF.set(regs.af)
+	gb.Cpu.BC.set(regs.bc)
+	gb.Cpu.DE.set(regs.de)
+	gb.Cpu.HL.set(regs.hl)
 	gb.Cpu.PC = ENTRY_POINT
 	gb.Cpu.SP = 0xFFFE
 
-	// Hardware registers
+	// Hardware registers, in address order, as the order of some writes
+	// matters (e.g. a sound channel's envelope before its trigger)
+	values := make(map[uint16]byte, len(hardwareRegisterInit))
 	for addr, val := range hardwareRegisterInit {
-		gb.CartRom[addr] = val
+		values[addr] = val
+	}
+	for addr, val := range hardwareRegisterModelInit[gb.model] {
+		values[addr] = val
+	}
+	addrs := make([]int, 0, len(values))
+	for addr := range values {
+		addrs = append(addrs, int(addr))
+	}
+	sort.Ints(addrs)
+	for _, a := range addrs {
+		addr, val := uint16(a), values[uint16(a)]
+		// Writing DMA would start a transfer, and writing DIV resets it
+		switch addr {
+		case IO_DMA:
+			gb.mmu.dma.value = val
+			continue
+		case IO_DIV:
+			gb.mmu.timer.counter = uint16(val) << 8
+			gb.sched.sync(EVENT_TIMER)
+			continue
+		}
+		gb.mmu.write(addr, val)
 	}
 
 	gb.isRunning = true
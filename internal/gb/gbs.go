@@ -0,0 +1,255 @@
+package gb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// GBS file layout
+const (
+	GBS_HEADER_SIZE = 0x70
+
+	GBS_MAGIC       = 0x00 // "GBS"
+	GBS_VERSION     = 0x03
+	GBS_SONGS       = 0x04
+	GBS_FIRST_SONG  = 0x05 // 1-based
+	GBS_LOAD_ADDR   = 0x06 // 16-bit little endian
+	GBS_INIT_ADDR   = 0x08
+	GBS_PLAY_ADDR   = 0x0A
+	GBS_STACK       = 0x0C
+	GBS_TMA         = 0x0E
+	GBS_TAC         = 0x0F
+	GBS_TITLE       = 0x10 // 32 bytes, each
+	GBS_AUTHOR      = 0x30
+	GBS_COPYRIGHT   = 0x50
+	GBS_STRING_SIZE = 0x20
+)
+
+// Addresses of the GBS player's code, mapped in front of the payload
+const (
+	GBS_PLAYER_START = 0x0100
+	GBS_PLAYER_END   = 0x03FF // lowest allowed load address - 1
+)
+
+// CYCLES_PER_FRAME is the number of machine cycles between VBlank interrupts
+const CYCLES_PER_FRAME = DOTS_PER_FRAME / 4
+
+// GBSHeader is the header of a GBS (Game Boy Sound) file, describing how to
+// play the music code ripped from a game
+//
+// reference: https://gbdev.gg8.se/wiki/articles/Gameboy_sound_system
+type GBSHeader struct {
+	Version   byte
+	Songs     int
+	FirstSong int // 1-based
+
+	LoadAddr uint16 // address the payload is mapped at
+	InitAddr uint16 // routine starting a song, called with its 0-based number in A
+	PlayAddr uint16 // routine called on every VBlank or timer interrupt
+	SP       uint16 // initial stack pointer
+
+	TMA byte
+	TAC byte // if bit 2 is set, PLAY is called on the timer interrupt
+
+	Title     string
+	Author    string
+	Copyright string
+}
+
+// GBS is a parsed GBS file: its header and the code and data payload
+type GBS struct {
+	Header  GBSHeader
+	payload []byte
+}
+
+// LoadGBS reads the GBS file at the given path
+func LoadGBS(path string) (*GBS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read GBS file: %w", err)
+	}
+
+	gbs, err := NewGBS(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return gbs, nil
+}
+
+// NewGBS parses the given GBS file contents
+func NewGBS(data []byte) (*GBS, error) {
+	if len(data) < GBS_HEADER_SIZE || string(data[GBS_MAGIC:GBS_MAGIC+3]) != "GBS" {
+		return nil, errGBSHeader
+	}
+
+	u16 := func(i int) uint16 {
+		return binary.LittleEndian.Uint16(data[i:])
+	}
+	str := func(i int) string {
+		s := data[i : i+GBS_STRING_SIZE]
+		if n := bytes.IndexByte(s, 0); n >= 0 {
+			s = s[:n]
+		}
+		return string(s)
+	}
+	h := GBSHeader{
+		Version:   data[GBS_VERSION],
+		Songs:     int(data[GBS_SONGS]),
+		FirstSong: int(data[GBS_FIRST_SONG]),
+		LoadAddr:  u16(GBS_LOAD_ADDR),
+		InitAddr:  u16(GBS_INIT_ADDR),
+		PlayAddr:  u16(GBS_PLAY_ADDR),
+		SP:        u16(GBS_STACK),
+		TMA:       data[GBS_TMA],
+		TAC:       data[GBS_TAC],
+		Title:     str(GBS_TITLE),
+		Author:    str(GBS_AUTHOR),
+		Copyright: str(GBS_COPYRIGHT),
+	}
+
+	if h.Version != 1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", errGBSHeader, h.Version)
+	}
+	if h.Songs == 0 || h.FirstSong < 1 || h.FirstSong > h.Songs {
+		return nil, fmt.Errorf("%w: first song %d of %d", errGBSHeader, h.FirstSong, h.Songs)
+	}
+	if h.LoadAddr <= GBS_PLAYER_END || h.LoadAddr > CARTRIDGE_ROM_01_END {
+		return nil, fmt.Errorf("%w (%#04x)", errGBSLoadAddress, h.LoadAddr)
+	}
+
+	return &GBS{Header: h, payload: data[GBS_HEADER_SIZE:]}, nil
+}
+
+// rom returns the ROM image the GBS file is played from. The payload is
+// mapped at its load address, and the RST vectors jump to the same offsets
+// from it. The VBlank and timer interrupt vectors call PLAY, and the player
+// code calls INIT with the given 0-based song number before idling, waiting
+// for interrupts. ROM banks are switched by writing to 0x2000-0x3FFF, as on
+// an MBC5.
+func (g *GBS) rom(song int) []byte {
+	h := g.Header
+	rom := make([]byte, int(h.LoadAddr)+len(g.payload))
+	copy(rom[h.LoadAddr:], g.payload)
+
+	for rst := uint16(0x00); rst <= 0x38; rst += 0x08 {
+		rom[rst] = 0xC3 // JP a16
+		binary.LittleEndian.PutUint16(rom[rst+1:], h.LoadAddr+rst)
+	}
+	for _, vector := range []uint16{interruptVector[INT_VBLANK], interruptVector[INT_TIMER]} {
+		rom[vector] = 0xCD // CALL a16
+		binary.LittleEndian.PutUint16(rom[vector+1:], h.PlayAddr)
+		rom[vector+3] = 0xD9 // RETI
+	}
+
+	player := []byte{
+		0x3E, byte(song), // LD A,song
+		0xCD, byte(h.InitAddr), byte(h.InitAddr >> 8), // CALL INIT
+		0xFB,       // EI
+		0x76,       // HALT
+		0x18, 0xFD, // JR -3 (HALT)
+	}
+	copy(rom[GBS_PLAYER_START:], player)
+	return rom
+}
+
+// String returns a description of the GBS file
+func (g *GBS) String() string {
+	h := g.Header
+
+	trigger := "VBlank"
+	if h.TAC&TAC_ENABLE > 0 {
+		trigger = fmt.Sprintf("Timer (TMA %#02x, TAC %#02x)", h.TMA, h.TAC)
+	}
+
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Title:\t%s\n", h.Title)
+	fmt.Fprintf(tw, "Author:\t%s\n", h.Author)
+	fmt.Fprintf(tw, "Copyright:\t%s\n", h.Copyright)
+	fmt.Fprintf(tw, "Songs:\t%d (first: %d)\n", h.Songs, h.FirstSong)
+	fmt.Fprintf(tw, "Load address:\t%#04x\n", h.LoadAddr)
+	fmt.Fprintf(tw, "Init address:\t%#04x\n", h.InitAddr)
+	fmt.Fprintf(tw, "Play address:\t%#04x\n", h.PlayAddr)
+	fmt.Fprintf(tw, "Stack pointer:\t%#04x\n", h.SP)
+	fmt.Fprintf(tw, "Play on:\t%s\n", trigger)
+	tw.Flush()
+
+	return sb.String()
+}
+
+// GBSPlayer plays the songs in a GBS file, using the CPU, timer and APU of an
+// emulated console. The LCD stays off, so the PPU does no work: VBlank
+// interrupts are requested by the player instead.
+type GBSPlayer struct {
+	GBS *GBS
+
+	opts []Option
+	gb   *GameBoy
+
+	nextVBlank int // cycle count of the next VBlank interrupt
+}
+
+// NewGBSPlayer returns a player for the given GBS file. The options configure
+// the console songs are played on, e.g. 'WithAudio' to hear them.
+func NewGBSPlayer(gbs *GBS, opts ...Option) *GBSPlayer {
+	return &GBSPlayer{GBS: gbs, opts: opts}
+}
+
+// Play starts the given song, numbered from 1, on a freshly powered-on
+// console
+func (p *GBSPlayer) Play(song int) error {
+	h := p.GBS.Header
+	if song < 1 || song > h.Songs {
+		return fmt.Errorf("%w: %d of %d", errGBSSong, song, h.Songs)
+	}
+
+	gb := newGameBoy(false, p.opts...)
+	mbc := newMBC5(p.GBS.rom(song-1), RAM_BANK_SIZE, nil)
+	mbc.write(0x0000, 0x0A) // Enable RAM
+	gb.mmu.cart = mbc
+
+	gb.Cpu.PC = GBS_PLAYER_START
+	gb.Cpu.SP = h.SP
+	gb.mmu.write(IO_NR52, NR52_POWER)
+	gb.mmu.write(IO_NR51, 0xFF)
+	gb.mmu.write(IO_NR50, 0x77)
+	gb.mmu.write(IO_TMA, h.TMA)
+	gb.mmu.write(IO_TIMA, h.TMA)
+	gb.mmu.write(IO_TAC, h.TAC&(TAC_ENABLE|TAC_CLOCK_SELECT))
+	if h.TAC&TAC_ENABLE > 0 {
+		gb.mmu.write(INTERRUPT_ENABLE, byte(INT_TIMER))
+	} else {
+		gb.mmu.write(INTERRUPT_ENABLE, byte(INT_VBLANK))
+	}
+
+	p.gb = gb
+	p.nextVBlank = CYCLES_PER_FRAME
+	return nil
+}
+
+// Run plays the current song for at least the given number of machine cycles
+func (p *GBSPlayer) Run(cycles int) {
+	gb := p.gb
+	end := gb.Cpu.cycles + cycles
+	for gb.Cpu.cycles < end {
+		gb.Cpu.execNextInst()
+		if gb.Cpu.cycles >= p.nextVBlank {
+			p.nextVBlank += CYCLES_PER_FRAME
+			gb.requestInterrupt(INT_VBLANK)
+		}
+	}
+}
+
+// RecordWAV plays the given song, numbered from 1, for the given duration,
+// recording it to a 16-bit PCM stereo WAV file at the given path
+func (p *GBSPlayer) RecordWAV(path string, song int, duration time.Duration, sampleRate int) error {
+	if err := p.Play(song); err != nil {
+		return err
+	}
+	return recordWAV(path, duration, sampleRate, p.gb.mmu.apu, p.Run)
+}
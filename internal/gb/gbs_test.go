@@ -0,0 +1,151 @@
+package gb
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestGBS returns a GBS file with 3 songs. INIT stores the song number at
+// 0xC000 and starts a tone on channel 2, and PLAY counts its calls at 0xC001.
+func newTestGBS(tma, tac byte) []byte {
+	data := make([]byte, GBS_HEADER_SIZE+0x40)
+	copy(data[GBS_MAGIC:], "GBS")
+	data[GBS_VERSION] = 1
+	data[GBS_SONGS] = 3
+	data[GBS_FIRST_SONG] = 1
+	binary.LittleEndian.PutUint16(data[GBS_LOAD_ADDR:], 0x0400)
+	binary.LittleEndian.PutUint16(data[GBS_INIT_ADDR:], 0x0400)
+	binary.LittleEndian.PutUint16(data[GBS_PLAY_ADDR:], 0x0420)
+	binary.LittleEndian.PutUint16(data[GBS_STACK:], 0xFFFE)
+	data[GBS_TMA] = tma
+	data[GBS_TAC] = tac
+	copy(data[GBS_TITLE:], "Test Tunes")
+	copy(data[GBS_AUTHOR:], "Nobody")
+	copy(data[GBS_COPYRIGHT:], "2022 Nobody")
+
+	copy(data[GBS_HEADER_SIZE:], []byte{
+		0xEA, 0x00, 0xC0, // LD (0xC000),A
+		0x3E, 0xF0, 0xE0, 0x17, // LD A,0xF0; LDH (NR22),A
+		0x3E, 0x80, 0xE0, 0x16, // LD A,0x80; LDH (NR21),A
+		0x3E, 0x87, 0xE0, 0x19, // LD A,0x87; LDH (NR24),A
+		0xC9, // RET
+	})
+	copy(data[GBS_HEADER_SIZE+0x20:], []byte{
+		0x21, 0x01, 0xC0, // LD HL,0xC001
+		0x34, // INC (HL)
+		0xC9, // RET
+	})
+	return data
+}
+
+// TestGBSHeader checks that GBS headers are parsed, and invalid ones rejected
+func TestGBSHeader(t *testing.T) {
+	gbs, err := NewGBS(newTestGBS(0xC0, 0x04))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := GBSHeader{
+		Version:   1,
+		Songs:     3,
+		FirstSong: 1,
+		LoadAddr:  0x0400,
+		InitAddr:  0x0400,
+		PlayAddr:  0x0420,
+		SP:        0xFFFE,
+		TMA:       0xC0,
+		TAC:       0x04,
+		Title:     "Test Tunes",
+		Author:    "Nobody",
+		Copyright: "2022 Nobody",
+	}
+	if gbs.Header != want {
+		t.Errorf("header = %+v, want %+v", gbs.Header, want)
+	}
+
+	badMagic := newTestGBS(0, 0)
+	badMagic[0] = 'X'
+	lowLoad := newTestGBS(0, 0)
+	lowLoad[GBS_LOAD_ADDR+1] = 0x01
+	for name, c := range map[string]struct {
+		data []byte
+		err  error
+	}{
+		"bad magic":     {badMagic, errGBSHeader},
+		"too small":     {badMagic[:0x20], errGBSHeader},
+		"low load addr": {lowLoad, errGBSLoadAddress},
+	} {
+		if _, err := NewGBS(c.data); !errors.Is(err, c.err) {
+			t.Errorf("%s: err = %v, want %v", name, err, c.err)
+		}
+	}
+}
+
+// TestGBSPlayer checks that INIT is called with the song number, and PLAY on
+// every VBlank or timer interrupt
+func TestGBSPlayer(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		tma, tac byte
+		plays    byte
+	}{
+		{"VBlank", 0x00, 0x00, 59},
+		{"timer", 0xC0, TAC_ENABLE, 64}, // 4096 Hz / 64
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			gbs, err := NewGBS(newTestGBS(c.tma, c.tac))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := NewGBSPlayer(gbs)
+			if err := p.Play(2); err != nil {
+				t.Fatal(err)
+			}
+			// Just over 1 second, as the last timer interrupt lands on it
+			p.Run(CYCLES_PER_SECOND + 1000)
+
+			if got := p.gb.mmu.read(0xC000); got != 1 {
+				t.Errorf("INIT called with song %d, want 1", got)
+			}
+			if got := p.gb.mmu.read(0xC001); got != c.plays {
+				t.Errorf("PLAY called %d times in 1 second, want %d", got, c.plays)
+			}
+		})
+	}
+
+	gbs, _ := NewGBS(newTestGBS(0, 0))
+	if err := NewGBSPlayer(gbs).Play(4); !errors.Is(err, errGBSSong) {
+		t.Errorf("playing song 4 of 3: err = %v, want %v", err, errGBSSong)
+	}
+}
+
+// TestGBSRecordWAV checks that a song is recorded to a WAV file
+func TestGBSRecordWAV(t *testing.T) {
+	gbs, err := NewGBS(newTestGBS(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.wav")
+	if err := NewGBSPlayer(gbs).RecordWAV(path, 1, 100*time.Millisecond, 8000); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(data), WAV_HEADER_SIZE+800*4; got != want {
+		t.Errorf("file size = %d, want %d", got, want)
+	}
+	silent := true
+	for _, b := range data[WAV_HEADER_SIZE:] {
+		silent = silent && b == 0
+	}
+	if silent {
+		t.Error("recording is silent")
+	}
+}
@@ -1,5 +1,9 @@
 package gb
 
+// hardwareRegisterInit maps hardware register addresses to their values after
+// the DMG boot ROM
+//
+// reference: https://gbdev.io/pandocs/Power_Up_Sequence.html#hardware-registers
 var hardwareRegisterInit = map[uint16]byte{
 	0xFF00: 0xCF, // P1
 	0xFF01: 0x00, // SB
@@ -57,3 +61,29 @@ var hardwareRegisterInit = map[uint16]byte{
 	0xFF70: 0xFF, // SVBK
 	0xFFFF: 0x00, // IE
 }
+
+// hardwareRegisterModelInit maps each model to the hardware registers it
+// leaves in a different state from the DMG. Registers whose value is unknown
+// on a model are left as on the DMG.
+var hardwareRegisterModelInit = map[Model]map[uint16]byte{
+	MODEL_DMG0: {
+		0xFF04: 0x18, // DIV
+		0xFF41: 0x81, // STAT
+	},
+	MODEL_SGB: {
+		0xFF14: 0x3F, // NR14, no boot sound: channel 1 is not triggered
+		0xFF26: 0xF0, // NR52
+	},
+	MODEL_SGB2: {
+		0xFF14: 0x3F, // NR14
+		0xFF26: 0xF0, // NR52
+	},
+	MODEL_CGB: {
+		0xFF02: 0x7F, // SC
+		0xFF46: 0x00, // DMA
+	},
+	MODEL_AGB: {
+		0xFF02: 0x7F, // SC
+		0xFF46: 0x00, // DMA
+	},
+}
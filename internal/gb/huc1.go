@@ -0,0 +1,88 @@
+package gb
+
+// HUC1_IR_SELECT is the value written to 0x0000-0x1FFF to map the infrared
+// port to 0xA000-0xBFFF, in place of cartridge RAM
+const HUC1_IR_SELECT = 0x0E
+
+// huc1 is Hudson's HuC1 memory bank controller, supporting up to 1MB of ROM,
+// 32KB of RAM, and an infrared LED and receiver.
+//
+// reference: https://gbdev.io/pandocs/HuC1.html
+type huc1 struct {
+	rom []byte
+	ram []byte
+
+	romBanks int // number of 16KB ROM banks
+
+	irSelected bool // 0x0000-0x1FFF, maps the IR port in place of RAM
+	romBank    byte // 0x2000-0x3FFF, 6-bit ROM bank number
+	ramBank    byte // 0x4000-0x5FFF, 2-bit RAM bank number
+}
+
+// newHuC1 returns a HuC1 mapping the given ROM, and RAM of the given size in
+// bytes
+func newHuC1(rom []byte, ramSize int) *huc1 {
+	return &huc1{
+		rom:      rom,
+		ram:      make([]byte, ramSize),
+		romBanks: romBankCount(rom),
+		romBank:  1,
+	}
+}
+
+// ramOffset returns the offset in cartridge RAM of the given address
+func (m *huc1) ramOffset(addr uint16) int {
+	offset := int(m.ramBank)*RAM_BANK_SIZE + int(addr-CARTRIDGE_RAM_START)
+	return offset % len(m.ram)
+}
+
+func (m *huc1) read(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_00_END:
+		return readRomBank(m.rom, 0, addr)
+	case addr <= CARTRIDGE_ROM_01_END:
+		return readRomBank(m.rom, int(m.romBank)&(m.romBanks-1), addr)
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if m.irSelected {
+			// Bit 0 is set while the receiver sees light. There is no
+			// other cartridge to talk to, so it never does.
+			return 0xC0
+		}
+		if len(m.ram) == 0 {
+			return 0xFF
+		}
+		return m.ram[m.ramOffset(addr)]
+	}
+	return 0xFF
+}
+
+func (m *huc1) write(addr uint16, data byte) {
+	switch {
+	case addr <= 0x1FFF:
+		m.irSelected = data&0x0F == HUC1_IR_SELECT
+	case addr <= 0x3FFF:
+		m.romBank = data & 0x3F
+	case addr <= 0x5FFF:
+		m.ramBank = data & 0x03
+	case addr <= CARTRIDGE_ROM_01_END:
+		// Unused
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if m.irSelected {
+			// Bit 0 turns the LED on, which no other cartridge can see
+			return
+		}
+		if len(m.ram) > 0 {
+			m.ram[m.ramOffset(addr)] = data
+		}
+	}
+}
+
+// saveData returns the contents of cartridge RAM
+func (m *huc1) saveData() []byte {
+	return append([]byte(nil), m.ram...)
+}
+
+// loadSaveData restores cartridge RAM from the given save data
+func (m *huc1) loadSaveData(data []byte) error {
+	return loadRAM(m.ram, data)
+}
@@ -0,0 +1,264 @@
+package gb
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// HuC3 modes, written to 0x0000-0x1FFF to select what is mapped to
+// 0xA000-0xBFFF
+const (
+	HUC3_RAM_READ  = 0x00 // Cartridge RAM, read-only
+	HUC3_RAM       = 0x0A // Cartridge RAM, read/write
+	HUC3_RTC_CMD   = 0x0B // RTC command/argument, write
+	HUC3_RTC_RESP  = 0x0C // RTC command/response, read
+	HUC3_RTC_READY = 0x0D // RTC semaphore, read/write
+	HUC3_IR        = 0x0E // Infrared port
+)
+
+// HuC3 RTC commands, written to the upper nibble in HUC3_RTC_CMD mode
+const (
+	HUC3_CMD_READ     = 0x1 // Read the value at the access address, and increment
+	HUC3_CMD_WRITE    = 0x3 // Write the argument to the access address, and increment
+	HUC3_CMD_ADDR_LO  = 0x4 // Set the access address lower nibble
+	HUC3_CMD_ADDR_HI  = 0x5 // Set the access address upper nibble
+	HUC3_CMD_EXTENDED = 0x6 // Run the extended command given by the argument
+)
+
+// HUC3_RTC_SAVE_SIZE is the size of the real-time clock state stored after the
+// battery-backed RAM in save files
+const HUC3_RTC_SAVE_SIZE = 272
+
+// huc3 is Hudson's HuC3 memory bank controller, supporting up to 2MB of ROM,
+// 32KB of RAM, a real-time clock, and an infrared port.
+//
+// reference: https://gbdev.io/pandocs/HuC3.html
+type huc3 struct {
+	rom []byte
+	ram []byte
+
+	romBanks int // number of 16KB ROM banks
+
+	mode    byte // 0x0000-0x1FFF, see HUC3_* modes
+	romBank byte // 0x2000-0x3FFF, 7-bit ROM bank number
+	ramBank byte // 0x4000-0x5FFF, 2-bit RAM bank number
+
+	rtc *huc3RTC
+}
+
+// newHuC3 returns a HuC3 mapping the given ROM, RAM of the given size in
+// bytes, and a real-time clock driven by the given clock
+func newHuC3(rom []byte, ramSize int, clock Clock) *huc3 {
+	return &huc3{
+		rom:      rom,
+		ram:      make([]byte, ramSize),
+		romBanks: romBankCount(rom),
+		romBank:  1,
+		rtc:      newHuC3RTC(clock),
+	}
+}
+
+// ramOffset returns the offset in cartridge RAM of the given address
+func (m *huc3) ramOffset(addr uint16) int {
+	offset := int(m.ramBank)*RAM_BANK_SIZE + int(addr-CARTRIDGE_RAM_START)
+	return offset % len(m.ram)
+}
+
+func (m *huc3) read(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_00_END:
+		return readRomBank(m.rom, 0, addr)
+	case addr <= CARTRIDGE_ROM_01_END:
+		return readRomBank(m.rom, int(m.romBank)&(m.romBanks-1), addr)
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		switch m.mode {
+		case HUC3_RAM_READ, HUC3_RAM:
+			if len(m.ram) == 0 {
+				return 0xFF
+			}
+			return m.ram[m.ramOffset(addr)]
+		case HUC3_RTC_RESP:
+			return 0x80 | m.rtc.command<<4 | m.rtc.response
+		case HUC3_RTC_READY:
+			// Commands complete immediately, so the clock is always ready
+			return 0xFF
+		case HUC3_IR:
+			// No other cartridge to talk to, so no light is ever seen
+			return 0xC0
+		}
+	}
+	return 0xFF
+}
+
+func (m *huc3) write(addr uint16, data byte) {
+	switch {
+	case addr <= 0x1FFF:
+		m.mode = data & 0x0F
+	case addr <= 0x3FFF:
+		m.romBank = data & 0x7F
+	case addr <= 0x5FFF:
+		m.ramBank = data & 0x03
+	case addr <= CARTRIDGE_ROM_01_END:
+		// Unused
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		switch m.mode {
+		case HUC3_RAM:
+			if len(m.ram) > 0 {
+				m.ram[m.ramOffset(addr)] = data
+			}
+		case HUC3_RTC_CMD:
+			m.rtc.execute((data>>4)&0x07, data&0x0F)
+		case HUC3_IR:
+			// Bit 0 turns the LED on, which no other cartridge can see
+		}
+	}
+}
+
+// saveData returns the contents of cartridge RAM, followed by the real-time
+// clock state
+func (m *huc3) saveData() []byte {
+	data := append([]byte(nil), m.ram...)
+	return append(data, m.rtc.marshal()...)
+}
+
+// clockSaveSize returns the size of the real-time clock state at the end of
+// the save data
+func (m *huc3) clockSaveSize() int {
+	return HUC3_RTC_SAVE_SIZE
+}
+
+// clockSet returns whether the game has written the real-time clock's time or
+// memory since the last call
+func (m *huc3) clockSet() bool {
+	set := m.rtc.set
+	m.rtc.set = false
+	return set
+}
+
+// loadSaveData restores cartridge RAM, and the real-time clock state if
+// present, from the given save data
+func (m *huc3) loadSaveData(data []byte) error {
+	if len(data) == len(m.ram)+HUC3_RTC_SAVE_SIZE {
+		if err := m.rtc.unmarshal(data[len(m.ram):]); err != nil {
+			return err
+		}
+		data = data[:len(m.ram)]
+	}
+	return loadRAM(m.ram, data)
+}
+
+// huc3RTC is the HuC3 real-time clock. It counts minutes of the day and days,
+// and is accessed through a small nibble-addressed memory using commands.
+// Extended commands copy the time to and from the start of that memory: the
+// minute count in nibbles 0x00-0x02, and the day count in nibbles 0x03-0x05,
+// least significant nibble first.
+type huc3RTC struct {
+	clock      Clock
+	lastUpdate time.Time // time the counters were last advanced to
+
+	minutes uint16 // minute of the day, 0-1439
+	days    uint16 // 12-bit day counter
+
+	memory   [0x100]byte // 4-bit values
+	address  byte        // access address
+	command  byte        // last command
+	response byte        // last response nibble
+
+	set bool // time or memory written since 'clockSet' was last called
+}
+
+// newHuC3RTC returns a HuC3 real-time clock driven by the given clock
+func newHuC3RTC(clock Clock) *huc3RTC {
+	return &huc3RTC{clock: clock, lastUpdate: clock.Now()}
+}
+
+// update advances the clock by the whole minutes elapsed since the last update
+func (r *huc3RTC) update() {
+	now := r.clock.Now()
+	if now.Before(r.lastUpdate) {
+		r.lastUpdate = now
+		return
+	}
+
+	elapsed := int64(now.Sub(r.lastUpdate) / time.Minute)
+	r.lastUpdate = r.lastUpdate.Add(time.Duration(elapsed) * time.Minute)
+
+	total := int64(r.days)*1440 + int64(r.minutes) + elapsed
+	r.minutes = uint16(total % 1440)
+	r.days = uint16(total/1440) & 0x0FFF
+}
+
+// marshal returns the clock state: the minute and day counters as 32-bit
+// little endian values, the clock's memory, one nibble per byte, and the
+// 64-bit UNIX timestamp the counters were last updated at
+func (r *huc3RTC) marshal() []byte {
+	r.update()
+
+	data := make([]byte, HUC3_RTC_SAVE_SIZE)
+	binary.LittleEndian.PutUint32(data[0:], uint32(r.minutes))
+	binary.LittleEndian.PutUint32(data[4:], uint32(r.days))
+	copy(data[8:], r.memory[:])
+	binary.LittleEndian.PutUint64(data[8+len(r.memory):], uint64(r.lastUpdate.Unix()))
+	return data
+}
+
+// unmarshal restores the clock state from the format returned by 'marshal',
+// advancing the clock by the time passed since it was saved
+func (r *huc3RTC) unmarshal(data []byte) error {
+	if len(data) != HUC3_RTC_SAVE_SIZE {
+		return errRTCSaveSize
+	}
+
+	r.minutes = uint16(binary.LittleEndian.Uint32(data[0:]) % 1440)
+	r.days = uint16(binary.LittleEndian.Uint32(data[4:])) & 0x0FFF
+	for i := range r.memory {
+		r.memory[i] = data[8+i] & 0x0F
+	}
+	r.lastUpdate = time.Unix(int64(binary.LittleEndian.Uint64(data[8+len(r.memory):])), 0)
+
+	r.update()
+	return nil
+}
+
+// execute runs the given RTC command with the given 4-bit argument
+func (r *huc3RTC) execute(command, arg byte) {
+	r.command = command
+
+	switch command {
+	case HUC3_CMD_READ:
+		r.response = r.memory[r.address]
+		r.address++
+	case HUC3_CMD_WRITE:
+		r.memory[r.address] = arg
+		r.address++
+		r.set = true
+	case HUC3_CMD_ADDR_LO:
+		r.address = r.address&0xF0 | arg
+	case HUC3_CMD_ADDR_HI:
+		r.address = r.address&0x0F | arg<<4
+	case HUC3_CMD_EXTENDED:
+		switch arg {
+		case 0x0:
+			// Copy the current time to memory
+			r.update()
+			for i := 0; i < 3; i++ {
+				r.memory[i] = byte(r.minutes>>(i*4)) & 0x0F
+				r.memory[3+i] = byte(r.days>>(i*4)) & 0x0F
+			}
+		case 0x1:
+			// Set the current time from memory
+			var minutes, days uint16
+			for i := 0; i < 3; i++ {
+				minutes |= uint16(r.memory[i]) << (i * 4)
+				days |= uint16(r.memory[3+i]) << (i * 4)
+			}
+			r.minutes = minutes % 1440
+			r.days = days
+			r.lastUpdate = r.clock.Now()
+			r.set = true
+		case 0x2:
+			// Status check, responds with 1 when the clock is working
+			r.response = 0x1
+		}
+	}
+}
@@ -2,145 +2,315 @@ package gb
 
 // inst represents a CPU inst and is used to lookup inst
 // byte length, number of cycles required, and the method used to execute the
-// inst
+// inst. Conditional jumps, calls and returns take longer when their branch is
+// taken; every other instruction takes the same number of cycles either way.
 type inst struct {
 	name   string // instruction mnemonic
 	length uint16 // number of bytes
 	cycles int    // number of machine cycles
+	taken  int    // number of machine cycles if a conditional branch is taken
 	exec   func() // method to execute instruction
 }
 
 // 0x00 - 0xFF
 const INSTRUCTION_COUNT = 0x100
 
-// instruction lookup arrays, used by the CPU during the decode stage
-var (
-	instructions = [INSTRUCTION_COUNT]inst{}
-)
-
 // setupInstuctionLookup defines all legal CPU instructions for the instruction
-// lookup array
+// lookup table. Each CPU has its own table, bound to its own methods.
 func (cpu *CPU) setupInstructionLookup() {
-	instructions[0x00] = inst{"NOP", 1, 1, cpu.op00}
-	instructions[0x01] = inst{"LD", 3, 3, cpu.op01}
-	instructions[0x02] = inst{"LD", 1, 2, cpu.op02}
-	instructions[0x03] = inst{"INC", 1, 2, cpu.op03}
-	instructions[0x05] = inst{"DEC", 1, 1, cpu.op05}
-	instructions[0x06] = inst{"LD", 2, 2, cpu.op06}
-	instructions[0x07] = inst{"RLCA", 1, 1, cpu.op07}
-	instructions[0x0A] = inst{"LD", 1, 2, cpu.op0A}
-	instructions[0x0E] = inst{"LD", 2, 2, cpu.op0E}
-	instructions[0x0F] = inst{"RRCA", 1, 1, cpu.op0F}
-	instructions[0x10] = inst{"STOP", 2, 1, cpu.op10}
-	instructions[0x11] = inst{"LD", 3, 3, cpu.op11}
-	instructions[0x12] = inst{"LD", 1, 2, cpu.op12}
-	instructions[0x13] = inst{"INC", 1, 2, cpu.op13}
-	instructions[0x18] = inst{"JR", 2, 3, cpu.op18}
-	instructions[0x1A] = inst{"LD", 1, 2, cpu.op1A}
-	instructions[0x1B] = inst{"DEC", 1, 2, cpu.op1B}
-	instructions[0x1C] = inst{"INC", 1, 1, cpu.op1C}
-	instructions[0x1D] = inst{"DEC", 1, 1, cpu.op1D}
-	instructions[0x1E] = inst{"LD", 2, 2, cpu.op1E}
-	instructions[0x1F] = inst{"RRA", 1, 1, cpu.op1F}
-	instructions[0x20] = inst{"JR", 2, 2, cpu.op20}
-	instructions[0x21] = inst{"LD", 3, 3, cpu.op21}
-	instructions[0x22] = inst{"LD", 1, 2, cpu.op22}
-	instructions[0x23] = inst{"INC", 1, 2, cpu.op23}
-	instructions[0x24] = inst{"INC", 1, 1, cpu.op24}
-	instructions[0x25] = inst{"DEC", 1, 1, cpu.op25}
-	instructions[0x26] = inst{"LD", 2, 2, cpu.op26}
-	instructions[0x28] = inst{"JR", 2, 2, cpu.op28}
-	instructions[0x29] = inst{"ADD", 1, 2, cpu.op29}
-	instructions[0x2A] = inst{"LD", 1, 2, cpu.op2A}
-	instructions[0x2C] = inst{"INC", 1, 1, cpu.op2C}
-	instructions[0x2D] = inst{"DEC", 1, 1, cpu.op2D}
-	instructions[0x2F] = inst{"CPL", 1, 1, cpu.op2F}
-	instructions[0x30] = inst{"JR", 2, 2, cpu.op30}
-	instructions[0x31] = inst{"LD", 3, 3, cpu.op31}
-	instructions[0x32] = inst{"LD", 1, 2, cpu.op32}
-	instructions[0x35] = inst{"DEC", 1, 3, cpu.op35}
-	instructions[0x36] = inst{"LD", 2, 3, cpu.op36}
-	instructions[0x38] = inst{"JR", 2, 2, cpu.op38}
-	instructions[0x3A] = inst{"LD", 1, 2, cpu.op3A}
-	instructions[0x3C] = inst{"INC", 1, 1, cpu.op3C}
-	instructions[0x3D] = inst{"DEC", 1, 1, cpu.op3D}
-	instructions[0x3E] = inst{"LD", 2, 2, cpu.op3E}
-	instructions[0x46] = inst{"LD", 1, 2, cpu.op46}
-	instructions[0x47] = inst{"LD", 1, 1, cpu.op47}
-	instructions[0x4E] = inst{"LD", 1, 2, cpu.op4E}
-	instructions[0x4F] = inst{"LD", 1, 1, cpu.op4F}
-	instructions[0x56] = inst{"LD", 1, 2, cpu.op56}
-	instructions[0x57] = inst{"LD", 1, 1, cpu.op57}
-	instructions[0x5D] = inst{"LD", 1, 1, cpu.op5D}
-	instructions[0x5F] = inst{"LD", 1, 1, cpu.op5F}
-	instructions[0x60] = inst{"LD", 1, 1, cpu.op60}
-	instructions[0x62] = inst{"LD", 1, 1, cpu.op62}
-	instructions[0x63] = inst{"LD", 1, 1, cpu.op63}
-	instructions[0x66] = inst{"LD", 1, 2, cpu.op66}
-	instructions[0x67] = inst{"LD", 1, 1, cpu.op67}
-	instructions[0x69] = inst{"LD", 1, 1, cpu.op69}
-	instructions[0x6B] = inst{"LD", 1, 1, cpu.op6B}
-	instructions[0x6C] = inst{"LD", 1, 1, cpu.op6C}
-	instructions[0x6E] = inst{"LD", 1, 2, cpu.op6E}
-	instructions[0x6F] = inst{"LD", 1, 1, cpu.op6F}
-	instructions[0x70] = inst{"LD", 1, 2, cpu.op70}
-	instructions[0x71] = inst{"LD", 1, 2, cpu.op71}
-	instructions[0x72] = inst{"LD", 1, 2, cpu.op72}
-	instructions[0x73] = inst{"LD", 1, 2, cpu.op73}
-	instructions[0x74] = inst{"LD", 1, 2, cpu.op74}
-	instructions[0x75] = inst{"LD", 1, 2, cpu.op75}
-	instructions[0x76] = inst{"HALT", 1, 1, cpu.op76}
-	instructions[0x77] = inst{"LD", 1, 2, cpu.op77}
-	instructions[0x78] = inst{"LD", 1, 1, cpu.op78}
-	instructions[0x79] = inst{"LD", 1, 1, cpu.op79}
-	instructions[0x7A] = inst{"LD", 1, 1, cpu.op7A}
-	instructions[0x7B] = inst{"LD", 1, 1, cpu.op7B}
-	instructions[0x7C] = inst{"LD", 1, 1, cpu.op7C}
-	instructions[0x7D] = inst{"LD", 1, 1, cpu.op7D}
-	instructions[0x7E] = inst{"LD", 1, 2, cpu.op7E}
-	instructions[0x80] = inst{"ADD", 1, 1, cpu.op80}
-	instructions[0x8E] = inst{"ADC", 1, 2, cpu.op8E}
-	instructions[0x93] = inst{"SUB", 1, 1, cpu.op93}
-	instructions[0xA3] = inst{"AND", 1, 1, cpu.opA3}
-	instructions[0xA9] = inst{"XOR", 1, 1, cpu.opA9}
-	instructions[0xAE] = inst{"XOR", 1, 2, cpu.opAE}
-	instructions[0xB1] = inst{"OR", 1, 1, cpu.opB1}
-	instructions[0xB6] = inst{"OR", 1, 2, cpu.opB6}
-	instructions[0xB7] = inst{"OR", 1, 1, cpu.opB7}
-	instructions[0xC0] = inst{"RET", 1, 2, cpu.opC0}
-	instructions[0xC1] = inst{"POP", 1, 3, cpu.opC1}
-	instructions[0xC3] = inst{"JP", 3, 4, cpu.opC3}
-	instructions[0xC4] = inst{"CALL", 3, 3, cpu.opC4}
-	instructions[0xC5] = inst{"PUSH", 1, 4, cpu.opC5}
-	instructions[0xC6] = inst{"ADD", 2, 2, cpu.opC6}
-	instructions[0xC8] = inst{"RET", 1, 2, cpu.opC8}
-	instructions[0xC9] = inst{"RET", 1, 4, cpu.opC9}
-	instructions[0xCA] = inst{"JP", 3, 3, cpu.opCA}
-	instructions[0xCB] = inst{"", 2, 1, cpu.opCB} // prefix 0xCB
-	instructions[0xCD] = inst{"CALL", 3, 6, cpu.opCD}
-	instructions[0xCE] = inst{"ADC", 2, 2, cpu.opCE}
-	instructions[0xD0] = inst{"RET", 1, 2, cpu.opD0}
-	instructions[0xD1] = inst{"POP", 1, 3, cpu.opD1}
-	instructions[0xD2] = inst{"JP", 3, 3, cpu.opD2}
-	instructions[0xD5] = inst{"PUSH", 1, 4, cpu.opD5}
-	instructions[0xD6] = inst{"SUB", 2, 2, cpu.opD6}
-	instructions[0xE0] = inst{"LDH", 2, 3, cpu.opE0}
-	instructions[0xE1] = inst{"POP", 1, 3, cpu.opE1}
-	instructions[0xE5] = inst{"PUSH", 1, 4, cpu.opE5}
-	instructions[0xE6] = inst{"AND", 2, 2, cpu.opE6}
-	instructions[0xEA] = inst{"LD", 3, 4, cpu.opEA}
-	instructions[0xEE] = inst{"XOR", 2, 2, cpu.opEE}
-	instructions[0xF0] = inst{"LDH", 2, 3, cpu.opF0}
-	instructions[0xF1] = inst{"POP", 1, 3, cpu.opF1}
-	instructions[0xF3] = inst{"DI", 1, 1, cpu.opF3}
-	instructions[0xF5] = inst{"PUSH", 1, 4, cpu.opF5}
-	instructions[0xF9] = inst{"LD", 1, 2, cpu.opF9}
-	instructions[0xFA] = inst{"LD", 3, 4, cpu.opFA}
-	instructions[0xFE] = inst{"CP", 2, 2, cpu.opFE}
-	instructions[0xFF] = inst{"RST", 1, 4, cpu.opFF}
+	cpu.instructions[0x00] = inst{"NOP", 1, 1, 1, cpu.op00}
+	cpu.instructions[0x01] = inst{"LD", 3, 3, 3, cpu.op01}
+	cpu.instructions[0x02] = inst{"LD", 1, 2, 2, cpu.op02}
+	cpu.instructions[0x03] = inst{"INC", 1, 2, 2, cpu.op03}
+	cpu.instructions[0x04] = inst{"INC", 1, 1, 1, cpu.op04}
+	cpu.instructions[0x05] = inst{"DEC", 1, 1, 1, cpu.op05}
+	cpu.instructions[0x06] = inst{"LD", 2, 2, 2, cpu.op06}
+	cpu.instructions[0x07] = inst{"RLCA", 1, 1, 1, cpu.op07}
+	cpu.instructions[0x08] = inst{"LD", 3, 5, 5, cpu.op08}
+	cpu.instructions[0x09] = inst{"ADD", 1, 2, 2, cpu.op09}
+	cpu.instructions[0x0A] = inst{"LD", 1, 2, 2, cpu.op0A}
+	cpu.instructions[0x0B] = inst{"DEC", 1, 2, 2, cpu.op0B}
+	cpu.instructions[0x0C] = inst{"INC", 1, 1, 1, cpu.op0C}
+	cpu.instructions[0x0D] = inst{"DEC", 1, 1, 1, cpu.op0D}
+	cpu.instructions[0x0E] = inst{"LD", 2, 2, 2, cpu.op0E}
+	cpu.instructions[0x0F] = inst{"RRCA", 1, 1, 1, cpu.op0F}
+	cpu.instructions[0x10] = inst{"STOP", 2, 1, 1, cpu.op10}
+	cpu.instructions[0x11] = inst{"LD", 3, 3, 3, cpu.op11}
+	cpu.instructions[0x12] = inst{"LD", 1, 2, 2, cpu.op12}
+	cpu.instructions[0x13] = inst{"INC", 1, 2, 2, cpu.op13}
+	cpu.instructions[0x14] = inst{"INC", 1, 1, 1, cpu.op14}
+	cpu.instructions[0x15] = inst{"DEC", 1, 1, 1, cpu.op15}
+	cpu.instructions[0x16] = inst{"LD", 2, 2, 2, cpu.op16}
+	cpu.instructions[0x17] = inst{"RLA", 1, 1, 1, cpu.op17}
+	cpu.instructions[0x18] = inst{"JR", 2, 3, 3, cpu.op18}
+	cpu.instructions[0x19] = inst{"ADD", 1, 2, 2, cpu.op19}
+	cpu.instructions[0x1A] = inst{"LD", 1, 2, 2, cpu.op1A}
+	cpu.instructions[0x1B] = inst{"DEC", 1, 2, 2, cpu.op1B}
+	cpu.instructions[0x1C] = inst{"INC", 1, 1, 1, cpu.op1C}
+	cpu.instructions[0x1D] = inst{"DEC", 1, 1, 1, cpu.op1D}
+	cpu.instructions[0x1E] = inst{"LD", 2, 2, 2, cpu.op1E}
+	cpu.instructions[0x1F] = inst{"RRA", 1, 1, 1, cpu.op1F}
+	cpu.instructions[0x20] = inst{"JR", 2, 2, 3, cpu.op20}
+	cpu.instructions[0x21] = inst{"LD", 3, 3, 3, cpu.op21}
+	cpu.instructions[0x22] = inst{"LD", 1, 2, 2, cpu.op22}
+	cpu.instructions[0x23] = inst{"INC", 1, 2, 2, cpu.op23}
+	cpu.instructions[0x24] = inst{"INC", 1, 1, 1, cpu.op24}
+	cpu.instructions[0x25] = inst{"DEC", 1, 1, 1, cpu.op25}
+	cpu.instructions[0x26] = inst{"LD", 2, 2, 2, cpu.op26}
+	cpu.instructions[0x27] = inst{"DAA", 1, 1, 1, cpu.op27}
+	cpu.instructions[0x28] = inst{"JR", 2, 2, 3, cpu.op28}
+	cpu.instructions[0x29] = inst{"ADD", 1, 2, 2, cpu.op29}
+	cpu.instructions[0x2A] = inst{"LD", 1, 2, 2, cpu.op2A}
+	cpu.instructions[0x2B] = inst{"DEC", 1, 2, 2, cpu.op2B}
+	cpu.instructions[0x2C] = inst{"INC", 1, 1, 1, cpu.op2C}
+	cpu.instructions[0x2D] = inst{"DEC", 1, 1, 1, cpu.op2D}
+	cpu.instructions[0x2E] = inst{"LD", 2, 2, 2, cpu.op2E}
+	cpu.instructions[0x2F] = inst{"CPL", 1, 1, 1, cpu.op2F}
+	cpu.instructions[0x30] = inst{"JR", 2, 2, 3, cpu.op30}
+	cpu.instructions[0x31] = inst{"LD", 3, 3, 3, cpu.op31}
+	cpu.instructions[0x32] = inst{"LD", 1, 2, 2, cpu.op32}
+	cpu.instructions[0x33] = inst{"INC", 1, 2, 2, cpu.op33}
+	cpu.instructions[0x34] = inst{"INC", 1, 3, 3, cpu.op34}
+	cpu.instructions[0x35] = inst{"DEC", 1, 3, 3, cpu.op35}
+	cpu.instructions[0x36] = inst{"LD", 2, 3, 3, cpu.op36}
+	cpu.instructions[0x37] = inst{"SCF", 1, 1, 1, cpu.op37}
+	cpu.instructions[0x38] = inst{"JR", 2, 2, 3, cpu.op38}
+	cpu.instructions[0x39] = inst{"ADD", 1, 2, 2, cpu.op39}
+	cpu.instructions[0x3A] = inst{"LD", 1, 2, 2, cpu.op3A}
+	cpu.instructions[0x3B] = inst{"DEC", 1, 2, 2, cpu.op3B}
+	cpu.instructions[0x3C] = inst{"INC", 1, 1, 1, cpu.op3C}
+	cpu.instructions[0x3D] = inst{"DEC", 1, 1, 1, cpu.op3D}
+	cpu.instructions[0x3E] = inst{"LD", 2, 2, 2, cpu.op3E}
+	cpu.instructions[0x3F] = inst{"CCF", 1, 1, 1, cpu.op3F}
+	cpu.instructions[0x40] = inst{"LD", 1, 1, 1, cpu.op40}
+	cpu.instructions[0x41] = inst{"LD", 1, 1, 1, cpu.op41}
+	cpu.instructions[0x42] = inst{"LD", 1, 1, 1, cpu.op42}
+	cpu.instructions[0x43] = inst{"LD", 1, 1, 1, cpu.op43}
+	cpu.instructions[0x44] = inst{"LD", 1, 1, 1, cpu.op44}
+	cpu.instructions[0x45] = inst{"LD", 1, 1, 1, cpu.op45}
+	cpu.instructions[0x46] = inst{"LD", 1, 2, 2, cpu.op46}
+	cpu.instructions[0x47] = inst{"LD", 1, 1, 1, cpu.op47}
+	cpu.instructions[0x48] = inst{"LD", 1, 1, 1, cpu.op48}
+	cpu.instructions[0x49] = inst{"LD", 1, 1, 1, cpu.op49}
+	cpu.instructions[0x4A] = inst{"LD", 1, 1, 1, cpu.op4A}
+	cpu.instructions[0x4B] = inst{"LD", 1, 1, 1, cpu.op4B}
+	cpu.instructions[0x4C] = inst{"LD", 1, 1, 1, cpu.op4C}
+	cpu.instructions[0x4D] = inst{"LD", 1, 1, 1, cpu.op4D}
+	cpu.instructions[0x4E] = inst{"LD", 1, 2, 2, cpu.op4E}
+	cpu.instructions[0x4F] = inst{"LD", 1, 1, 1, cpu.op4F}
+	cpu.instructions[0x50] = inst{"LD", 1, 1, 1, cpu.op50}
+	cpu.instructions[0x51] = inst{"LD", 1, 1, 1, cpu.op51}
+	cpu.instructions[0x52] = inst{"LD", 1, 1, 1, cpu.op52}
+	cpu.instructions[0x53] = inst{"LD", 1, 1, 1, cpu.op53}
+	cpu.instructions[0x54] = inst{"LD", 1, 1, 1, cpu.op54}
+	cpu.instructions[0x55] = inst{"LD", 1, 1, 1, cpu.op55}
+	cpu.instructions[0x56] = inst{"LD", 1, 2, 2, cpu.op56}
+	cpu.instructions[0x57] = inst{"LD", 1, 1, 1, cpu.op57}
+	cpu.instructions[0x58] = inst{"LD", 1, 1, 1, cpu.op58}
+	cpu.instructions[0x59] = inst{"LD", 1, 1, 1, cpu.op59}
+	cpu.instructions[0x5A] = inst{"LD", 1, 1, 1, cpu.op5A}
+	cpu.instructions[0x5B] = inst{"LD", 1, 1, 1, cpu.op5B}
+	cpu.instructions[0x5C] = inst{"LD", 1, 1, 1, cpu.op5C}
+	cpu.instructions[0x5D] = inst{"LD", 1, 1, 1, cpu.op5D}
+	cpu.instructions[0x5E] = inst{"LD", 1, 2, 2, cpu.op5E}
+	cpu.instructions[0x5F] = inst{"LD", 1, 1, 1, cpu.op5F}
+	cpu.instructions[0x60] = inst{"LD", 1, 1, 1, cpu.op60}
+	cpu.instructions[0x61] = inst{"LD", 1, 1, 1, cpu.op61}
+	cpu.instructions[0x62] = inst{"LD", 1, 1, 1, cpu.op62}
+	cpu.instructions[0x63] = inst{"LD", 1, 1, 1, cpu.op63}
+	cpu.instructions[0x64] = inst{"LD", 1, 1, 1, cpu.op64}
+	cpu.instructions[0x65] = inst{"LD", 1, 1, 1, cpu.op65}
+	cpu.instructions[0x66] = inst{"LD", 1, 2, 2, cpu.op66}
+	cpu.instructions[0x67] = inst{"LD", 1, 1, 1, cpu.op67}
+	cpu.instructions[0x68] = inst{"LD", 1, 1, 1, cpu.op68}
+	cpu.instructions[0x69] = inst{"LD", 1, 1, 1, cpu.op69}
+	cpu.instructions[0x6A] = inst{"LD", 1, 1, 1, cpu.op6A}
+	cpu.instructions[0x6B] = inst{"LD", 1, 1, 1, cpu.op6B}
+	cpu.instructions[0x6C] = inst{"LD", 1, 1, 1, cpu.op6C}
+	cpu.instructions[0x6D] = inst{"LD", 1, 1, 1, cpu.op6D}
+	cpu.instructions[0x6E] = inst{"LD", 1, 2, 2, cpu.op6E}
+	cpu.instructions[0x6F] = inst{"LD", 1, 1, 1, cpu.op6F}
+	cpu.instructions[0x70] = inst{"LD", 1, 2, 2, cpu.op70}
+	cpu.instructions[0x71] = inst{"LD", 1, 2, 2, cpu.op71}
+	cpu.instructions[0x72] = inst{"LD", 1, 2, 2, cpu.op72}
+	cpu.instructions[0x73] = inst{"LD", 1, 2, 2, cpu.op73}
+	cpu.instructions[0x74] = inst{"LD", 1, 2, 2, cpu.op74}
+	cpu.instructions[0x75] = inst{"LD", 1, 2, 2, cpu.op75}
+	cpu.instructions[0x76] = inst{"HALT", 1, 1, 1, cpu.op76}
+	cpu.instructions[0x77] = inst{"LD", 1, 2, 2, cpu.op77}
+	cpu.instructions[0x78] = inst{"LD", 1, 1, 1, cpu.op78}
+	cpu.instructions[0x79] = inst{"LD", 1, 1, 1, cpu.op79}
+	cpu.instructions[0x7A] = inst{"LD", 1, 1, 1, cpu.op7A}
+	cpu.instructions[0x7B] = inst{"LD", 1, 1, 1, cpu.op7B}
+	cpu.instructions[0x7C] = inst{"LD", 1, 1, 1, cpu.op7C}
+	cpu.instructions[0x7D] = inst{"LD", 1, 1, 1, cpu.op7D}
+	cpu.instructions[0x7E] = inst{"LD", 1, 2, 2, cpu.op7E}
+	cpu.instructions[0x7F] = inst{"LD", 1, 1, 1, cpu.op7F}
+	cpu.instructions[0x80] = inst{"ADD", 1, 1, 1, cpu.op80}
+	cpu.instructions[0x81] = inst{"ADD", 1, 1, 1, cpu.op81}
+	cpu.instructions[0x82] = inst{"ADD", 1, 1, 1, cpu.op82}
+	cpu.instructions[0x83] = inst{"ADD", 1, 1, 1, cpu.op83}
+	cpu.instructions[0x84] = inst{"ADD", 1, 1, 1, cpu.op84}
+	cpu.instructions[0x85] = inst{"ADD", 1, 1, 1, cpu.op85}
+	cpu.instructions[0x86] = inst{"ADD", 1, 2, 2, cpu.op86}
+	cpu.instructions[0x87] = inst{"ADD", 1, 1, 1, cpu.op87}
+	cpu.instructions[0x88] = inst{"ADC", 1, 1, 1, cpu.op88}
+	cpu.instructions[0x89] = inst{"ADC", 1, 1, 1, cpu.op89}
+	cpu.instructions[0x8A] = inst{"ADC", 1, 1, 1, cpu.op8A}
+	cpu.instructions[0x8B] = inst{"ADC", 1, 1, 1, cpu.op8B}
+	cpu.instructions[0x8C] = inst{"ADC", 1, 1, 1, cpu.op8C}
+	cpu.instructions[0x8D] = inst{"ADC", 1, 1, 1, cpu.op8D}
+	cpu.instructions[0x8E] = inst{"ADC", 1, 2, 2, cpu.op8E}
+	cpu.instructions[0x8F] = inst{"ADC", 1, 1, 1, cpu.op8F}
+	cpu.instructions[0x90] = inst{"SUB", 1, 1, 1, cpu.op90}
+	cpu.instructions[0x91] = inst{"SUB", 1, 1, 1, cpu.op91}
+	cpu.instructions[0x92] = inst{"SUB", 1, 1, 1, cpu.op92}
+	cpu.instructions[0x93] = inst{"SUB", 1, 1, 1, cpu.op93}
+	cpu.instructions[0x94] = inst{"SUB", 1, 1, 1, cpu.op94}
+	cpu.instructions[0x95] = inst{"SUB", 1, 1, 1, cpu.op95}
+	cpu.instructions[0x96] = inst{"SUB", 1, 2, 2, cpu.op96}
+	cpu.instructions[0x97] = inst{"SUB", 1, 1, 1, cpu.op97}
+	cpu.instructions[0x98] = inst{"SBC", 1, 1, 1, cpu.op98}
+	cpu.instructions[0x99] = inst{"SBC", 1, 1, 1, cpu.op99}
+	cpu.instructions[0x9A] = inst{"SBC", 1, 1, 1, cpu.op9A}
+	cpu.instructions[0x9B] = inst{"SBC", 1, 1, 1, cpu.op9B}
+	cpu.instructions[0x9C] = inst{"SBC", 1, 1, 1, cpu.op9C}
+	cpu.instructions[0x9D] = inst{"SBC", 1, 1, 1, cpu.op9D}
+	cpu.instructions[0x9E] = inst{"SBC", 1, 2, 2, cpu.op9E}
+	cpu.instructions[0x9F] = inst{"SBC", 1, 1, 1, cpu.op9F}
+	cpu.instructions[0xA0] = inst{"AND", 1, 1, 1, cpu.opA0}
+	cpu.instructions[0xA1] = inst{"AND", 1, 1, 1, cpu.opA1}
+	cpu.instructions[0xA2] = inst{"AND", 1, 1, 1, cpu.opA2}
+	cpu.instructions[0xA3] = inst{"AND", 1, 1, 1, cpu.opA3}
+	cpu.instructions[0xA4] = inst{"AND", 1, 1, 1, cpu.opA4}
+	cpu.instructions[0xA5] = inst{"AND", 1, 1, 1, cpu.opA5}
+	cpu.instructions[0xA6] = inst{"AND", 1, 2, 2, cpu.opA6}
+	cpu.instructions[0xA7] = inst{"AND", 1, 1, 1, cpu.opA7}
+	cpu.instructions[0xA8] = inst{"XOR", 1, 1, 1, cpu.opA8}
+	cpu.instructions[0xA9] = inst{"XOR", 1, 1, 1, cpu.opA9}
+	cpu.instructions[0xAA] = inst{"XOR", 1, 1, 1, cpu.opAA}
+	cpu.instructions[0xAB] = inst{"XOR", 1, 1, 1, cpu.opAB}
+	cpu.instructions[0xAC] = inst{"XOR", 1, 1, 1, cpu.opAC}
+	cpu.instructions[0xAD] = inst{"XOR", 1, 1, 1, cpu.opAD}
+	cpu.instructions[0xAE] = inst{"XOR", 1, 2, 2, cpu.opAE}
+	cpu.instructions[0xAF] = inst{"XOR", 1, 1, 1, cpu.opAF}
+	cpu.instructions[0xB0] = inst{"OR", 1, 1, 1, cpu.opB0}
+	cpu.instructions[0xB1] = inst{"OR", 1, 1, 1, cpu.opB1}
+	cpu.instructions[0xB2] = inst{"OR", 1, 1, 1, cpu.opB2}
+	cpu.instructions[0xB3] = inst{"OR", 1, 1, 1, cpu.opB3}
+	cpu.instructions[0xB4] = inst{"OR", 1, 1, 1, cpu.opB4}
+	cpu.instructions[0xB5] = inst{"OR", 1, 1, 1, cpu.opB5}
+	cpu.instructions[0xB6] = inst{"OR", 1, 2, 2, cpu.opB6}
+	cpu.instructions[0xB7] = inst{"OR", 1, 1, 1, cpu.opB7}
+	cpu.instructions[0xB8] = inst{"CP", 1, 1, 1, cpu.opB8}
+	cpu.instructions[0xB9] = inst{"CP", 1, 1, 1, cpu.opB9}
+	cpu.instructions[0xBA] = inst{"CP", 1, 1, 1, cpu.opBA}
+	cpu.instructions[0xBB] = inst{"CP", 1, 1, 1, cpu.opBB}
+	cpu.instructions[0xBC] = inst{"CP", 1, 1, 1, cpu.opBC}
+	cpu.instructions[0xBD] = inst{"CP", 1, 1, 1, cpu.opBD}
+	cpu.instructions[0xBE] = inst{"CP", 1, 2, 2, cpu.opBE}
+	cpu.instructions[0xBF] = inst{"CP", 1, 1, 1, cpu.opBF}
+	cpu.instructions[0xC0] = inst{"RET", 1, 2, 5, cpu.opC0}
+	cpu.instructions[0xC1] = inst{"POP", 1, 3, 3, cpu.opC1}
+	cpu.instructions[0xC2] = inst{"JP", 3, 3, 4, cpu.opC2}
+	cpu.instructions[0xC3] = inst{"JP", 3, 4, 4, cpu.opC3}
+	cpu.instructions[0xC4] = inst{"CALL", 3, 3, 6, cpu.opC4}
+	cpu.instructions[0xC5] = inst{"PUSH", 1, 4, 4, cpu.opC5}
+	cpu.instructions[0xC6] = inst{"ADD", 2, 2, 2, cpu.opC6}
+	cpu.instructions[0xC7] = inst{"RST", 1, 4, 4, cpu.opC7}
+	cpu.instructions[0xC8] = inst{"RET", 1, 2, 5, cpu.opC8}
+	cpu.instructions[0xC9] = inst{"RET", 1, 4, 4, cpu.opC9}
+	cpu.instructions[0xCA] = inst{"JP", 3, 3, 4, cpu.opCA}
+	cpu.instructions[0xCB] = inst{"PREFIX", 2, 2, 2, nil} // see cpu.prefixInstructions
+	cpu.instructions[0xCC] = inst{"CALL", 3, 3, 6, cpu.opCC}
+	cpu.instructions[0xCD] = inst{"CALL", 3, 6, 6, cpu.opCD}
+	cpu.instructions[0xCE] = inst{"ADC", 2, 2, 2, cpu.opCE}
+	cpu.instructions[0xCF] = inst{"RST", 1, 4, 4, cpu.opCF}
+	cpu.instructions[0xD0] = inst{"RET", 1, 2, 5, cpu.opD0}
+	cpu.instructions[0xD1] = inst{"POP", 1, 3, 3, cpu.opD1}
+	cpu.instructions[0xD2] = inst{"JP", 3, 3, 4, cpu.opD2}
+	cpu.instructions[0xD4] = inst{"CALL", 3, 3, 6, cpu.opD4}
+	cpu.instructions[0xD5] = inst{"PUSH", 1, 4, 4, cpu.opD5}
+	cpu.instructions[0xD6] = inst{"SUB", 2, 2, 2, cpu.opD6}
+	cpu.instructions[0xD7] = inst{"RST", 1, 4, 4, cpu.opD7}
+	cpu.instructions[0xD8] = inst{"RET", 1, 2, 5, cpu.opD8}
+	cpu.instructions[0xD9] = inst{"RETI", 1, 4, 4, cpu.opD9}
+	cpu.instructions[0xDA] = inst{"JP", 3, 3, 4, cpu.opDA}
+	cpu.instructions[0xDC] = inst{"CALL", 3, 3, 6, cpu.opDC}
+	cpu.instructions[0xDE] = inst{"SBC", 2, 2, 2, cpu.opDE}
+	cpu.instructions[0xDF] = inst{"RST", 1, 4, 4, cpu.opDF}
+	cpu.instructions[0xE0] = inst{"LDH", 2, 3, 3, cpu.opE0}
+	cpu.instructions[0xE1] = inst{"POP", 1, 3, 3, cpu.opE1}
+	cpu.instructions[0xE2] = inst{"LD", 1, 2, 2, cpu.opE2}
+	cpu.instructions[0xE5] = inst{"PUSH", 1, 4, 4, cpu.opE5}
+	cpu.instructions[0xE6] = inst{"AND", 2, 2, 2, cpu.opE6}
+	cpu.instructions[0xE7] = inst{"RST", 1, 4, 4, cpu.opE7}
+	cpu.instructions[0xE8] = inst{"ADD", 2, 4, 4, cpu.opE8}
+	cpu.instructions[0xE9] = inst{"JP", 1, 1, 1, cpu.opE9}
+	cpu.instructions[0xEA] = inst{"LD", 3, 4, 4, cpu.opEA}
+	cpu.instructions[0xEE] = inst{"XOR", 2, 2, 2, cpu.opEE}
+	cpu.instructions[0xEF] = inst{"RST", 1, 4, 4, cpu.opEF}
+	cpu.instructions[0xF0] = inst{"LDH", 2, 3, 3, cpu.opF0}
+	cpu.instructions[0xF1] = inst{"POP", 1, 3, 3, cpu.opF1}
+	cpu.instructions[0xF2] = inst{"LD", 1, 2, 2, cpu.opF2}
+	cpu.instructions[0xF3] = inst{"DI", 1, 1, 1, cpu.opF3}
+	cpu.instructions[0xF5] = inst{"PUSH", 1, 4, 4, cpu.opF5}
+	cpu.instructions[0xF6] = inst{"OR", 2, 2, 2, cpu.opF6}
+	cpu.instructions[0xF7] = inst{"RST", 1, 4, 4, cpu.opF7}
+	cpu.instructions[0xF8] = inst{"LD", 2, 3, 3, cpu.opF8}
+	cpu.instructions[0xF9] = inst{"LD", 1, 2, 2, cpu.opF9}
+	cpu.instructions[0xFA] = inst{"LD", 3, 4, 4, cpu.opFA}
+	cpu.instructions[0xFB] = inst{"EI", 1, 1, 1, cpu.opFB}
+	cpu.instructions[0xFE] = inst{"CP", 2, 2, 2, cpu.opFE}
+	cpu.instructions[0xFF] = inst{"RST", 1, 4, 4, cpu.opFF}
 
 	// Illegal codes
-	instructions[0xE3] = inst{"XXX", 1, 1, cpu.nop}
+	cpu.instructions[0xD3] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xDB] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xDD] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xE3] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xE4] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xEB] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xEC] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xED] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xF4] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xFC] = inst{"XXX", 1, 1, 1, cpu.illegal}
+	cpu.instructions[0xFD] = inst{"XXX", 1, 1, 1, cpu.illegal}
+}
+
+// prefixNames maps bits 3-7 of $CB-prefixed opcodes 0x00-0x3F to their
+// mnemonics. Opcodes 0x40-0xFF are BIT, RES and SET, in blocks of 0x40.
+var prefixNames = [8]string{"RLC", "RRC", "RL", "RR", "SLA", "SRA", "SWAP", "SRL"}
+
+// setupPrefixInstructionLookup defines the 256 $CB-prefixed instructions. Each
+// takes 2 machine cycles, plus 1 to read an (HL) operand, and 1 more to write
+// it back for every instruction but BIT.
+func (cpu *CPU) setupPrefixInstructionLookup() {
+	for i := 0; i < INSTRUCTION_COUNT; i++ {
+		op := byte(i)
+
+		var name string
+		switch {
+		case op <= 0x3F:
+			name = prefixNames[op/8]
+		case op <= 0x7F:
+			name = "BIT"
+		case op <= 0xBF:
+			name = "RES"
+		default:
+			name = "SET"
+		}
+
+		cycles := 2
+		if op%8 == 0x6 {
+			cycles++
+			if name != "BIT" {
+				cycles++
+			}
+		}
+
+		cpu.prefixInstructions[op] = inst{name, 2, cycles, cycles, func() { cpu.opCB(op) }}
+	}
 }
 
 // NOP
@@ -164,6 +334,11 @@ func (cpu *CPU) op03() {
 	cpu.BC.inc()
 }
 
+// INC B
+func (cpu *CPU) op04() {
+	cpu.inc8(&cpu.BC.hiReg)
+}
+
 // DEC B
 func (cpu *CPU) op05() {
 	cpu.dec8(&cpu.BC.hiReg)
@@ -180,6 +355,17 @@ func (cpu *CPU) op07() {
 	cpu.rlca()
 }
 
+// LD (nn),SP
+func (cpu *CPU) op08() {
+	addr := cpu.readWord(cpu.PC + 1)
+	cpu.writeWord(addr, cpu.SP)
+}
+
+// ADD HL,BC
+func (cpu *CPU) op09() {
+	cpu.addHL(cpu.BC.get())
+}
+
 // LD A,(BC)
 func (cpu *CPU) op0A() {
 	addr := cpu.BC.get()
@@ -187,6 +373,21 @@ func (cpu *CPU) op0A() {
 	cpu.AF.setHi(data)
 }
 
+// DEC BC
+func (cpu *CPU) op0B() {
+	cpu.BC.dec()
+}
+
+// INC C
+func (cpu *CPU) op0C() {
+	cpu.inc8(&cpu.BC.loReg)
+}
+
+// DEC C
+func (cpu *CPU) op0D() {
+	cpu.dec8(&cpu.BC.loReg)
+}
+
 // LD C,n
 func (cpu *CPU) op0E() {
 	data := cpu.read(cpu.PC + 1)
@@ -204,6 +405,11 @@ func (cpu *CPU) op0F() {
 // signals the CPU to go into "low power standby mode."
 func (cpu *CPU) op10() {
 	_ = cpu.read(cpu.PC + 1)
+	// A held button in a selected joypad row keeps the CPU running
+	cpu.bus.sched.sync(EVENT_JOYPAD)
+	if cpu.bus.mmu.joypad.lines != 0x0F {
+		return
+	}
 	cpu.stopped = true
 }
 
@@ -225,12 +431,38 @@ func (cpu *CPU) op13() {
 	cpu.DE.inc()
 }
 
+// INC D
+func (cpu *CPU) op14() {
+	cpu.inc8(&cpu.DE.hiReg)
+}
+
+// DEC D
+func (cpu *CPU) op15() {
+	cpu.dec8(&cpu.DE.hiReg)
+}
+
+// LD D,n
+func (cpu *CPU) op16() {
+	data := cpu.read(cpu.PC + 1)
+	cpu.DE.setHi(data)
+}
+
+// RLA
+func (cpu *CPU) op17() {
+	cpu.rla()
+}
+
 // JR e
 func (cpu *CPU) op18() {
 	offset := cpu.read(cpu.PC + 1)
 	cpu.jr(offset)
 }
 
+// ADD HL,DE
+func (cpu *CPU) op19() {
+	cpu.addHL(cpu.DE.get())
+}
+
 // LD A,(DE)
 func (cpu *CPU) op1A() {
 	addr := cpu.DE.get()
@@ -250,7 +482,7 @@ func (cpu *CPU) op1C() {
 
 // DEC E
 func (cpu *CPU) op1D() {
-	cpu.dec8(&cpu.DE.hiReg)
+	cpu.dec8(&cpu.DE.loReg)
 }
 
 // LD E,n
@@ -307,6 +539,11 @@ func (cpu *CPU) op26() {
 	cpu.HL.setHi(data)
 }
 
+// DAA
+func (cpu *CPU) op27() {
+	cpu.daa()
+}
+
 // JR Z,e
 func (cpu *CPU) op28() {
 	offset := cpu.read(cpu.PC + 1)
@@ -316,7 +553,7 @@ func (cpu *CPU) op28() {
 
 // ADD HL,HL
 func (cpu *CPU) op29() {
-	cpu.addHL(&cpu.HL)
+	cpu.addHL(cpu.HL.get())
 }
 
 // LD A,(HL+)
@@ -328,6 +565,11 @@ func (cpu *CPU) op2A() {
 	cpu.HL.inc()
 }
 
+// DEC HL
+func (cpu *CPU) op2B() {
+	cpu.HL.dec()
+}
+
 // INC L
 func (cpu *CPU) op2C() {
 	cpu.inc8(&cpu.HL.loReg)
@@ -338,6 +580,12 @@ func (cpu *CPU) op2D() {
 	cpu.dec8(&cpu.HL.loReg)
 }
 
+// LD L,n
+func (cpu *CPU) op2E() {
+	data := cpu.read(cpu.PC + 1)
+	cpu.HL.setLo(data)
+}
+
 // CPL
 func (cpu *CPU) op2F() {
 	cpu.cpl()
@@ -364,6 +612,23 @@ func (cpu *CPU) op32() {
 	cpu.HL.dec()
 }
 
+// INC SP
+func (cpu *CPU) op33() {
+	cpu.SP++
+}
+
+// INC (HL)
+func (cpu *CPU) op34() {
+	addr := cpu.HL.get()
+	data := cpu.read(addr)
+	res := data + 1
+	cpu.write(addr, res)
+
+	cpu.setFlag(FLAG_Z, res == 0)
+	cpu.setFlag(FLAG_N, false)
+	cpu.setFlag(FLAG_H, halfCarryOccurs(data, 1))
+}
+
 // DEC (HL)
 func (cpu *CPU) op35() {
 	addr := cpu.HL.get()
@@ -373,7 +638,7 @@ func (cpu *CPU) op35() {
 
 	cpu.setFlag(FLAG_Z, res == 0)
 	cpu.setFlag(FLAG_N, true)
-	cpu.setFlag(FLAG_H, halfCarryOccurs(data, 0xFF))
+	cpu.setFlag(FLAG_H, halfBorrowOccurs(data, 1))
 }
 
 // LD (HL),n
@@ -383,6 +648,11 @@ func (cpu *CPU) op36() {
 	cpu.ld8(addr, data)
 }
 
+// SCF
+func (cpu *CPU) op37() {
+	cpu.scf()
+}
+
 // JR C,e
 func (cpu *CPU) op38() {
 	offset := cpu.read(cpu.PC + 1)
@@ -390,6 +660,11 @@ func (cpu *CPU) op38() {
 	cpu.jrIf(offset, cond)
 }
 
+// ADD HL,SP
+func (cpu *CPU) op39() {
+	cpu.addHL(cpu.SP)
+}
+
 // LD A,(HL-)
 func (cpu *CPU) op3A() {
 	addr := cpu.HL.get()
@@ -399,6 +674,11 @@ func (cpu *CPU) op3A() {
 	cpu.HL.dec()
 }
 
+// DEC SP
+func (cpu *CPU) op3B() {
+	cpu.SP--
+}
+
 // INC A
 func (cpu *CPU) op3C() {
 	cpu.inc8(&cpu.AF.hiReg)
@@ -415,6 +695,47 @@ func (cpu *CPU) op3E() {
 	cpu.AF.setHi(data)
 }
 
+// CCF
+func (cpu *CPU) op3F() {
+	cpu.ccf()
+}
+
+// LD B,B
+func (cpu *CPU) op40() {
+	data := cpu.BC.getHi()
+	cpu.BC.setHi(data)
+}
+
+// LD B,C
+func (cpu *CPU) op41() {
+	data := cpu.BC.getLo()
+	cpu.BC.setHi(data)
+}
+
+// LD B,D
+func (cpu *CPU) op42() {
+	data := cpu.DE.getHi()
+	cpu.BC.setHi(data)
+}
+
+// LD B,E
+func (cpu *CPU) op43() {
+	data := cpu.DE.getLo()
+	cpu.BC.setHi(data)
+}
+
+// LD B,H
+func (cpu *CPU) op44() {
+	data := cpu.HL.getHi()
+	cpu.BC.setHi(data)
+}
+
+// LD B,L
+func (cpu *CPU) op45() {
+	data := cpu.HL.getLo()
+	cpu.BC.setHi(data)
+}
+
 // LD B,(HL)
 func (cpu *CPU) op46() {
 	addr := cpu.HL.get()
@@ -428,6 +749,42 @@ func (cpu *CPU) op47() {
 	cpu.BC.setHi(data)
 }
 
+// LD C,B
+func (cpu *CPU) op48() {
+	data := cpu.BC.getHi()
+	cpu.BC.setLo(data)
+}
+
+// LD C,C
+func (cpu *CPU) op49() {
+	data := cpu.BC.getLo()
+	cpu.BC.setLo(data)
+}
+
+// LD C,D
+func (cpu *CPU) op4A() {
+	data := cpu.DE.getHi()
+	cpu.BC.setLo(data)
+}
+
+// LD C,E
+func (cpu *CPU) op4B() {
+	data := cpu.DE.getLo()
+	cpu.BC.setLo(data)
+}
+
+// LD C,H
+func (cpu *CPU) op4C() {
+	data := cpu.HL.getHi()
+	cpu.BC.setLo(data)
+}
+
+// LD C,L
+func (cpu *CPU) op4D() {
+	data := cpu.HL.getLo()
+	cpu.BC.setLo(data)
+}
+
 // LD C,(HL)
 func (cpu *CPU) op4E() {
 	addr := cpu.HL.get()
@@ -441,6 +798,42 @@ func (cpu *CPU) op4F() {
 	cpu.BC.setLo(data)
 }
 
+// LD D,B
+func (cpu *CPU) op50() {
+	data := cpu.BC.getHi()
+	cpu.DE.setHi(data)
+}
+
+// LD D,C
+func (cpu *CPU) op51() {
+	data := cpu.BC.getLo()
+	cpu.DE.setHi(data)
+}
+
+// LD D,D
+func (cpu *CPU) op52() {
+	data := cpu.DE.getHi()
+	cpu.DE.setHi(data)
+}
+
+// LD D,E
+func (cpu *CPU) op53() {
+	data := cpu.DE.getLo()
+	cpu.DE.setHi(data)
+}
+
+// LD D,H
+func (cpu *CPU) op54() {
+	data := cpu.HL.getHi()
+	cpu.DE.setHi(data)
+}
+
+// LD D,L
+func (cpu *CPU) op55() {
+	data := cpu.HL.getLo()
+	cpu.DE.setHi(data)
+}
+
 // LD D,(HL)
 func (cpu *CPU) op56() {
 	addr := cpu.HL.get()
@@ -454,12 +847,49 @@ func (cpu *CPU) op57() {
 	cpu.DE.setHi(data)
 }
 
+// LD E,B
+func (cpu *CPU) op58() {
+	data := cpu.BC.getHi()
+	cpu.DE.setLo(data)
+}
+
+// LD E,C
+func (cpu *CPU) op59() {
+	data := cpu.BC.getLo()
+	cpu.DE.setLo(data)
+}
+
+// LD E,D
+func (cpu *CPU) op5A() {
+	data := cpu.DE.getHi()
+	cpu.DE.setLo(data)
+}
+
+// LD E,E
+func (cpu *CPU) op5B() {
+	data := cpu.DE.getLo()
+	cpu.DE.setLo(data)
+}
+
+// LD E,H
+func (cpu *CPU) op5C() {
+	data := cpu.HL.getHi()
+	cpu.DE.setLo(data)
+}
+
 // LD E,L
 func (cpu *CPU) op5D() {
 	data := cpu.HL.getLo()
 	cpu.DE.setLo(data)
 }
 
+// LD E,(HL)
+func (cpu *CPU) op5E() {
+	addr := cpu.HL.get()
+	data := cpu.read(addr)
+	cpu.DE.setLo(data)
+}
+
 // LD E,A
 func (cpu *CPU) op5F() {
 	data := cpu.AF.getHi()
@@ -472,6 +902,12 @@ func (cpu *CPU) op60() {
 	cpu.HL.setHi(data)
 }
 
+// LD H,C
+func (cpu *CPU) op61() {
+	data := cpu.BC.getLo()
+	cpu.HL.setHi(data)
+}
+
 // LD H,D
 func (cpu *CPU) op62() {
 	data := cpu.DE.getHi()
@@ -484,6 +920,18 @@ func (cpu *CPU) op63() {
 	cpu.HL.setHi(data)
 }
 
+// LD H,H
+func (cpu *CPU) op64() {
+	data := cpu.HL.getHi()
+	cpu.HL.setHi(data)
+}
+
+// LD H,L
+func (cpu *CPU) op65() {
+	data := cpu.HL.getLo()
+	cpu.HL.setHi(data)
+}
+
 // LD H,(HL)
 func (cpu *CPU) op66() {
 	addr := cpu.HL.get()
@@ -497,12 +945,24 @@ func (cpu *CPU) op67() {
 	cpu.HL.setHi(data)
 }
 
+// LD L,B
+func (cpu *CPU) op68() {
+	data := cpu.BC.getHi()
+	cpu.HL.setLo(data)
+}
+
 // LD L,C
 func (cpu *CPU) op69() {
 	data := cpu.BC.getLo()
 	cpu.HL.setLo(data)
 }
 
+// LD L,D
+func (cpu *CPU) op6A() {
+	data := cpu.DE.getHi()
+	cpu.HL.setLo(data)
+}
+
 // LD L,E
 func (cpu *CPU) op6B() {
 	data := cpu.DE.getLo()
@@ -515,6 +975,12 @@ func (cpu *CPU) op6C() {
 	cpu.HL.setLo(data)
 }
 
+// LD L,L
+func (cpu *CPU) op6D() {
+	data := cpu.HL.getLo()
+	cpu.HL.setLo(data)
+}
+
 // LD L,(HL)
 func (cpu *CPU) op6E() {
 	addr := cpu.HL.get()
@@ -572,7 +1038,7 @@ func (cpu *CPU) op75() {
 
 // HALT
 func (cpu *CPU) op76() {
-	cpu.halted = true
+	cpu.halt()
 }
 
 // LD (HL),A
@@ -625,12 +1091,97 @@ func (cpu *CPU) op7E() {
 	cpu.AF.setHi(data)
 }
 
+// LD A,A
+func (cpu *CPU) op7F() {
+	data := cpu.AF.getHi()
+	cpu.AF.setHi(data)
+}
+
 // ADD A,B
 func (cpu *CPU) op80() {
 	b := cpu.BC.getHi()
 	cpu.add(b)
 }
 
+// ADD A,C
+func (cpu *CPU) op81() {
+	c := cpu.BC.getLo()
+	cpu.add(c)
+}
+
+// ADD A,D
+func (cpu *CPU) op82() {
+	d := cpu.DE.getHi()
+	cpu.add(d)
+}
+
+// ADD A,E
+func (cpu *CPU) op83() {
+	e := cpu.DE.getLo()
+	cpu.add(e)
+}
+
+// ADD A,H
+func (cpu *CPU) op84() {
+	h := cpu.HL.getHi()
+	cpu.add(h)
+}
+
+// ADD A,L
+func (cpu *CPU) op85() {
+	l := cpu.HL.getLo()
+	cpu.add(l)
+}
+
+// ADD A,(HL)
+func (cpu *CPU) op86() {
+	addr := cpu.HL.get()
+	data := cpu.read(addr)
+	cpu.add(data)
+}
+
+// ADD A,A
+func (cpu *CPU) op87() {
+	a := cpu.AF.getHi()
+	cpu.add(a)
+}
+
+// ADC A,B
+func (cpu *CPU) op88() {
+	b := cpu.BC.getHi()
+	cpu.adc(b)
+}
+
+// ADC A,C
+func (cpu *CPU) op89() {
+	c := cpu.BC.getLo()
+	cpu.adc(c)
+}
+
+// ADC A,D
+func (cpu *CPU) op8A() {
+	d := cpu.DE.getHi()
+	cpu.adc(d)
+}
+
+// ADC A,E
+func (cpu *CPU) op8B() {
+	e := cpu.DE.getLo()
+	cpu.adc(e)
+}
+
+// ADC A,H
+func (cpu *CPU) op8C() {
+	h := cpu.HL.getHi()
+	cpu.adc(h)
+}
+
+// ADC A,L
+func (cpu *CPU) op8D() {
+	l := cpu.HL.getLo()
+	cpu.adc(l)
+}
+
 // ADC A,(HL)
 func (cpu *CPU) op8E() {
 	addr := cpu.HL.get()
@@ -638,24 +1189,195 @@ func (cpu *CPU) op8E() {
 	cpu.adc(hl)
 }
 
+// ADC A,A
+func (cpu *CPU) op8F() {
+	a := cpu.AF.getHi()
+	cpu.adc(a)
+}
+
+// SUB B
+func (cpu *CPU) op90() {
+	b := cpu.BC.getHi()
+	cpu.sub(b)
+}
+
+// SUB C
+func (cpu *CPU) op91() {
+	c := cpu.BC.getLo()
+	cpu.sub(c)
+}
+
+// SUB D
+func (cpu *CPU) op92() {
+	d := cpu.DE.getHi()
+	cpu.sub(d)
+}
+
 // SUB E
 func (cpu *CPU) op93() {
 	e := cpu.DE.getLo()
 	cpu.sub(e)
 }
 
+// SUB H
+func (cpu *CPU) op94() {
+	h := cpu.HL.getHi()
+	cpu.sub(h)
+}
+
+// SUB L
+func (cpu *CPU) op95() {
+	l := cpu.HL.getLo()
+	cpu.sub(l)
+}
+
+// SUB (HL)
+func (cpu *CPU) op96() {
+	addr := cpu.HL.get()
+	data := cpu.read(addr)
+	cpu.sub(data)
+}
+
+// SUB A
+func (cpu *CPU) op97() {
+	a := cpu.AF.getHi()
+	cpu.sub(a)
+}
+
+// SBC A,B
+func (cpu *CPU) op98() {
+	b := cpu.BC.getHi()
+	cpu.sbc(b)
+}
+
+// SBC A,C
+func (cpu *CPU) op99() {
+	c := cpu.BC.getLo()
+	cpu.sbc(c)
+}
+
+// SBC A,D
+func (cpu *CPU) op9A() {
+	d := cpu.DE.getHi()
+	cpu.sbc(d)
+}
+
+// SBC A,E
+func (cpu *CPU) op9B() {
+	e := cpu.DE.getLo()
+	cpu.sbc(e)
+}
+
+// SBC A,H
+func (cpu *CPU) op9C() {
+	h := cpu.HL.getHi()
+	cpu.sbc(h)
+}
+
+// SBC A,L
+func (cpu *CPU) op9D() {
+	l := cpu.HL.getLo()
+	cpu.sbc(l)
+}
+
+// SBC A,(HL)
+func (cpu *CPU) op9E() {
+	addr := cpu.HL.get()
+	data := cpu.read(addr)
+	cpu.sbc(data)
+}
+
+// SBC A,A
+func (cpu *CPU) op9F() {
+	a := cpu.AF.getHi()
+	cpu.sbc(a)
+}
+
+// AND B
+func (cpu *CPU) opA0() {
+	b := cpu.BC.getHi()
+	cpu.and(b)
+}
+
+// AND C
+func (cpu *CPU) opA1() {
+	c := cpu.BC.getLo()
+	cpu.and(c)
+}
+
+// AND D
+func (cpu *CPU) opA2() {
+	d := cpu.DE.getHi()
+	cpu.and(d)
+}
+
 // AND E
 func (cpu *CPU) opA3() {
 	e := cpu.DE.getLo()
 	cpu.and(e)
 }
 
+// AND H
+func (cpu *CPU) opA4() {
+	h := cpu.HL.getHi()
+	cpu.and(h)
+}
+
+// AND L
+func (cpu *CPU) opA5() {
+	l := cpu.HL.getLo()
+	cpu.and(l)
+}
+
+// AND (HL)
+func (cpu *CPU) opA6() {
+	addr := cpu.HL.get()
+	data := cpu.read(addr)
+	cpu.and(data)
+}
+
+// AND A
+func (cpu *CPU) opA7() {
+	a := cpu.AF.getHi()
+	cpu.and(a)
+}
+
+// XOR B
+func (cpu *CPU) opA8() {
+	b := cpu.BC.getHi()
+	cpu.xor(b)
+}
+
 // XOR C
 func (cpu *CPU) opA9() {
 	c := cpu.BC.getLo()
 	cpu.xor(c)
 }
 
+// XOR D
+func (cpu *CPU) opAA() {
+	d := cpu.DE.getHi()
+	cpu.xor(d)
+}
+
+// XOR E
+func (cpu *CPU) opAB() {
+	e := cpu.DE.getLo()
+	cpu.xor(e)
+}
+
+// XOR H
+func (cpu *CPU) opAC() {
+	h := cpu.HL.getHi()
+	cpu.xor(h)
+}
+
+// XOR L
+func (cpu *CPU) opAD() {
+	l := cpu.HL.getLo()
+	cpu.xor(l)
+}
+
 // XOR (HL)
 func (cpu *CPU) opAE() {
 	addr := cpu.HL.get()
@@ -663,12 +1385,48 @@ func (cpu *CPU) opAE() {
 	cpu.xor(data)
 }
 
+// XOR A
+func (cpu *CPU) opAF() {
+	a := cpu.AF.getHi()
+	cpu.xor(a)
+}
+
+// OR B
+func (cpu *CPU) opB0() {
+	b := cpu.BC.getHi()
+	cpu.or(b)
+}
+
 // OR C
 func (cpu *CPU) opB1() {
 	c := cpu.BC.getLo()
 	cpu.or(c)
 }
 
+// OR D
+func (cpu *CPU) opB2() {
+	d := cpu.DE.getHi()
+	cpu.or(d)
+}
+
+// OR E
+func (cpu *CPU) opB3() {
+	e := cpu.DE.getLo()
+	cpu.or(e)
+}
+
+// OR H
+func (cpu *CPU) opB4() {
+	h := cpu.HL.getHi()
+	cpu.or(h)
+}
+
+// OR L
+func (cpu *CPU) opB5() {
+	l := cpu.HL.getLo()
+	cpu.or(l)
+}
+
 // OR (HL)
 func (cpu *CPU) opB6() {
 	addr := cpu.HL.get()
@@ -682,6 +1440,55 @@ func (cpu *CPU) opB7() {
 	cpu.or(a)
 }
 
+// CP B
+func (cpu *CPU) opB8() {
+	b := cpu.BC.getHi()
+	cpu.cp(b)
+}
+
+// CP C
+func (cpu *CPU) opB9() {
+	c := cpu.BC.getLo()
+	cpu.cp(c)
+}
+
+// CP D
+func (cpu *CPU) opBA() {
+	d := cpu.DE.getHi()
+	cpu.cp(d)
+}
+
+// CP E
+func (cpu *CPU) opBB() {
+	e := cpu.DE.getLo()
+	cpu.cp(e)
+}
+
+// CP H
+func (cpu *CPU) opBC() {
+	h := cpu.HL.getHi()
+	cpu.cp(h)
+}
+
+// CP L
+func (cpu *CPU) opBD() {
+	l := cpu.HL.getLo()
+	cpu.cp(l)
+}
+
+// CP (HL)
+func (cpu *CPU) opBE() {
+	addr := cpu.HL.get()
+	data := cpu.read(addr)
+	cpu.cp(data)
+}
+
+// CP A
+func (cpu *CPU) opBF() {
+	a := cpu.AF.getHi()
+	cpu.cp(a)
+}
+
 // RET NZ
 func (cpu *CPU) opC0() {
 	cond := !cpu.getFlag(FLAG_Z)
@@ -694,12 +1501,18 @@ func (cpu *CPU) opC1() {
 	cpu.BC.set(data)
 }
 
+// JP NZ,nn
+func (cpu *CPU) opC2() {
+	nn := cpu.readWord(cpu.PC + 1)
+	cond := !cpu.getFlag(FLAG_Z)
+
+	cpu.jpIf(nn, cond)
+}
+
 // JP nn
 func (cpu *CPU) opC3() {
 	addr := cpu.readWord(cpu.PC + 1)
 	cpu.jp(addr)
-
-	cpu.PC -= instructions[0xC3].length
 }
 
 // CALL NZ,nn
@@ -722,6 +1535,11 @@ func (cpu *CPU) opC6() {
 	cpu.add(data)
 }
 
+// RST 0x00
+func (cpu *CPU) opC7() {
+	cpu.rst(0xC7)
+}
+
 // RET Z
 func (cpu *CPU) opC8() {
 	cond := cpu.getFlag(FLAG_Z)
@@ -741,10 +1559,8 @@ func (cpu *CPU) opCA() {
 	cpu.jpIf(nn, cond)
 }
 
-// Prefix instructions
-func (cpu *CPU) opCB() {
-	op := cpu.read(cpu.PC + 1)
-
+// Prefix instructions, decoded from the byte following 0xCB
+func (cpu *CPU) opCB(op byte) {
 	// Determine register
 	// The registers used in these instructions are determined by the LSB of
 	// opcode as follows:
@@ -754,6 +1570,7 @@ func (cpu *CPU) opCB() {
 	//
 	// This pattern repeats for opcodes with LSB 8 through F (x8-xF).
 	var reg *byte
+	var hl byte
 	switch op % 8 {
 	case 0x0:
 		reg = &cpu.BC.hiReg.value
@@ -768,9 +1585,8 @@ func (cpu *CPU) opCB() {
 	case 0x5:
 		reg = &cpu.HL.loReg.value
 	case 0x6:
-		addr := cpu.HL.get()
-		cpu.cycles++
-		reg = &cpu.bus.CartRom[addr]
+		hl = cpu.read(cpu.HL.get())
+		reg = &hl
 	case 0x7:
 		reg = &cpu.AF.hiReg.value
 	}
@@ -832,6 +1648,20 @@ func (cpu *CPU) opCB() {
 			cpu.set(bit, reg)
 		}
 	}
+
+	// (HL) operands are modified in a copy of the data, which must be written
+	// back to memory. BIT only tests the data.
+	if op%8 == 0x6 && (op < 0x40 || op > 0x7F) {
+		cpu.write(cpu.HL.get(), hl)
+	}
+}
+
+// CALL Z,nn
+func (cpu *CPU) opCC() {
+	nn := cpu.readWord(cpu.PC + 1)
+	cond := cpu.getFlag(FLAG_Z)
+
+	cpu.callIf(nn, cond)
 }
 
 // CALL nn
@@ -846,6 +1676,11 @@ func (cpu *CPU) opCE() {
 	cpu.adc(n)
 }
 
+// RST 0x08
+func (cpu *CPU) opCF() {
+	cpu.rst(0xCF)
+}
+
 // RET NC
 func (cpu *CPU) opD0() {
 	cond := !cpu.getFlag(FLAG_C)
@@ -866,6 +1701,14 @@ func (cpu *CPU) opD2() {
 	cpu.jpIf(nn, cond)
 }
 
+// CALL NC,nn
+func (cpu *CPU) opD4() {
+	nn := cpu.readWord(cpu.PC + 1)
+	cond := !cpu.getFlag(FLAG_C)
+
+	cpu.callIf(nn, cond)
+}
+
 // PUSH DE
 func (cpu *CPU) opD5() {
 	data := cpu.DE.get()
@@ -878,6 +1721,49 @@ func (cpu *CPU) opD6() {
 	cpu.sub(data)
 }
 
+// RST 0x10
+func (cpu *CPU) opD7() {
+	cpu.rst(0xD7)
+}
+
+// RET C
+func (cpu *CPU) opD8() {
+	cond := cpu.getFlag(FLAG_C)
+	cpu.retIf(cond)
+}
+
+// RETI
+func (cpu *CPU) opD9() {
+	cpu.reti()
+}
+
+// JP C,nn
+func (cpu *CPU) opDA() {
+	nn := cpu.readWord(cpu.PC + 1)
+	cond := cpu.getFlag(FLAG_C)
+
+	cpu.jpIf(nn, cond)
+}
+
+// CALL C,nn
+func (cpu *CPU) opDC() {
+	nn := cpu.readWord(cpu.PC + 1)
+	cond := cpu.getFlag(FLAG_C)
+
+	cpu.callIf(nn, cond)
+}
+
+// SBC A,n
+func (cpu *CPU) opDE() {
+	n := cpu.read(cpu.PC + 1)
+	cpu.sbc(n)
+}
+
+// RST 0x18
+func (cpu *CPU) opDF() {
+	cpu.rst(0xDF)
+}
+
 // LDH (n),A
 func (cpu *CPU) opE0() {
 	lo := cpu.read(cpu.PC + 1)
@@ -892,6 +1778,14 @@ func (cpu *CPU) opE1() {
 	cpu.HL.set(data)
 }
 
+// LD (C),A
+func (cpu *CPU) opE2() {
+	lo := cpu.BC.getLo()
+	addr := u16(lo, 0xFF)
+	data := cpu.AF.getHi()
+	cpu.ld8(addr, data)
+}
+
 // PUSH HL
 func (cpu *CPU) opE5() {
 	data := cpu.HL.get()
@@ -904,6 +1798,23 @@ func (cpu *CPU) opE6() {
 	cpu.and(n)
 }
 
+// RST 0x20
+func (cpu *CPU) opE7() {
+	cpu.rst(0xE7)
+}
+
+// ADD SP,e
+func (cpu *CPU) opE8() {
+	offset := cpu.read(cpu.PC + 1)
+	cpu.SP = cpu.addSPe(offset)
+}
+
+// JP HL
+func (cpu *CPU) opE9() {
+	addr := cpu.HL.get()
+	cpu.jp(addr)
+}
+
 // LD (nn),A
 func (cpu *CPU) opEA() {
 	addr := cpu.readWord(cpu.PC + 1)
@@ -917,6 +1828,11 @@ func (cpu *CPU) opEE() {
 	cpu.xor(n)
 }
 
+// RST 0x28
+func (cpu *CPU) opEF() {
+	cpu.rst(0xEF)
+}
+
 // LDH A,(n)
 func (cpu *CPU) opF0() {
 	lo := cpu.read(cpu.PC + 1)
@@ -928,7 +1844,15 @@ func (cpu *CPU) opF0() {
 // POP AF
 func (cpu *CPU) opF1() {
 	data := cpu.pop()
-	cpu.AF.set(data)
+	cpu.AF.set(data & 0xFFF0) // lower 4 bits of F are always 0
+}
+
+// LD A,(C)
+func (cpu *CPU) opF2() {
+	lo := cpu.BC.getLo()
+	addr := u16(lo, 0xFF)
+	data := cpu.read(addr)
+	cpu.AF.setHi(data)
 }
 
 // DI
@@ -942,6 +1866,24 @@ func (cpu *CPU) opF5() {
 	cpu.push(data)
 }
 
+// OR n
+func (cpu *CPU) opF6() {
+	n := cpu.read(cpu.PC + 1)
+	cpu.or(n)
+}
+
+// RST 0x30
+func (cpu *CPU) opF7() {
+	cpu.rst(0xF7)
+}
+
+// LD HL,SP+e
+func (cpu *CPU) opF8() {
+	offset := cpu.read(cpu.PC + 1)
+	data := cpu.addSPe(offset)
+	cpu.HL.set(data)
+}
+
 // LD SP,HL
 func (cpu *CPU) opF9() {
 	data := cpu.HL.get()
@@ -955,17 +1897,15 @@ func (cpu *CPU) opFA() {
 	cpu.AF.setHi(data)
 }
 
+// EI
+func (cpu *CPU) opFB() {
+	cpu.ei()
+}
+
 // CP n
 func (cpu *CPU) opFE() {
 	n := cpu.read(cpu.PC + 1)
-	add1 := cpu.AF.getHi()
-	add2 := byte(int(n) * -1)
-	res := add1 + add2
-
-	cpu.setFlag(FLAG_Z, res == 0)
-	cpu.setFlag(FLAG_N, true)
-	cpu.setFlag(FLAG_H, halfCarryOccurs(add1, add2))
-	cpu.setFlag(FLAG_C, add1 > res)
+	cpu.cp(n)
 }
 
 // RST 0x38
@@ -7,34 +7,29 @@ func (cpu *CPU) ld8(addr uint16, data byte) {
 
 // jp jumps unconditionally to the given 16-bit address
 func (cpu *CPU) jp(addr uint16) {
-	cpu.PC = addr
+	cpu.nextPC = addr
 }
 
 // jpIf performs a conditional absolute jump to the given address based on the
 // given condition
 func (cpu *CPU) jpIf(addr uint16, condition bool) {
 	if condition {
-		cpu.PC = addr
-		cpu.cycles++
-
-		// Every cycle, the PC is updated according to the number of bytes used
-		// by the current instruction found in the lookup table. If a jump
-		// occurs, we must subtract the number of bytes used to counteract the
-		// automatic update to PC.
-		cpu.PC -= 3
+		cpu.jp(addr)
+		cpu.branched = true
 	}
 }
 
-// jr performs a relative jump using the given `offset`. This jump only affects
-// the low byte of the PC (no carry to high byte).
+// jr performs a relative jump using the given `offset`, a signed byte. The
+// offset is relative to the address of the next instruction.
 func (cpu *CPU) jr(offset byte) {
-	cpu.PC = (cpu.PC & 0xFF00) | uint16(byte(cpu.PC)+offset)
+	cpu.nextPC += uint16(int8(offset))
 }
 
 // jrIf performs a conditional relative jump based on the given condition
 func (cpu *CPU) jrIf(offset byte, condition bool) {
 	if condition {
 		cpu.jr(offset)
+		cpu.branched = true
 	}
 }
 
@@ -57,7 +52,7 @@ func (cpu *CPU) dec8(reg *register8Bit) {
 
 	cpu.setFlag(FLAG_Z, res == 0)
 	cpu.setFlag(FLAG_N, true)
-	cpu.setFlag(FLAG_H, halfCarryOccurs(val, 0xFF))
+	cpu.setFlag(FLAG_H, halfBorrowOccurs(val, 1))
 }
 
 // add performs an addition on the value in register A and the given value, and
@@ -82,8 +77,38 @@ func (cpu *CPU) sub(b byte) {
 
 	cpu.setFlag(FLAG_Z, res == 0)
 	cpu.setFlag(FLAG_N, true)
-	cpu.setFlag(FLAG_H, halfCarryOccurs(a, -b))
-	cpu.setFlag(FLAG_C, a > res)
+	cpu.setFlag(FLAG_H, halfBorrowOccurs(a, b))
+	cpu.setFlag(FLAG_C, a < b)
+}
+
+// sbc subtracts the given value and the carry flag from the value in register
+// A, and stores the result in register A
+func (cpu *CPU) sbc(b byte) {
+	carry := byte(0)
+	if cpu.getFlag(FLAG_C) {
+		carry = 1
+	}
+
+	a := cpu.AF.getHi()
+	res := a - b - carry
+	cpu.AF.setHi(res)
+
+	cpu.setFlag(FLAG_Z, res == 0)
+	cpu.setFlag(FLAG_N, true)
+	cpu.setFlag(FLAG_H, a&0xF < b&0xF+carry)
+	cpu.setFlag(FLAG_C, int(a) < int(b)+int(carry))
+}
+
+// cp compares the value in register A with the given value by performing a
+// subtraction, setting flags without storing the result
+func (cpu *CPU) cp(b byte) {
+	a := cpu.AF.getHi()
+	res := a - b
+
+	cpu.setFlag(FLAG_Z, res == 0)
+	cpu.setFlag(FLAG_N, true)
+	cpu.setFlag(FLAG_H, halfBorrowOccurs(a, b))
+	cpu.setFlag(FLAG_C, a < b)
 }
 
 // adc performs an addition with carry on the value in register A and the given
@@ -100,15 +125,14 @@ func (cpu *CPU) adc(add byte) {
 
 	cpu.setFlag(FLAG_Z, res == 0)
 	cpu.setFlag(FLAG_N, false)
-	cpu.setFlag(FLAG_H, halfCarryOccurs(a, add+carry))
-	cpu.setFlag(FLAG_C, a > res)
+	cpu.setFlag(FLAG_H, a&0xF+add&0xF+carry > 0xF)
+	cpu.setFlag(FLAG_C, int(a)+int(add)+int(carry) > 0xFF)
 }
 
-// addHL performs an addition on the value stored in register HL and the value
-// stored in the given register, and stores the result in register HL.
-func (cpu *CPU) addHL(reg *register) {
+// addHL performs an addition on the value stored in register HL and the given
+// value, and stores the result in register HL.
+func (cpu *CPU) addHL(toAdd uint16) {
 	hl := cpu.HL.get()
-	toAdd := reg.get()
 	res := hl + toAdd
 	cpu.HL.set(hl + toAdd)
 
@@ -166,6 +190,66 @@ func (cpu *CPU) cpl() {
 	cpu.setFlag(FLAG_H, true)
 }
 
+// daa adjusts the value in register A to be a valid binary-coded decimal
+// number, based on the flags left behind by the previous addition/subtraction
+func (cpu *CPU) daa() {
+	a := cpu.AF.getHi()
+	carry := cpu.getFlag(FLAG_C)
+
+	if !cpu.getFlag(FLAG_N) {
+		if carry || a > 0x99 {
+			a += 0x60
+			carry = true
+		}
+		if cpu.getFlag(FLAG_H) || a&0x0F > 0x09 {
+			a += 0x06
+		}
+	} else {
+		if carry {
+			a -= 0x60
+		}
+		if cpu.getFlag(FLAG_H) {
+			a -= 0x06
+		}
+	}
+	cpu.AF.setHi(a)
+
+	cpu.setFlag(FLAG_Z, a == 0)
+	cpu.setFlag(FLAG_H, false)
+	cpu.setFlag(FLAG_C, carry)
+}
+
+// scf sets the carry flag
+func (cpu *CPU) scf() {
+	cpu.setFlag(FLAG_N, false)
+	cpu.setFlag(FLAG_H, false)
+	cpu.setFlag(FLAG_C, true)
+}
+
+// ccf complements the carry flag
+func (cpu *CPU) ccf() {
+	cpu.setFlag(FLAG_N, false)
+	cpu.setFlag(FLAG_H, false)
+	cpu.setFlag(FLAG_C, !cpu.getFlag(FLAG_C))
+}
+
+// addSPe returns the sum of SP and the given signed offset, setting the flags
+// used by both 'ADD SP,e' and 'LD HL,SP+e'. H and C are set from the unsigned
+// addition of the offset to the low byte of SP.
+//
+// ref: (https://newbedev.com/game-boy-half-carry-flag-and-16-bit-instructions-especially-opcode-0xe8)
+func (cpu *CPU) addSPe(offset byte) uint16 {
+	sp := cpu.SP
+	res := sp + uint16(int8(offset))
+
+	cpu.setFlag(FLAG_Z, false)
+	cpu.setFlag(FLAG_N, false)
+	cpu.setFlag(FLAG_H, halfCarryOccurs(byte(sp), offset))
+	cpu.setFlag(FLAG_C, uint16(byte(sp))+uint16(offset) > 0xFF)
+
+	return res
+}
+
 // rlca rotates A left 1 bit with wrapping, leaving the previous MSB in the LSB
 // position
 func (cpu *CPU) rlca() {
@@ -333,7 +417,13 @@ func (cpu *CPU) srl(data *byte) {
 func (cpu *CPU) swap(data *byte) {
 	hi := *data & 0xF0
 	lo := *data & 0x0F
-	*data = (lo << 4) | (hi >> 4)
+	res := (lo << 4) | (hi >> 4)
+	*data = res
+
+	cpu.setFlag(FLAG_Z, res == 0)
+	cpu.setFlag(FLAG_N, false)
+	cpu.setFlag(FLAG_H, false)
+	cpu.setFlag(FLAG_C, false)
 }
 
 // bit tests bit 'b', setting the zero flag if the bit is 0
@@ -351,11 +441,13 @@ func (cpu *CPU) res(b int, data *byte) {
 
 // set sets a bit 'b' to 1 in the given data
 func (cpu *CPU) set(b int, data *byte) {
-	*data &= (1 << b)
+	*data |= (1 << b)
 }
 
-// push pushes a word of data to the stack
+// push pushes a word of data to the stack, after 1 internal machine cycle
+// decrementing SP
 func (cpu *CPU) push(data uint16) {
+	cpu.idle()
 	hi := byte(data >> 8)
 	lo := byte(data)
 	cpu.stackPush(hi)
@@ -371,9 +463,8 @@ func (cpu *CPU) pop() uint16 {
 
 // call performs an unconditional function call to the given address
 func (cpu *CPU) call(addr uint16) {
-	cpu.push(cpu.PC + 3)
-	cpu.PC = addr
-	cpu.PC -= 3
+	cpu.push(cpu.nextPC)
+	cpu.nextPC = addr
 }
 
 // rstAddr is the restore address lookup table, mapping  CPU opcodes to restore
@@ -392,9 +483,8 @@ var rstAddr = map[byte]byte{
 // rst performs an unconditional function call to a fixed address specified by
 // the opcode
 func (cpu *CPU) rst(op byte) {
-	addr := rstAddr[op]
-	cpu.PC = uint16(addr)
-	cpu.PC -= 1
+	cpu.push(cpu.nextPC)
+	cpu.nextPC = uint16(rstAddr[op])
 }
 
 // callIf performs a conditional function call to the given address if the
@@ -402,28 +492,57 @@ func (cpu *CPU) rst(op byte) {
 func (cpu *CPU) callIf(addr uint16, cond bool) {
 	if cond {
 		cpu.call(addr)
-		cpu.cycles += 3
+		cpu.branched = true
 	}
 }
 
 // ret unconditionally returns from a function
 func (cpu *CPU) ret() {
-	cpu.PC = cpu.pop()
-	cpu.PC -= 1
+	cpu.nextPC = cpu.pop()
 }
 
 // retIf returns from a function if the given `cond` is true
 func (cpu *CPU) retIf(condition bool) {
+	// The condition is checked in an internal machine cycle
+	cpu.idle()
 	if condition {
 		cpu.ret()
-		cpu.cycles += 3
+		cpu.branched = true
 	}
 }
 
+// reti unconditionally returns from a function, enabling interrupts
+func (cpu *CPU) reti() {
+	cpu.ret()
+	cpu.IME = true
+}
+
 // di disables interrupt handling
 func (cpu *CPU) di() {
 	cpu.IME = false
+	cpu.imePended = false
+}
+
+// ei enables interrupt handling after the next instruction
+func (cpu *CPU) ei() {
+	cpu.imePended = true
 }
 
-// nop nops
-func (cpu *CPU) nop() {}
+// halt pauses CPU execution until an interrupt occurs. If IME is clear and an
+// interrupt is already pending, the CPU does not halt and instead fails to
+// increment PC after reading the next opcode (the DMG "HALT bug").
+//
+// reference: https://gbdev.io/pandocs/halt.html
+func (cpu *CPU) halt() {
+	if !cpu.IME && cpu.bus.pendingInterrupts() != 0 {
+		cpu.haltBug = true
+		return
+	}
+	cpu.halted = true
+}
+
+// illegal locks up the CPU. The 11 unused opcodes hang the CPU on hardware
+// until the console is powered off.
+func (cpu *CPU) illegal() {
+	cpu.locked = true
+}
@@ -0,0 +1,78 @@
+package gb
+
+// interrupt is used to represent an interrupt source (bit in the IE and IF
+// registers)
+type interrupt byte
+
+// Interrupt bits found in the IE and IF registers, in order of priority
+const (
+	INT_VBLANK   interrupt = 1 << iota // VBlank
+	INT_LCD_STAT                       // LCD STAT
+	INT_TIMER                          // Timer overflow
+	INT_SERIAL                         // Serial transfer complete
+	INT_JOYPAD                         // Joypad button press
+)
+
+// interruptVector is the interrupt handler lookup table, mapping each
+// interrupt to the address the CPU calls when servicing it
+var interruptVector = map[interrupt]uint16{
+	INT_VBLANK:   0x0040,
+	INT_LCD_STAT: 0x0048,
+	INT_TIMER:    0x0050,
+	INT_SERIAL:   0x0058,
+	INT_JOYPAD:   0x0060,
+}
+
+// interruptDispatchCycles is the number of machine cycles used by the CPU to
+// call an interrupt handler
+const interruptDispatchCycles = 5
+
+// requestInterrupt requests the given interrupt by setting its bit in IF
+func (gb *GameBoy) requestInterrupt(i interrupt) {
+	gb.mmu.intFlag |= byte(i)
+}
+
+// acknowledgeInterrupt clears the given interrupt's bit in IF
+func (gb *GameBoy) acknowledgeInterrupt(i interrupt) {
+	gb.mmu.intFlag &^= byte(i)
+}
+
+// pendingInterrupts returns the interrupts that are both requested (IF) and
+// enabled (IE)
+func (gb *GameBoy) pendingInterrupts() interrupt {
+	return interrupt(gb.mmu.intEnable & gb.mmu.intFlag & 0x1F)
+}
+
+// handleInterrupts wakes the CPU from HALT if an interrupt is pending, and
+// services the highest priority pending interrupt if IME is set. Returns
+// whether an interrupt handler was called.
+//
+// reference: https://gbdev.io/pandocs/Interrupts.html
+func (cpu *CPU) handleInterrupts() bool {
+	pending := cpu.bus.pendingInterrupts()
+	if pending == 0 {
+		return false
+	}
+
+	// HALT is exited whenever an interrupt is pending, even if IME is clear
+	cpu.halted = false
+
+	if !cpu.IME {
+		return false
+	}
+
+	for i := INT_VBLANK; i <= INT_JOYPAD; i <<= 1 {
+		if pending&i == 0 {
+			continue
+		}
+
+		cpu.IME = false
+		cpu.bus.acknowledgeInterrupt(i)
+		cpu.idle()
+		cpu.push(cpu.PC)
+		cpu.PC = interruptVector[i]
+		cpu.idle()
+		break
+	}
+	return true
+}
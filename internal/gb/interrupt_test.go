@@ -0,0 +1,84 @@
+package gb
+
+import "testing"
+
+// TestInterruptDispatch checks that EI is delayed by one instruction, and that
+// servicing an interrupt pushes PC and jumps to the handler in 5 cycles
+func TestInterruptDispatch(t *testing.T) {
+	gb := newTestGameBoy(t, 0xFB, 0x00, 0x00) // EI, NOP, NOP
+	gb.cpuWrite(INTERRUPT_ENABLE, byte(INT_TIMER))
+	gb.requestInterrupt(INT_TIMER)
+
+	gb.Cpu.execNextInst() // EI
+	if gb.Cpu.IME {
+		t.Fatal("IME set immediately after EI")
+	}
+	gb.Cpu.execNextInst() // NOP
+	if !gb.Cpu.IME {
+		t.Fatal("IME not set after the instruction following EI")
+	}
+
+	cycles := gb.Cpu.cycles
+	gb.Cpu.execNextInst() // dispatch
+	if gb.Cpu.PC != interruptVector[INT_TIMER] {
+		t.Errorf("PC = %#04x, want %#04x", gb.Cpu.PC, interruptVector[INT_TIMER])
+	}
+	if got := gb.Cpu.cycles - cycles; got != interruptDispatchCycles {
+		t.Errorf("dispatch took %d cycles, want %d", got, interruptDispatchCycles)
+	}
+	if gb.Cpu.IME {
+		t.Error("IME not cleared by dispatch")
+	}
+	if gb.pendingInterrupts() != 0 {
+		t.Error("IF not acknowledged by dispatch")
+	}
+	if ret := gb.Cpu.pop(); ret != 0x0102 {
+		t.Errorf("pushed return address %#04x, want 0x0102", ret)
+	}
+}
+
+// TestHaltWakeup checks that HALT is exited by a pending interrupt when IME
+// is clear, without calling the interrupt handler
+func TestHaltWakeup(t *testing.T) {
+	gb := newTestGameBoy(t, 0xF3, 0x76, 0x3C) // DI, HALT, INC A
+	gb.cpuWrite(INTERRUPT_ENABLE, byte(INT_VBLANK))
+	gb.cpuWrite(IO_IF, 0x00)
+
+	gb.Cpu.execNextInst() // DI
+	gb.Cpu.execNextInst() // HALT
+	for i := 0; i < 10; i++ {
+		gb.Cpu.execNextInst()
+	}
+	if !gb.Cpu.halted || gb.Cpu.PC != 0x0102 {
+		t.Fatalf("CPU not halted at 0x0102 (PC = %#04x)", gb.Cpu.PC)
+	}
+
+	a := gb.Cpu.AF.getHi()
+	gb.requestInterrupt(INT_VBLANK)
+	gb.Cpu.execNextInst() // INC A
+	if gb.Cpu.halted {
+		t.Fatal("CPU still halted with an interrupt pending")
+	}
+	if gb.Cpu.AF.getHi() != a+1 || gb.Cpu.PC != 0x0103 {
+		t.Errorf("instruction after HALT not executed (PC = %#04x)", gb.Cpu.PC)
+	}
+}
+
+// TestHaltBug checks that HALT with IME clear and an interrupt pending causes
+// the following byte to be read twice
+func TestHaltBug(t *testing.T) {
+	gb := newTestGameBoy(t, 0x76, 0x3C) // HALT, INC A
+	gb.cpuWrite(INTERRUPT_ENABLE, byte(INT_VBLANK))
+	gb.requestInterrupt(INT_VBLANK)
+
+	a := gb.Cpu.AF.getHi()
+	gb.Cpu.execNextInst() // HALT
+	gb.Cpu.execNextInst() // INC A
+	gb.Cpu.execNextInst() // INC A
+	if gb.Cpu.AF.getHi() != a+2 {
+		t.Errorf("A = %#02x, want %#02x", gb.Cpu.AF.getHi(), a+2)
+	}
+	if gb.Cpu.PC != 0x0102 {
+		t.Errorf("PC = %#04x, want 0x0102", gb.Cpu.PC)
+	}
+}
@@ -0,0 +1,105 @@
+package gb
+
+// Button is a set of Game Boy buttons
+type Button byte
+
+// Buttons, as reported by an Input
+const (
+	BUTTON_RIGHT Button = 1 << iota
+	BUTTON_LEFT
+	BUTTON_UP
+	BUTTON_DOWN
+	BUTTON_A
+	BUTTON_B
+	BUTTON_SELECT
+	BUTTON_START
+)
+
+// P1 bits
+const (
+	P1_SELECT_DPAD    = 1 << 4 // 0: direction buttons are read in bits 0-3
+	P1_SELECT_BUTTONS = 1 << 5 // 0: action buttons are read in bits 0-3
+)
+
+// JOYPAD_POLL_DOTS is how often the input is polled, in dots, besides on every
+// access to P1. Once per frame is as often as front-ends update it.
+const JOYPAD_POLL_DOTS = DOTS_PER_FRAME
+
+// Input is a source of button presses. Front-ends, replay files and test
+// scripts provide an Input to control the emulated console.
+type Input interface {
+	// Buttons returns the buttons currently held down
+	Buttons() Button
+}
+
+// joypad is the Game Boy's joypad, read through P1. The buttons are wired in
+// a 2x4 matrix: writing P1 bits 4-5 selects the direction and/or action
+// button rows, and bits 0-3 read 0 for each held button in a selected row.
+// Any of those lines going from high to low requests the joypad interrupt,
+// and wakes the CPU from STOP.
+//
+// reference: https://gbdev.io/pandocs/Joypad_Input.html
+type joypad struct {
+	input Input // nil if no buttons are ever pressed
+
+	selected byte // P1 bits 4-5
+	held     Button
+	lines    byte // P1 bits 0-3, as of the last update
+
+	requestInterrupt func(interrupt)
+}
+
+// newJoypad returns a joypad requesting interrupts through the given function
+func newJoypad(requestInterrupt func(interrupt)) *joypad {
+	return &joypad{
+		selected:         P1_SELECT_DPAD | P1_SELECT_BUTTONS,
+		lines:            0x0F,
+		requestInterrupt: requestInterrupt,
+	}
+}
+
+// readLines returns P1 bits 0-3 for the selected rows and held buttons
+func (j *joypad) readLines() byte {
+	pressed := byte(0)
+	if j.selected&P1_SELECT_DPAD == 0 {
+		pressed |= byte(j.held) & 0x0F
+	}
+	if j.selected&P1_SELECT_BUTTONS == 0 {
+		pressed |= byte(j.held>>4) & 0x0F
+	}
+	return 0x0F &^ pressed
+}
+
+// next returns the number of dots until the input is next polled
+func (j *joypad) next() (int, bool) {
+	return JOYPAD_POLL_DOTS, true
+}
+
+// update polls the input, and refreshes the P1 lines. Returns whether any
+// line went from high to low, requesting the joypad interrupt.
+func (j *joypad) update() bool {
+	if j.input != nil {
+		j.held = j.input.Buttons()
+	}
+
+	lines := j.readLines()
+	fell := j.lines&^lines != 0
+	j.lines = lines
+
+	if fell {
+		j.requestInterrupt(INT_JOYPAD)
+	}
+	return fell
+}
+
+// read reads P1
+func (j *joypad) read() byte {
+	// Upper 2 bits are unused, and always read as 1
+	return 0xC0 | j.selected | j.lines
+}
+
+// write writes P1. Only the row select bits are writable.
+func (j *joypad) write(data byte) {
+	j.selected = data & (P1_SELECT_DPAD | P1_SELECT_BUTTONS)
+	j.update()
+}
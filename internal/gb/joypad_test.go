@@ -0,0 +1,80 @@
+package gb
+
+import "testing"
+
+// testInput is an Input whose held buttons are set directly
+type testInput struct {
+	held Button
+}
+
+func (i *testInput) Buttons() Button {
+	return i.held
+}
+
+// TestJoypadRows checks that P1 reads the held buttons of the selected rows
+func TestJoypadRows(t *testing.T) {
+	input := &testInput{held: BUTTON_DOWN | BUTTON_A | BUTTON_START}
+	gb := newTestGameBoyFromRom(t, newTestRom(), WithInput(input))
+
+	tests := []struct {
+		selected byte
+		want     byte
+	}{
+		{P1_SELECT_BUTTONS, 0xE7}, // direction row: down
+		{P1_SELECT_DPAD, 0xD6},    // action row: A, start
+		{0x00, 0xC6},              // both rows
+		{P1_SELECT_DPAD | P1_SELECT_BUTTONS, 0xFF},
+	}
+	for _, tt := range tests {
+		gb.cpuWrite(IO_P1, tt.selected)
+		gb.tick(1)
+		if got := gb.cpuRead(IO_P1); got != tt.want {
+			t.Errorf("P1 with rows %#02x selected = %#02x, want %#02x", tt.selected, got, tt.want)
+		}
+	}
+}
+
+// TestJoypadInterrupt checks that pressing a button in a selected row requests
+// the joypad interrupt, and wakes the CPU from STOP by the next input poll
+func TestJoypadInterrupt(t *testing.T) {
+	input := &testInput{}
+	gb := newTestGameBoyFromRom(t, newTestRom(0x10, 0x00), WithInput(input))
+	gb.cpuWrite(IO_P1, P1_SELECT_DPAD) // action row
+	gb.cpuWrite(IO_IF, 0x00)
+
+	gb.Cpu.execNextInst()
+	if !gb.Cpu.stopped {
+		t.Fatal("CPU not stopped after STOP")
+	}
+
+	// Buttons in unselected rows are ignored. The input is polled once per
+	// frame.
+	input.held = BUTTON_LEFT
+	gb.RunCycles(JOYPAD_POLL_DOTS)
+	if !gb.Cpu.stopped || gb.cpuRead(IO_IF)&byte(INT_JOYPAD) > 0 {
+		t.Fatal("unselected button woke the CPU or requested an interrupt")
+	}
+
+	input.held |= BUTTON_B
+	gb.RunCycles(JOYPAD_POLL_DOTS)
+	if gb.Cpu.stopped {
+		t.Error("CPU still stopped after button press")
+	}
+	if gb.cpuRead(IO_IF)&byte(INT_JOYPAD) == 0 {
+		t.Error("joypad interrupt not requested after button press")
+	}
+}
+
+// TestStopButtonHeld checks that STOP sees a button pressed since the last
+// input poll, and keeps the CPU running
+func TestStopButtonHeld(t *testing.T) {
+	input := &testInput{}
+	gb := newTestGameBoyFromRom(t, newTestRom(0x10, 0x00), WithInput(input))
+	gb.cpuWrite(IO_P1, P1_SELECT_DPAD) // action row
+
+	input.held = BUTTON_A
+	gb.Cpu.execNextInst()
+	if gb.Cpu.stopped {
+		t.Error("CPU stopped with a button held")
+	}
+}
@@ -0,0 +1,196 @@
+package gb
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// LINK_QUANTUM is the number of machine cycles linked consoles run between
+// synchronisations. Neither console gets further than this ahead of the other.
+const LINK_QUANTUM = SERIAL_BIT_CYCLES
+
+// Link synchronisation message, sent by each console at every quantum
+// boundary
+const (
+	LINK_MESSAGE_SIZE = 3
+
+	LINK_FLAGS = 0 // see LINK_PENDING and LINK_READY
+	LINK_OUT   = 1 // byte sent by a pending transfer
+	LINK_SB    = 2 // SB, sent to a peer clocking a transfer
+
+	LINK_PENDING = 1 << 0 // an internally clocked transfer started, waiting for the peer's byte
+	LINK_READY   = 1 << 1 // an externally clocked transfer is waiting for the peer's clock
+)
+
+// ListenLink waits for another console to connect to the given address, e.g.
+// ("unix", "/tmp/gbemu.sock") or ("tcp", "127.0.0.1:7777"), and returns the
+// connection to pass to 'WithLink'
+func ListenLink(network, address string) (net.Conn, error) {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	return ln.Accept()
+}
+
+// DialLink connects to a console waiting in 'ListenLink' at the given address,
+// and returns the connection to pass to 'WithLink'
+func DialLink(network, address string) (net.Conn, error) {
+	return net.Dial(network, address)
+}
+
+// linkPort is a LinkPeer connecting the serial port to another console over
+// a connection: a Unix socket or TCP connection to another process, or one end
+// of a net.Pipe to a console in the same process.
+//
+// Linked consoles run in lockstep. At the end of every LINK_QUANTUM machine
+// cycles, each console sends the state of its serial port, then waits for the
+// other's. Transfers are resolved from those two messages alone: a transfer
+// clocked by one console during the quantum exchanges bytes with the other
+// console if it has an externally clocked transfer waiting, and shifts in
+// 0xFF otherwise. Both consoles reach the same outcome at the same emulated
+// time, however fast each one runs, so linked sessions are deterministic.
+type linkPort struct {
+	serial *serial
+	conn   io.ReadWriteCloser
+	send   chan [LINK_MESSAGE_SIZE]byte // messages for the writer goroutine
+
+	mu       sync.Mutex
+	writeErr error // first error writing to the connection
+
+	next int // cycle count of the next synchronisation
+
+	pending bool // an internally clocked transfer is waiting for the peer's byte
+	out     byte // byte sent by the pending transfer
+}
+
+// newLinkPort returns a link port for the given serial port, synchronising
+// over the given connection
+func newLinkPort(serial *serial, conn io.ReadWriteCloser) *linkPort {
+	l := &linkPort{
+		serial: serial,
+		conn:   conn,
+		send:   make(chan [LINK_MESSAGE_SIZE]byte, 1),
+		next:   LINK_QUANTUM,
+	}
+
+	// Writes are made from their own goroutine so both consoles can send
+	// at once, even over an unbuffered net.Pipe. Once a write fails, the
+	// connection is closed so the next read fails too, and the remaining
+	// messages are dropped so 'sync' never blocks sending one.
+	go func() {
+		for msg := range l.send {
+			if l.writeError() != nil {
+				continue
+			}
+			if _, err := conn.Write(msg[:]); err != nil {
+				l.mu.Lock()
+				l.writeErr = err
+				l.mu.Unlock()
+				conn.Close()
+			}
+		}
+	}()
+	return l
+}
+
+// writeError returns the error the writer goroutine failed with, if any
+func (l *linkPort) writeError() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.writeErr
+}
+
+// Transfer starts a transfer clocked by this console. The peer's byte is not
+// known until the next synchronisation, so bits shifted in before then are
+// corrected once it is.
+func (l *linkPort) Transfer(out byte) byte {
+	l.pending = true
+	l.out = out
+	return 0xFF
+}
+
+// message returns this console's synchronisation message
+func (l *linkPort) message() [LINK_MESSAGE_SIZE]byte {
+	var msg [LINK_MESSAGE_SIZE]byte
+	if l.pending {
+		msg[LINK_FLAGS] |= LINK_PENDING
+		msg[LINK_OUT] = l.out
+	}
+	if l.serial.sc&SC_TRANSFER > 0 && l.serial.sc&SC_CLOCK_SELECT == 0 {
+		msg[LINK_FLAGS] |= LINK_READY
+	}
+	msg[LINK_SB] = l.serial.sb
+	return msg
+}
+
+// sync exchanges synchronisation messages with the peer, and resolves
+// transfers between the two consoles
+func (l *linkPort) sync() error {
+	msg := l.message()
+	l.send <- msg
+
+	var peer [LINK_MESSAGE_SIZE]byte
+	if _, err := io.ReadFull(l.conn, peer[:]); err != nil {
+		if writeErr := l.writeError(); writeErr != nil {
+			err = writeErr
+		}
+		return fmt.Errorf("link cable disconnected: %w", err)
+	}
+
+	// This console clocked a transfer
+	if l.pending {
+		in := byte(0xFF)
+		if peer[LINK_FLAGS]&LINK_READY > 0 {
+			in = peer[LINK_SB]
+		}
+		l.pending = false
+		l.serial.resolve(in)
+	}
+
+	// The peer clocked a transfer
+	if msg[LINK_FLAGS]&LINK_READY > 0 && peer[LINK_FLAGS]&LINK_PENDING > 0 {
+		l.serial.clockExternal(peer[LINK_OUT])
+	}
+	return nil
+}
+
+// close closes the connection, disconnecting the peer
+func (l *linkPort) close() error {
+	close(l.send)
+	return l.conn.Close()
+}
+
+// syncLink synchronises with the linked console at each quantum boundary
+// reached. If the link fails, the link port is disconnected.
+func (gb *GameBoy) syncLink() {
+	for gb.link != nil && gb.Cpu.cycles >= gb.link.next {
+		gb.link.next += LINK_QUANTUM
+		gb.sched.sync(EVENT_SERIAL)
+		if err := gb.link.sync(); err != nil {
+			gb.logger.Println(err)
+			gb.disconnectLink()
+		}
+		gb.sched.sync(EVENT_SERIAL)
+	}
+}
+
+// disconnectLink closes the link cable connection, leaving the link port
+// disconnected
+func (gb *GameBoy) disconnectLink() {
+	if gb.link == nil {
+		return
+	}
+
+	if gb.link.pending {
+		gb.sched.sync(EVENT_SERIAL)
+		gb.mmu.serial.resolve(0xFF)
+	}
+	gb.link.close()
+	gb.link = nil
+	gb.mmu.serial.peer = DisconnectedPeer{}
+}
@@ -0,0 +1,171 @@
+package gb
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newLinkTestRom returns a ROM which writes 'sb' to SB, starts a transfer by
+// writing 'sc' to SC, then loops forever
+func newLinkTestRom(sb, sc byte) []byte {
+	return newTestRom(
+		0x3E, sb, // LD A,sb
+		0xE0, 0x01, // LDH (SB),A
+		0x3E, sc, // LD A,sc
+		0xE0, 0x02, // LDH (SC),A
+		0x18, 0xFE, // JR -2
+	)
+}
+
+// linkConns returns both ends of a link cable connection over the given
+// network ("pipe" for an in-process net.Pipe)
+func linkConns(t *testing.T, network string) (io.ReadWriteCloser, io.ReadWriteCloser) {
+	t.Helper()
+
+	if network == "pipe" {
+		return net.Pipe()
+	}
+
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		address = filepath.Join(t.TempDir(), "link.sock")
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", network, err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, _ := ln.Accept()
+		accepted <- conn
+	}()
+	conn1, err := DialLink(network, ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn1, <-accepted
+}
+
+// TestLink checks that two linked consoles exchange bytes over the link cable,
+// clocked by one of them
+func TestLink(t *testing.T) {
+	for _, network := range []string{"pipe", "unix", "tcp"} {
+		t.Run(network, func(t *testing.T) {
+			conn1, conn2 := linkConns(t, network)
+			master := newTestGameBoyFromRom(t, newLinkTestRom(0x42, SC_TRANSFER|SC_CLOCK_SELECT), WithLink(conn1))
+			slave := newTestGameBoyFromRom(t, newLinkTestRom(0x99, SC_TRANSFER), WithLink(conn2))
+			master.cpuWrite(IO_IF, 0x00)
+			slave.cpuWrite(IO_IF, 0x00)
+
+			var wg sync.WaitGroup
+			for _, gb := range []*GameBoy{master, slave} {
+				wg.Add(1)
+				go func(gb *GameBoy) {
+					defer wg.Done()
+					for gb.Cpu.cycles < 16*LINK_QUANTUM {
+						gb.Cpu.execNextInst()
+					}
+				}(gb)
+			}
+			wg.Wait()
+			master.disconnectLink()
+			slave.disconnectLink()
+
+			if got := master.cpuRead(IO_SB); got != 0x99 {
+				t.Errorf("master SB = %#02x, want 0x99", got)
+			}
+			if got := slave.cpuRead(IO_SB); got != 0x42 {
+				t.Errorf("slave SB = %#02x, want 0x42", got)
+			}
+			for name, gb := range map[string]*GameBoy{"master": master, "slave": slave} {
+				if gb.cpuRead(IO_SC)&SC_TRANSFER > 0 {
+					t.Errorf("%s transfer still active", name)
+				}
+				if gb.cpuRead(IO_IF)&byte(INT_SERIAL) == 0 {
+					t.Errorf("%s serial interrupt not requested", name)
+				}
+			}
+		})
+	}
+}
+
+// TestLinkDisconnect checks that a console keeps running with a disconnected
+// link port once its peer goes away
+func TestLinkDisconnect(t *testing.T) {
+	conn1, conn2 := net.Pipe()
+	gb := newTestGameBoyFromRom(t, newLinkTestRom(0x42, SC_TRANSFER|SC_CLOCK_SELECT), WithLink(conn1))
+	conn2.Close()
+
+	for gb.Cpu.cycles < 16*LINK_QUANTUM {
+		gb.Cpu.execNextInst()
+	}
+	if gb.link != nil {
+		t.Error("link still connected")
+	}
+	if got := gb.cpuRead(IO_SB); got != 0xFF {
+		t.Errorf("SB = %#02x after disconnected transfer, want 0xff", got)
+	}
+}
+
+// linkTestConn is a link cable connection made of separate read and write
+// pipes, so a test peer can stop reading while still sending
+type linkTestConn struct {
+	*io.PipeReader
+	*io.PipeWriter
+}
+
+// Close closes both pipes
+func (c linkTestConn) Close() error {
+	c.PipeWriter.Close()
+	return c.PipeReader.Close()
+}
+
+// TestLinkPeerClosed checks that a console disconnects its link port, rather
+// than hanging, when its peer closes its side of the connection during a run
+func TestLinkPeerClosed(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	gb := newTestGameBoyFromRom(t, newLinkTestRom(0x42, SC_TRANSFER|SC_CLOCK_SELECT),
+		WithLink(linkTestConn{inR, outW}))
+
+	// The peer answers every message, and closes its reading side after a
+	// few quanta. Writes then fail while reads still succeed.
+	go func() {
+		var msg [LINK_MESSAGE_SIZE]byte
+		for {
+			if _, err := inW.Write(msg[:]); err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		var msg [LINK_MESSAGE_SIZE]byte
+		for i := 0; i < 4; i++ {
+			io.ReadFull(outR, msg[:])
+		}
+		outR.Close()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for gb.link != nil && gb.Cpu.cycles < 64*LINK_QUANTUM {
+			gb.Cpu.execNextInst()
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("console hung after its peer closed the connection")
+	}
+
+	if gb.link != nil {
+		t.Error("link still connected")
+	}
+}
@@ -0,0 +1,140 @@
+package gb
+
+import "fmt"
+
+const (
+	ROM_BANK_SIZE = 0x4000 // 16KB
+	RAM_BANK_SIZE = 0x2000 // 8KB
+)
+
+// mbc is a cartridge memory bank controller (mapper). It maps the cartridge's
+// ROM and RAM into the CPU address space, and is configured by writes to the
+// cartridge ROM address range.
+type mbc interface {
+	// read reads from cartridge ROM (0x0000-0x7FFF) or RAM (0xA000-0xBFFF)
+	read(addr uint16) byte
+
+	// write writes to a mapper register (0x0000-0x7FFF) or cartridge RAM
+	// (0xA000-0xBFFF)
+	write(addr uint16, data byte)
+}
+
+// batteryBacked is implemented by memory bank controllers whose RAM can be
+// kept by a battery while the console is off
+type batteryBacked interface {
+	// saveData returns the contents of cartridge RAM, in the raw format used
+	// for save files
+	saveData() []byte
+
+	// loadSaveData restores cartridge RAM from the given save file contents
+	loadSaveData(data []byte) error
+}
+
+// newMBC returns the memory bank controller used by the given cartridge,
+// selected by the cartridge type found in its header. Real-time clocks found
+// in the cartridge are driven by the given clock, and rumble motors report
+// their state to the given function.
+func newMBC(cart *Cartridge, clock Clock, rumble func(on bool)) (mbc, error) {
+	h := cart.Header
+
+	switch h.CartridgeType {
+	case 0x00, 0x08, 0x09:
+		return newRomOnly(cart.rom, h.RAMSize), nil
+	case 0x01, 0x02, 0x03:
+		return newMBC1(cart.rom, h.RAMSize), nil
+	case 0x05, 0x06:
+		return newMBC2(cart.rom), nil
+	case 0x0B, 0x0C, 0x0D:
+		return newMMM01(cart.rom, h.RAMSize), nil
+	case 0x0F, 0x10:
+		return newMBC3(cart.rom, h.RAMSize, clock), nil
+	case 0x11, 0x12, 0x13:
+		return newMBC3(cart.rom, h.RAMSize, nil), nil
+	case 0x19, 0x1A, 0x1B:
+		return newMBC5(cart.rom, h.RAMSize, nil), nil
+	case 0x1C, 0x1D, 0x1E:
+		return newMBC5(cart.rom, h.RAMSize, rumble), nil
+	case 0xFE:
+		return newHuC3(cart.rom, h.RAMSize, clock), nil
+	case 0xFF:
+		return newHuC1(cart.rom, h.RAMSize), nil
+	default:
+		return nil, fmt.Errorf("%w: %s (%#02x)", errUnsupportedCartridge,
+			h.TypeName(), h.CartridgeType)
+	}
+}
+
+// romBankCount returns the number of 16KB banks in the given ROM, rounded up
+// to a power of two
+func romBankCount(rom []byte) int {
+	banks := 2
+	for banks*ROM_BANK_SIZE < len(rom) {
+		banks *= 2
+	}
+	return banks
+}
+
+// readRomBank reads from the given 16KB ROM bank, at the given address offset
+// within the bank. Reads beyond the end of the ROM return 0xFF (open bus).
+func readRomBank(rom []byte, bank int, addr uint16) byte {
+	i := bank*ROM_BANK_SIZE + int(addr&(ROM_BANK_SIZE-1))
+	if i >= len(rom) {
+		return 0xFF
+	}
+	return rom[i]
+}
+
+// loadRAM copies the given save data into cartridge RAM. The save data must be
+// the same size as the RAM.
+func loadRAM(ram []byte, data []byte) error {
+	if len(data) != len(ram) {
+		return fmt.Errorf("%w: %d bytes, want %d", errSaveSize, len(data), len(ram))
+	}
+	copy(ram, data)
+	return nil
+}
+
+// romOnly is a cartridge without a memory bank controller. Up to 32KB of ROM,
+// and optionally 8KB of RAM, are mapped directly into the CPU address space.
+type romOnly struct {
+	rom []byte
+	ram []byte
+}
+
+// newRomOnly returns a mapper for a cartridge containing only the given ROM,
+// and RAM of the given size in bytes
+func newRomOnly(rom []byte, ramSize int) *romOnly {
+	return &romOnly{rom: rom, ram: make([]byte, ramSize)}
+}
+
+func (m *romOnly) read(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_01_END:
+		if int(addr) >= len(m.rom) {
+			return 0xFF
+		}
+		return m.rom[addr]
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if len(m.ram) == 0 {
+			return 0xFF
+		}
+		return m.ram[int(addr-CARTRIDGE_RAM_START)%len(m.ram)]
+	}
+	return 0xFF
+}
+
+func (m *romOnly) write(addr uint16, data byte) {
+	if addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END && len(m.ram) > 0 {
+		m.ram[int(addr-CARTRIDGE_RAM_START)%len(m.ram)] = data
+	}
+}
+
+// saveData returns the contents of cartridge RAM
+func (m *romOnly) saveData() []byte {
+	return append([]byte(nil), m.ram...)
+}
+
+// loadSaveData restores cartridge RAM from the given save data
+func (m *romOnly) loadSaveData(data []byte) error {
+	return loadRAM(m.ram, data)
+}
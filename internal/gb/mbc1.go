@@ -0,0 +1,136 @@
+package gb
+
+import "bytes"
+
+// mbc1 is the MBC1 memory bank controller, supporting up to 2MB of ROM and
+// 32KB of RAM.
+//
+// reference: https://gbdev.io/pandocs/MBC1.html
+type mbc1 struct {
+	rom []byte
+	ram []byte
+
+	romBanks int // number of 16KB ROM banks
+	ramBanks int // number of 8KB RAM banks
+
+	ramEnabled bool // 0x0000-0x1FFF
+	bank1      byte // 0x2000-0x3FFF, 5-bit ROM bank number
+	bank2      byte // 0x4000-0x5FFF, 2-bit RAM bank or upper ROM bank number
+	mode       byte // 0x6000-0x7FFF, banking mode select
+
+	// MBC1M multicarts wire only 4 bits of the bank1 register, so bank2
+	// selects one of four 256KB games instead of a 512KB region
+	multicart bool
+}
+
+// newMBC1 returns an MBC1 mapping the given ROM, and RAM of the given size in
+// bytes
+func newMBC1(rom []byte, ramSize int) *mbc1 {
+	m := &mbc1{
+		rom:       rom,
+		ram:       make([]byte, ramSize),
+		romBanks:  romBankCount(rom),
+		ramBanks:  (ramSize + RAM_BANK_SIZE - 1) / RAM_BANK_SIZE,
+		bank1:     1,
+		multicart: isMBC1M(rom),
+	}
+	return m
+}
+
+// isMBC1M returns whether the given ROM is an MBC1M multicart. These are 1MB
+// ROMs containing a game in each 256KB region, detected by the Nintendo logo
+// found in the header of the second game.
+func isMBC1M(rom []byte) bool {
+	const logoStart, logoEnd = 0x0104, 0x0133
+	const secondGame = 0x10 * ROM_BANK_SIZE
+
+	if len(rom) != 64*ROM_BANK_SIZE {
+		return false
+	}
+	return bytes.Equal(rom[logoStart:logoEnd+1], rom[secondGame+logoStart:secondGame+logoEnd+1])
+}
+
+// bankShift returns the bit position of bank2 in the ROM bank number
+func (m *mbc1) bankShift() byte {
+	if m.multicart {
+		return 4
+	}
+	return 5
+}
+
+// romBank0 returns the ROM bank mapped at 0x0000-0x3FFF
+func (m *mbc1) romBank0() int {
+	if m.mode == 0 {
+		return 0
+	}
+	bank := int(m.bank2 << m.bankShift())
+	return bank & (m.romBanks - 1)
+}
+
+// romBank1 returns the ROM bank mapped at 0x4000-0x7FFF
+func (m *mbc1) romBank1() int {
+	lo := m.bank1
+	if m.multicart {
+		lo &= 0x0F
+	}
+	bank := int(m.bank2<<m.bankShift() | lo)
+	return bank & (m.romBanks - 1)
+}
+
+// ramOffset returns the offset in cartridge RAM of the given address
+func (m *mbc1) ramOffset(addr uint16) int {
+	bank := 0
+	if m.mode == 1 && m.ramBanks > 1 {
+		bank = int(m.bank2) % m.ramBanks
+	}
+	offset := bank*RAM_BANK_SIZE + int(addr-CARTRIDGE_RAM_START)
+	return offset % len(m.ram)
+}
+
+func (m *mbc1) read(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_00_END:
+		return readRomBank(m.rom, m.romBank0(), addr)
+	case addr <= CARTRIDGE_ROM_01_END:
+		return readRomBank(m.rom, m.romBank1(), addr)
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return 0xFF
+		}
+		return m.ram[m.ramOffset(addr)]
+	}
+	return 0xFF
+}
+
+func (m *mbc1) write(addr uint16, data byte) {
+	switch {
+	case addr <= 0x1FFF:
+		m.ramEnabled = data&0x0F == 0x0A
+	case addr <= 0x3FFF:
+		// Bank 0 can't be selected in the 0x4000-0x7FFF region, selecting it
+		// selects bank 1 instead. Only the 5-bit register is checked, so banks
+		// 0x20, 0x40 and 0x60 are also unreachable.
+		m.bank1 = data & 0x1F
+		if m.bank1 == 0 {
+			m.bank1 = 1
+		}
+	case addr <= 0x5FFF:
+		m.bank2 = data & 0x03
+	case addr <= CARTRIDGE_ROM_01_END:
+		m.mode = data & 0x01
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if m.ramEnabled && len(m.ram) > 0 {
+			m.ram[m.ramOffset(addr)] = data
+		}
+	}
+}
+
+// saveData returns the contents of cartridge RAM
+func (m *mbc1) saveData() []byte {
+	return append([]byte(nil), m.ram...)
+}
+
+// loadSaveData restores cartridge RAM from the given save data
+func (m *mbc1) loadSaveData(data []byte) error {
+	return loadRAM(m.ram, data)
+}
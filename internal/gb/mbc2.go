@@ -0,0 +1,70 @@
+package gb
+
+// MBC2_RAM_SIZE is the size of the MBC2's built-in RAM, in 4-bit values
+const MBC2_RAM_SIZE = 512
+
+// mbc2 is the MBC2 memory bank controller, supporting up to 256KB of ROM and
+// containing 512x4 bits of RAM.
+//
+// reference: https://gbdev.io/pandocs/MBC2.html
+type mbc2 struct {
+	rom []byte
+	ram [MBC2_RAM_SIZE]byte // lower 4 bits of each byte are used
+
+	romBanks int // number of 16KB ROM banks
+
+	ramEnabled bool // 0x0000-0x3FFF, address bit 8 clear
+	romBank    byte // 0x0000-0x3FFF, address bit 8 set, 4-bit ROM bank number
+}
+
+// newMBC2 returns an MBC2 mapping the given ROM
+func newMBC2(rom []byte) *mbc2 {
+	return &mbc2{rom: rom, romBanks: romBankCount(rom), romBank: 1}
+}
+
+func (m *mbc2) read(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_00_END:
+		return readRomBank(m.rom, 0, addr)
+	case addr <= CARTRIDGE_ROM_01_END:
+		return readRomBank(m.rom, int(m.romBank)&(m.romBanks-1), addr)
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if !m.ramEnabled {
+			return 0xFF
+		}
+		// Only the lower 9 address bits are used, so RAM is echoed throughout
+		// 0xA000-0xBFFF. The upper 4 bits of each value are undefined, and
+		// read as 1.
+		return 0xF0 | m.ram[addr&(MBC2_RAM_SIZE-1)]
+	}
+	return 0xFF
+}
+
+func (m *mbc2) write(addr uint16, data byte) {
+	switch {
+	case addr <= CARTRIDGE_ROM_00_END:
+		// Address bit 8 selects the register being written
+		if addr&0x0100 == 0 {
+			m.ramEnabled = data&0x0F == 0x0A
+		} else {
+			m.romBank = data & 0x0F
+			if m.romBank == 0 {
+				m.romBank = 1
+			}
+		}
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if m.ramEnabled {
+			m.ram[addr&(MBC2_RAM_SIZE-1)] = data & 0x0F
+		}
+	}
+}
+
+// saveData returns the contents of cartridge RAM
+func (m *mbc2) saveData() []byte {
+	return append([]byte(nil), m.ram[:]...)
+}
+
+// loadSaveData restores cartridge RAM from the given save data
+func (m *mbc2) loadSaveData(data []byte) error {
+	return loadRAM(m.ram[:], data)
+}
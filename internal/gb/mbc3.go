@@ -0,0 +1,334 @@
+package gb
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// RTC register select values, written to 0x4000-0x5FFF
+const (
+	RTC_S  = 0x08 // Seconds
+	RTC_M  = 0x09 // Minutes
+	RTC_H  = 0x0A // Hours
+	RTC_DL = 0x0B // Lower 8 bits of day counter
+	RTC_DH = 0x0C // Bit 0: day counter bit 8, bit 6: halt, bit 7: day carry
+)
+
+// RTC_SAVE_SIZE is the size of the real-time clock state stored after the
+// battery-backed RAM in save files
+const RTC_SAVE_SIZE = 48
+
+// mbc3 is the MBC3 memory bank controller, supporting up to 2MB of ROM, 32KB
+// of RAM, and an optional real-time clock.
+//
+// reference: https://gbdev.io/pandocs/MBC3.html
+type mbc3 struct {
+	rom []byte
+	ram []byte
+
+	romBanks int // number of 16KB ROM banks
+
+	ramEnabled bool // 0x0000-0x1FFF, also enables the RTC
+	romBank    byte // 0x2000-0x3FFF, 7-bit ROM bank number
+	ramBank    byte // 0x4000-0x5FFF, RAM bank number or RTC register select
+	latchWrite byte // 0x6000-0x7FFF, last byte written, latching on 0x00->0x01
+
+	rtc *rtc // nil if the cartridge has no timer
+}
+
+// newMBC3 returns an MBC3 mapping the given ROM, RAM of the given size in
+// bytes, and a real-time clock driven by the given clock if 'clock' is not nil
+func newMBC3(rom []byte, ramSize int, clock Clock) *mbc3 {
+	m := &mbc3{
+		rom:        rom,
+		ram:        make([]byte, ramSize),
+		romBanks:   romBankCount(rom),
+		romBank:    1,
+		latchWrite: 0xFF,
+	}
+	if clock != nil {
+		m.rtc = newRTC(clock)
+	}
+	return m
+}
+
+// ramOffset returns the offset in cartridge RAM of the given address
+func (m *mbc3) ramOffset(addr uint16) int {
+	offset := int(m.ramBank)*RAM_BANK_SIZE + int(addr-CARTRIDGE_RAM_START)
+	return offset % len(m.ram)
+}
+
+func (m *mbc3) read(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_00_END:
+		return readRomBank(m.rom, 0, addr)
+	case addr <= CARTRIDGE_ROM_01_END:
+		return readRomBank(m.rom, int(m.romBank)&(m.romBanks-1), addr)
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if !m.ramEnabled {
+			return 0xFF
+		}
+		if m.ramBank >= RTC_S {
+			if m.rtc == nil {
+				return 0xFF
+			}
+			return m.rtc.read(m.ramBank)
+		}
+		if len(m.ram) == 0 {
+			return 0xFF
+		}
+		return m.ram[m.ramOffset(addr)]
+	}
+	return 0xFF
+}
+
+func (m *mbc3) write(addr uint16, data byte) {
+	switch {
+	case addr <= 0x1FFF:
+		m.ramEnabled = data&0x0F == 0x0A
+	case addr <= 0x3FFF:
+		m.romBank = data & 0x7F
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+	case addr <= 0x5FFF:
+		if data <= 0x03 || (data >= RTC_S && data <= RTC_DH) {
+			m.ramBank = data
+		}
+	case addr <= CARTRIDGE_ROM_01_END:
+		if m.latchWrite == 0x00 && data == 0x01 && m.rtc != nil {
+			m.rtc.latch()
+		}
+		m.latchWrite = data
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if !m.ramEnabled {
+			return
+		}
+		if m.ramBank >= RTC_S {
+			if m.rtc != nil {
+				m.rtc.write(m.ramBank, data)
+			}
+			return
+		}
+		if len(m.ram) > 0 {
+			m.ram[m.ramOffset(addr)] = data
+		}
+	}
+}
+
+// saveData returns the contents of cartridge RAM, followed by the real-time
+// clock state if the cartridge has a timer
+func (m *mbc3) saveData() []byte {
+	data := append([]byte(nil), m.ram...)
+	if m.rtc != nil {
+		data = append(data, m.rtc.marshal()...)
+	}
+	return data
+}
+
+// clockSaveSize returns the size of the real-time clock state at the end of
+// the save data
+func (m *mbc3) clockSaveSize() int {
+	if m.rtc == nil {
+		return 0
+	}
+	return RTC_SAVE_SIZE
+}
+
+// clockSet returns whether the game has written the real-time clock registers
+// since the last call
+func (m *mbc3) clockSet() bool {
+	if m.rtc == nil {
+		return false
+	}
+	set := m.rtc.set
+	m.rtc.set = false
+	return set
+}
+
+// loadSaveData restores cartridge RAM, and the real-time clock state if
+// present, from the given save data
+func (m *mbc3) loadSaveData(data []byte) error {
+	if m.rtc != nil && len(data) == len(m.ram)+RTC_SAVE_SIZE {
+		if err := m.rtc.unmarshal(data[len(m.ram):]); err != nil {
+			return err
+		}
+		data = data[:len(m.ram)]
+	}
+	return loadRAM(m.ram, data)
+}
+
+// rtc is the MBC3 real-time clock. The clock counts seconds, minutes, hours
+// and days, advancing with the time reported by its Clock. Reads return the
+// values captured by the most recent latch.
+type rtc struct {
+	clock      Clock
+	lastUpdate time.Time // time the registers were last advanced to
+
+	s, m, h byte
+	days    uint16 // 9-bit day counter
+	halt    bool
+	carry   bool // day counter overflow
+
+	latched [5]byte // RTC_S-RTC_DH, as of the last latch
+
+	set bool // registers written since 'clockSet' was last called
+}
+
+// newRTC returns a real-time clock driven by the given clock
+func newRTC(clock Clock) *rtc {
+	return &rtc{clock: clock, lastUpdate: clock.Now()}
+}
+
+// update advances the clock registers by the whole seconds elapsed since the
+// last update. Time does not pass while the clock is halted.
+func (r *rtc) update() {
+	now := r.clock.Now()
+	if r.halt || now.Before(r.lastUpdate) {
+		r.lastUpdate = now
+		return
+	}
+
+	elapsed := int64(now.Sub(r.lastUpdate) / time.Second)
+	r.lastUpdate = r.lastUpdate.Add(time.Duration(elapsed) * time.Second)
+	r.advance(elapsed)
+}
+
+// advance advances the clock registers by the given number of seconds
+func (r *rtc) advance(seconds int64) {
+	// Registers written with out of range values count up to their maximum
+	// before wrapping to 0, without carrying
+	for seconds > 0 && (r.s >= 60 || r.m >= 60 || r.h >= 24) {
+		r.tick()
+		seconds--
+	}
+
+	total := ((int64(r.days)*24+int64(r.h))*60+int64(r.m))*60 + int64(r.s) + seconds
+	r.s = byte(total % 60)
+	r.m = byte(total / 60 % 60)
+	r.h = byte(total / 3600 % 24)
+	days := total / 86400
+	if days >= 512 {
+		r.carry = true
+		days %= 512
+	}
+	r.days = uint16(days)
+}
+
+// tick advances the clock registers by one second
+func (r *rtc) tick() {
+	r.s = (r.s + 1) & 0x3F
+	if r.s != 60 {
+		return
+	}
+	r.s = 0
+	r.m = (r.m + 1) & 0x3F
+	if r.m != 60 {
+		return
+	}
+	r.m = 0
+	r.h = (r.h + 1) & 0x1F
+	if r.h != 24 {
+		return
+	}
+	r.h = 0
+	r.days = (r.days + 1) & 0x1FF
+	if r.days == 0 {
+		r.carry = true
+	}
+}
+
+// latch copies the current clock registers into the latched registers
+func (r *rtc) latch() {
+	r.update()
+	r.latched[RTC_S-RTC_S] = r.s
+	r.latched[RTC_M-RTC_S] = r.m
+	r.latched[RTC_H-RTC_S] = r.h
+	r.latched[RTC_DL-RTC_S] = byte(r.days)
+	r.latched[RTC_DH-RTC_S] = r.dh()
+}
+
+// dh returns the value of the RTC_DH register
+func (r *rtc) dh() byte {
+	dh := byte(r.days>>8) & 0x01
+	if r.halt {
+		dh |= 1 << 6
+	}
+	if r.carry {
+		dh |= 1 << 7
+	}
+	return dh
+}
+
+// read reads the latched value of the given RTC register
+func (r *rtc) read(reg byte) byte {
+	return r.latched[reg-RTC_S]
+}
+
+// marshal returns the clock state in the 48-byte format used by BGB and VBA-M:
+// the current and latched registers as 32-bit little endian values, followed
+// by the 64-bit UNIX timestamp the registers were last updated at.
+//
+// reference: https://bgb.bircd.org/rtcsave.html
+func (r *rtc) marshal() []byte {
+	r.update()
+
+	data := make([]byte, RTC_SAVE_SIZE)
+	current := [5]byte{r.s, r.m, r.h, byte(r.days), r.dh()}
+	for i, reg := range current {
+		binary.LittleEndian.PutUint32(data[i*4:], uint32(reg))
+	}
+	for i, reg := range r.latched {
+		binary.LittleEndian.PutUint32(data[20+i*4:], uint32(reg))
+	}
+	binary.LittleEndian.PutUint64(data[40:], uint64(r.lastUpdate.Unix()))
+	return data
+}
+
+// unmarshal restores the clock state from the format returned by 'marshal',
+// advancing the clock by the time passed since it was saved
+func (r *rtc) unmarshal(data []byte) error {
+	if len(data) != RTC_SAVE_SIZE {
+		return errRTCSaveSize
+	}
+
+	reg := func(i int) byte {
+		return byte(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	r.s = reg(0) & 0x3F
+	r.m = reg(1) & 0x3F
+	r.h = reg(2) & 0x1F
+	r.days = uint16(reg(3)) | uint16(reg(4)&0x01)<<8
+	r.halt = reg(4)&(1<<6) > 0
+	r.carry = reg(4)&(1<<7) > 0
+	for i := range r.latched {
+		r.latched[i] = reg(5 + i)
+	}
+	r.lastUpdate = time.Unix(int64(binary.LittleEndian.Uint64(data[40:])), 0)
+
+	r.update()
+	return nil
+}
+
+// write writes to the given RTC register
+func (r *rtc) write(reg byte, data byte) {
+	r.update()
+	r.set = true
+
+	switch reg {
+	case RTC_S:
+		r.s = data & 0x3F
+		// Writing the seconds resets the sub-second counter
+		r.lastUpdate = r.clock.Now()
+	case RTC_M:
+		r.m = data & 0x3F
+	case RTC_H:
+		r.h = data & 0x1F
+	case RTC_DL:
+		r.days = r.days&0x100 | uint16(data)
+	case RTC_DH:
+		r.days = r.days&0xFF | uint16(data&0x01)<<8
+		r.halt = data&(1<<6) > 0
+		r.carry = data&(1<<7) > 0
+	}
+}
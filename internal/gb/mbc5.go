@@ -0,0 +1,88 @@
+package gb
+
+// mbc5 is the MBC5 memory bank controller, supporting up to 8MB of ROM, 128KB
+// of RAM, and an optional rumble motor.
+//
+// reference: https://gbdev.io/pandocs/MBC5.html
+type mbc5 struct {
+	rom []byte
+	ram []byte
+
+	romBanks int // number of 16KB ROM banks
+
+	ramEnabled bool   // 0x0000-0x1FFF
+	romBank    uint16 // 0x2000-0x2FFF low 8 bits, 0x3000-0x3FFF bit 8
+	ramBank    byte   // 0x4000-0x5FFF, 4-bit RAM bank number
+
+	// Rumble cartridges wire bit 3 of the RAM bank register to the motor. The
+	// given function is called with the new motor state when it's written.
+	rumble func(on bool)
+}
+
+// newMBC5 returns an MBC5 mapping the given ROM, and RAM of the given size in
+// bytes. If 'rumble' is not nil, the cartridge has a rumble motor controlled
+// through the RAM bank register.
+func newMBC5(rom []byte, ramSize int, rumble func(on bool)) *mbc5 {
+	m := &mbc5{
+		rom:      rom,
+		ram:      make([]byte, ramSize),
+		romBanks: romBankCount(rom),
+		romBank:  1,
+		rumble:   rumble,
+	}
+	return m
+}
+
+// ramOffset returns the offset in cartridge RAM of the given address
+func (m *mbc5) ramOffset(addr uint16) int {
+	offset := int(m.ramBank)*RAM_BANK_SIZE + int(addr-CARTRIDGE_RAM_START)
+	return offset % len(m.ram)
+}
+
+func (m *mbc5) read(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_00_END:
+		return readRomBank(m.rom, 0, addr)
+	case addr <= CARTRIDGE_ROM_01_END:
+		// Unlike MBC1 and MBC3, bank 0 can be mapped here
+		return readRomBank(m.rom, int(m.romBank)&(m.romBanks-1), addr)
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return 0xFF
+		}
+		return m.ram[m.ramOffset(addr)]
+	}
+	return 0xFF
+}
+
+func (m *mbc5) write(addr uint16, data byte) {
+	switch {
+	case addr <= 0x1FFF:
+		m.ramEnabled = data&0x0F == 0x0A
+	case addr <= 0x2FFF:
+		m.romBank = m.romBank&0x100 | uint16(data)
+	case addr <= 0x3FFF:
+		m.romBank = m.romBank&0xFF | uint16(data&0x01)<<8
+	case addr <= 0x5FFF:
+		if m.rumble != nil {
+			m.ramBank = data & 0x07
+			m.rumble(data&0x08 > 0)
+		} else {
+			m.ramBank = data & 0x0F
+		}
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if m.ramEnabled && len(m.ram) > 0 {
+			m.ram[m.ramOffset(addr)] = data
+		}
+	}
+}
+
+// saveData returns the contents of cartridge RAM
+func (m *mbc5) saveData() []byte {
+	return append([]byte(nil), m.ram...)
+}
+
+// loadSaveData restores cartridge RAM from the given save data
+func (m *mbc5) loadSaveData(data []byte) error {
+	return loadRAM(m.ram, data)
+}
@@ -0,0 +1,496 @@
+package gb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newBankedRom returns a ROM of the given number of 16KB banks, with each
+// bank's number (low byte, high byte) stored in the first 2 bytes of the bank
+func newBankedRom(banks int) []byte {
+	rom := make([]byte, banks*ROM_BANK_SIZE)
+	for bank := 0; bank < banks; bank++ {
+		rom[bank*ROM_BANK_SIZE] = byte(bank)
+		rom[bank*ROM_BANK_SIZE+1] = byte(bank >> 8)
+	}
+	return rom
+}
+
+// mappedBank returns the number of the ROM bank mapped at the given address
+func mappedBank(m mbc, addr uint16) int {
+	return int(m.read(addr)) | int(m.read(addr+1))<<8
+}
+
+// TestMBC1 checks MBC1 ROM and RAM bank switching
+func TestMBC1(t *testing.T) {
+	m := newMBC1(newBankedRom(128), 32*1024)
+
+	if bank := mappedBank(m, 0x4000); bank != 1 {
+		t.Errorf("initial bank = %#02x, want 0x01", bank)
+	}
+
+	tests := []struct {
+		bank1, bank2, mode byte
+		wantBank0          int
+		wantBank1          int
+	}{
+		{0x00, 0, 0, 0x00, 0x01}, // bank 0 maps to bank 1
+		{0x05, 0, 0, 0x00, 0x05},
+		{0x1F, 0, 0, 0x00, 0x1F},
+		{0x00, 1, 0, 0x00, 0x21}, // bank 0x20 maps to bank 0x21
+		{0x12, 3, 0, 0x00, 0x72},
+		{0x12, 3, 1, 0x60, 0x72}, // mode 1 banks the 0x0000-0x3FFF region
+		{0xE3, 2, 1, 0x40, 0x43}, // only 5 bits of bank1 are used
+	}
+	for _, tt := range tests {
+		m.write(0x2000, tt.bank1)
+		m.write(0x4000, tt.bank2)
+		m.write(0x6000, tt.mode)
+
+		if bank := mappedBank(m, 0x0000); bank != tt.wantBank0 {
+			t.Errorf("bank1=%#02x bank2=%d mode=%d: 0x0000 bank = %#02x, want %#02x",
+				tt.bank1, tt.bank2, tt.mode, bank, tt.wantBank0)
+		}
+		if bank := mappedBank(m, 0x4000); bank != tt.wantBank1 {
+			t.Errorf("bank1=%#02x bank2=%d mode=%d: 0x4000 bank = %#02x, want %#02x",
+				tt.bank1, tt.bank2, tt.mode, bank, tt.wantBank1)
+		}
+	}
+
+	// RAM is disabled by default, and when disabled
+	m.write(0xA000, 0x42)
+	if got := m.read(0xA000); got != 0xFF {
+		t.Errorf("disabled RAM read %#02x, want 0xff", got)
+	}
+
+	m.write(0x0000, 0x0A)
+	m.write(0x6000, 1)
+	for bank := byte(0); bank < 4; bank++ {
+		m.write(0x4000, bank)
+		m.write(0xA000, 0x10+bank)
+	}
+	for bank := byte(0); bank < 4; bank++ {
+		m.write(0x4000, bank)
+		if got := m.read(0xA000); got != 0x10+bank {
+			t.Errorf("RAM bank %d read %#02x, want %#02x", bank, got, 0x10+bank)
+		}
+	}
+
+	// Mode 0 always uses RAM bank 0
+	m.write(0x6000, 0)
+	if got := m.read(0xA000); got != 0x10 {
+		t.Errorf("mode 0 RAM read %#02x, want 0x10", got)
+	}
+
+	m.write(0x0000, 0x00)
+	if got := m.read(0xA000); got != 0xFF {
+		t.Errorf("disabled RAM read %#02x, want 0xff", got)
+	}
+}
+
+// TestMBC1M checks bank switching in MBC1M multicarts, which use bank2 to
+// select 256KB games
+func TestMBC1M(t *testing.T) {
+	rom := newBankedRom(64)
+	for game := 0; game < 4; game++ {
+		copy(rom[game*0x10*ROM_BANK_SIZE+0x104:], nintendoLogo)
+	}
+
+	m := newMBC1(rom, 0)
+	if !m.multicart {
+		t.Fatal("MBC1M multicart not detected")
+	}
+
+	m.write(0x4000, 2)
+	m.write(0x2000, 0x13) // bit 4 is not wired
+	if bank := mappedBank(m, 0x4000); bank != 0x23 {
+		t.Errorf("0x4000 bank = %#02x, want 0x23", bank)
+	}
+	m.write(0x6000, 1)
+	if bank := mappedBank(m, 0x0000); bank != 0x20 {
+		t.Errorf("0x0000 bank = %#02x, want 0x20", bank)
+	}
+}
+
+// nintendoLogo is the logo bitmap found in every cartridge header
+var nintendoLogo = []byte{
+	0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B, 0x03, 0x73, 0x00, 0x83,
+	0x00, 0x0C, 0x00, 0x0D, 0x00, 0x08, 0x11, 0x1F, 0x88, 0x89, 0x00, 0x0E,
+	0xDC, 0xCC, 0x6E, 0xE6, 0xDD, 0xDD, 0xD9, 0x99, 0xBB, 0xBB, 0x67, 0x63,
+	0x6E, 0x0E, 0xEC, 0xCC, 0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E,
+}
+
+// testClock is a Clock whose time is advanced manually
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// readRTC latches the clock, and returns the value of the given RTC register
+func readRTC(m *mbc3, reg byte) byte {
+	m.write(0x6000, 0x00)
+	m.write(0x6000, 0x01)
+	m.write(0x4000, reg)
+	return m.read(0xA000)
+}
+
+// TestMBC3 checks MBC3 ROM and RAM bank switching
+func TestMBC3(t *testing.T) {
+	m := newMBC3(newBankedRom(128), 32*1024, nil)
+
+	for _, bank := range []int{0x01, 0x02, 0x40, 0x7F} {
+		m.write(0x2000, byte(bank))
+		if got := mappedBank(m, 0x4000); got != bank {
+			t.Errorf("0x4000 bank = %#02x, want %#02x", got, bank)
+		}
+	}
+	m.write(0x2000, 0x00)
+	if got := mappedBank(m, 0x4000); got != 0x01 {
+		t.Errorf("0x4000 bank = %#02x after selecting bank 0, want 0x01", got)
+	}
+
+	m.write(0x0000, 0x0A)
+	for bank := byte(0); bank < 4; bank++ {
+		m.write(0x4000, bank)
+		m.write(0xA000, 0x10+bank)
+	}
+	for bank := byte(0); bank < 4; bank++ {
+		m.write(0x4000, bank)
+		if got := m.read(0xA000); got != 0x10+bank {
+			t.Errorf("RAM bank %d read %#02x, want %#02x", bank, got, 0x10+bank)
+		}
+	}
+
+	// No RTC present
+	m.write(0x4000, RTC_S)
+	if got := m.read(0xA000); got != 0xFF {
+		t.Errorf("missing RTC read %#02x, want 0xff", got)
+	}
+}
+
+// TestMBC3RTC checks that the MBC3 real-time clock advances with its clock,
+// latches, halts, and carries
+func TestMBC3RTC(t *testing.T) {
+	clock := &testClock{now: time.Unix(0, 0)}
+	m := newMBC3(newBankedRom(4), 8*1024, clock)
+	m.write(0x0000, 0x0A)
+
+	clock.now = clock.now.Add(1*time.Hour + 2*time.Minute + 3*time.Second)
+	if s, mins, h := readRTC(m, RTC_S), readRTC(m, RTC_M), readRTC(m, RTC_H); s != 3 || mins != 2 || h != 1 {
+		t.Errorf("RTC = %02d:%02d:%02d, want 01:02:03", h, mins, s)
+	}
+
+	// Reads return the latched value until the next latch
+	clock.now = clock.now.Add(10 * time.Second)
+	if got := m.read(0xA000); got != 1 {
+		t.Errorf("unlatched hours = %d, want 1", got)
+	}
+	if got := readRTC(m, RTC_S); got != 13 {
+		t.Errorf("seconds = %d, want 13", got)
+	}
+
+	// Halted clocks don't advance
+	m.write(0x4000, RTC_DH)
+	m.write(0xA000, 1<<6)
+	clock.now = clock.now.Add(time.Minute)
+	if got := readRTC(m, RTC_S); got != 13 {
+		t.Errorf("halted seconds = %d, want 13", got)
+	}
+	m.write(0x4000, RTC_DH)
+	m.write(0xA000, 0x00)
+
+	// Day counter overflow sets the carry bit
+	clock.now = clock.now.Add(512 * 24 * time.Hour)
+	if dh := readRTC(m, RTC_DH); dh&(1<<7) == 0 {
+		t.Errorf("DH = %#02x, want carry bit set", dh)
+	}
+	if dl := readRTC(m, RTC_DL); dl != 0 {
+		t.Errorf("DL = %d, want 0", dl)
+	}
+}
+
+// TestRTCSave checks that the real-time clock state is restored from a save,
+// including the time passed since the save was made
+func TestRTCSave(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_000_000, 0)}
+	m := newMBC3(newBankedRom(4), 0, clock)
+	m.write(0x0000, 0x0A)
+
+	clock.now = clock.now.Add(2*24*time.Hour + 30*time.Second)
+	readRTC(m, RTC_S)
+	save := m.rtc.marshal()
+
+	clock.now = clock.now.Add(5 * time.Minute)
+	restored := newMBC3(newBankedRom(4), 0, clock)
+	restored.write(0x0000, 0x0A)
+	if err := restored.rtc.unmarshal(save); err != nil {
+		t.Fatal(err)
+	}
+
+	restored.write(0x4000, RTC_S)
+	if got := restored.read(0xA000); got != 30 {
+		t.Errorf("latched seconds = %d, want 30", got)
+	}
+	if s, mins, dl := readRTC(restored, RTC_S), readRTC(restored, RTC_M), readRTC(restored, RTC_DL); s != 30 || mins != 5 || dl != 2 {
+		t.Errorf("RTC = day %d %02d:%02d, want day 2 05:30", dl, mins, s)
+	}
+}
+
+// TestMBC5 checks MBC5 ROM and RAM bank switching
+func TestMBC5(t *testing.T) {
+	m := newMBC5(newBankedRom(512), 128*1024, nil)
+
+	for _, bank := range []int{0x000, 0x001, 0x0FF, 0x100, 0x1FF} {
+		m.write(0x2000, byte(bank))
+		m.write(0x3000, byte(bank>>8))
+		if got := mappedBank(m, 0x4000); got != bank {
+			t.Errorf("0x4000 bank = %#03x, want %#03x", got, bank)
+		}
+	}
+
+	m.write(0x0000, 0x0A)
+	for bank := byte(0); bank < 16; bank++ {
+		m.write(0x4000, bank)
+		m.write(0xA000, 0x10+bank)
+	}
+	for bank := byte(0); bank < 16; bank++ {
+		m.write(0x4000, bank)
+		if got := m.read(0xA000); got != 0x10+bank {
+			t.Errorf("RAM bank %d read %#02x, want %#02x", bank, got, 0x10+bank)
+		}
+	}
+}
+
+// TestMBC5Rumble checks that writes to the RAM bank register of a rumble
+// cartridge are reported by the GameBoy
+func TestMBC5Rumble(t *testing.T) {
+	rom := newTestRom(
+		0x3E, 0x08, // LD A,0x08
+		0xEA, 0x00, 0x40, // LD (0x4000),A
+		0x3E, 0x01, // LD A,0x01
+		0xEA, 0x00, 0x40, // LD (0x4000),A
+	)
+	rom[HEADER_CARTRIDGE_TYPE] = 0x1C // MBC5+RUMBLE
+	rom[HEADER_CHECKSUM] = headerChecksum(rom)
+
+	var events []bool
+	gb := newTestGameBoyFromRom(t, rom, WithRumbleHandler(func(on bool) {
+		events = append(events, on)
+	}))
+
+	gb.Cpu.execNextInst()
+	gb.Cpu.execNextInst()
+	if !gb.Rumbling() {
+		t.Error("rumble motor off after writing bit 3")
+	}
+	gb.Cpu.execNextInst()
+	gb.Cpu.execNextInst()
+	if gb.Rumbling() {
+		t.Error("rumble motor on after clearing bit 3")
+	}
+
+	if len(events) != 2 || !events[0] || events[1] {
+		t.Errorf("rumble events = %v, want [true false]", events)
+	}
+}
+
+// TestMBC2 checks MBC2 register selection by address bit 8, and its built-in
+// 4-bit RAM
+func TestMBC2(t *testing.T) {
+	m := newMBC2(newBankedRom(16))
+
+	// Address bit 8 set selects the ROM bank register
+	m.write(0x2100, 0x05)
+	if got := mappedBank(m, 0x4000); got != 0x05 {
+		t.Errorf("0x4000 bank = %#02x, want 0x05", got)
+	}
+	m.write(0x0100, 0x00)
+	if got := mappedBank(m, 0x4000); got != 0x01 {
+		t.Errorf("0x4000 bank = %#02x after selecting bank 0, want 0x01", got)
+	}
+
+	// Address bit 8 clear selects RAM enable, and doesn't change the bank
+	m.write(0x2000, 0x0A)
+	if got := mappedBank(m, 0x4000); got != 0x01 {
+		t.Errorf("0x4000 bank = %#02x after enabling RAM, want 0x01", got)
+	}
+
+	m.write(0xA000, 0x5C)
+	if got := m.read(0xA000); got != 0xFC {
+		t.Errorf("RAM read %#02x, want 0xfc", got)
+	}
+	// RAM is echoed every 512 bytes
+	if got := m.read(0xA200); got != 0xFC {
+		t.Errorf("RAM echo read %#02x, want 0xfc", got)
+	}
+
+	m.write(0x0000, 0x00)
+	if got := m.read(0xA000); got != 0xFF {
+		t.Errorf("disabled RAM read %#02x, want 0xff", got)
+	}
+}
+
+// TestMMM01 checks that an MMM01 starts in its menu, and maps the selected
+// game once locked
+func TestMMM01(t *testing.T) {
+	m := newMMM01(newBankedRom(128), 32*1024)
+
+	// The menu is in the last 32KB of ROM
+	if got := mappedBank(m, 0x0000); got != 126 {
+		t.Errorf("unmapped 0x0000 bank = %d, want 126", got)
+	}
+	if got := mappedBank(m, 0x4000); got != 127 {
+		t.Errorf("unmapped 0x4000 bank = %d, want 127", got)
+	}
+
+	// Select a 128KB game starting at bank 0x20, with ROM bank bits 3-4
+	// locked, then map it
+	m.write(0x2000, 0x20)
+	m.write(0x6000, 0x30)
+	m.write(0x0000, 0x40)
+
+	if got := mappedBank(m, 0x0000); got != 0x20 {
+		t.Errorf("mapped 0x0000 bank = %#02x, want 0x20", got)
+	}
+	if got := mappedBank(m, 0x4000); got != 0x21 {
+		t.Errorf("mapped 0x4000 bank = %#02x, want 0x21", got)
+	}
+
+	// The game can only switch banks within its own 128KB
+	m.write(0x2000, 0x1F)
+	if got := mappedBank(m, 0x4000); got != 0x27 {
+		t.Errorf("mapped 0x4000 bank = %#02x, want 0x27", got)
+	}
+
+	// Multicart registers are locked once mapped
+	m.write(0x0000, 0x0A)
+	m.write(0x6000, 0x00)
+	m.write(0x2000, 0x03)
+	if got := mappedBank(m, 0x4000); got != 0x23 {
+		t.Errorf("mapped 0x4000 bank = %#02x after unlocking, want 0x23", got)
+	}
+
+	m.write(0xA000, 0x42)
+	if got := m.read(0xA000); got != 0x42 {
+		t.Errorf("RAM read %#02x, want 0x42", got)
+	}
+}
+
+// TestHuC1 checks HuC1 bank switching and its infrared port
+func TestHuC1(t *testing.T) {
+	m := newHuC1(newBankedRom(64), 32*1024)
+
+	m.write(0x2000, 0x3F)
+	if got := mappedBank(m, 0x4000); got != 0x3F {
+		t.Errorf("0x4000 bank = %#02x, want 0x3f", got)
+	}
+
+	m.write(0x4000, 0x02)
+	m.write(0xA000, 0x42)
+	m.write(0x0000, HUC1_IR_SELECT)
+	if got := m.read(0xA000); got != 0xC0 {
+		t.Errorf("IR read %#02x, want 0xc0", got)
+	}
+	m.write(0xA000, 0x01)
+	if got := m.read(0xA000); got != 0xC0 {
+		t.Errorf("IR read %#02x with the LED on, want 0xc0", got)
+	}
+
+	m.write(0x0000, 0x0A)
+	if got := m.read(0xA000); got != 0x42 {
+		t.Errorf("RAM read %#02x, want 0x42", got)
+	}
+}
+
+// huc3Command runs a HuC3 RTC command, and returns the response nibble
+func huc3Command(m *huc3, command, arg byte) byte {
+	m.write(0x0000, HUC3_RTC_CMD)
+	m.write(0xA000, command<<4|arg)
+	m.write(0x0000, HUC3_RTC_RESP)
+	return m.read(0xA000) & 0x0F
+}
+
+// TestHuC3RTC checks that the HuC3 real-time clock can be set and read back
+// through RTC commands, and advances with its clock
+func TestHuC3RTC(t *testing.T) {
+	clock := &testClock{now: time.Unix(0, 0)}
+	m := newHuC3(newBankedRom(4), 8*1024, clock)
+
+	// Set the time to day 0x123, minute 1439
+	huc3Command(m, HUC3_CMD_ADDR_LO, 0)
+	huc3Command(m, HUC3_CMD_ADDR_HI, 0)
+	for _, n := range []byte{0xF, 0x9, 0x5, 0x3, 0x2, 0x1} {
+		huc3Command(m, HUC3_CMD_WRITE, n)
+	}
+	huc3Command(m, HUC3_CMD_EXTENDED, 0x1)
+
+	clock.now = clock.now.Add(90 * time.Second)
+
+	huc3Command(m, HUC3_CMD_EXTENDED, 0x0)
+	huc3Command(m, HUC3_CMD_ADDR_LO, 0)
+	var got [6]byte
+	for i := range got {
+		got[i] = huc3Command(m, HUC3_CMD_READ, 0)
+	}
+	if want := [6]byte{0x0, 0x0, 0x0, 0x4, 0x2, 0x1}; got != want {
+		t.Errorf("time nibbles = %x, want %x", got, want)
+	}
+
+	m.write(0x0000, HUC3_RTC_READY)
+	if got := m.read(0xA000) & 0x01; got != 1 {
+		t.Error("RTC not ready")
+	}
+}
+
+// TestHuC3RTCSave checks that the HuC3 real-time clock state and cartridge RAM
+// are restored from a save, including the time passed since the save was made
+func TestHuC3RTCSave(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_000_000, 0)}
+	m := newHuC3(newBankedRom(4), 8*1024, clock)
+	m.write(0x0000, HUC3_RAM)
+	m.write(0xA000, 0x42)
+
+	// Day 3, minute 100, and a nibble left in the clock's memory
+	huc3Command(m, HUC3_CMD_ADDR_LO, 0)
+	huc3Command(m, HUC3_CMD_ADDR_HI, 0)
+	for _, n := range []byte{0x4, 0x6, 0x0, 0x3, 0x0, 0x0, 0x7} {
+		huc3Command(m, HUC3_CMD_WRITE, n)
+	}
+	huc3Command(m, HUC3_CMD_EXTENDED, 0x1)
+	save := m.saveData()
+	if len(save) != 8*1024+HUC3_RTC_SAVE_SIZE {
+		t.Fatalf("save size = %d, want %d", len(save), 8*1024+HUC3_RTC_SAVE_SIZE)
+	}
+
+	clock.now = clock.now.Add(24*time.Hour + 5*time.Minute)
+	restored := newHuC3(newBankedRom(4), 8*1024, clock)
+	if err := restored.loadSaveData(save); err != nil {
+		t.Fatal(err)
+	}
+
+	restored.write(0x0000, HUC3_RAM)
+	if got := restored.read(0xA000); got != 0x42 {
+		t.Errorf("RAM read %#02x, want 0x42", got)
+	}
+	if got := restored.rtc.memory[6]; got != 0x7 {
+		t.Errorf("RTC memory nibble = %#x, want 0x7", got)
+	}
+	if restored.rtc.update(); restored.rtc.days != 4 || restored.rtc.minutes != 105 {
+		t.Errorf("RTC = day %d minute %d, want day 4 minute 105", restored.rtc.days, restored.rtc.minutes)
+	}
+}
+
+// TestUnsupportedMBC checks that cartridges with unsupported mappers are
+// refused
+func TestUnsupportedMBC(t *testing.T) {
+	for _, cartType := range []byte{0x20, 0x22, 0xFC, 0xFD} {
+		cart := &Cartridge{Header: CartridgeHeader{CartridgeType: cartType}}
+		if _, err := newMBC(cart, nil, nil); !errors.Is(err, errUnsupportedCartridge) {
+			t.Errorf("type %#02x error = %v, want %v", cartType, err, errUnsupportedCartridge)
+		}
+	}
+}
@@ -25,13 +25,60 @@ const (
 	INTERNAL_RAM_START = 0xC000 // 8KB
 	INTERNAL_RAM_END   = 0xDFFF
 
+	ECHO_RAM_START = 0xE000 // 7.5KB, mirror of 0xC000-0xDDFF
+	ECHO_RAM_END   = 0xFDFF
+
 	OAM_START = 0xFE00 // 160B
 	OAM_END   = 0xFE9F
 
+	UNUSABLE_START = 0xFEA0 // 96B
+	UNUSABLE_END   = 0xFEFF
+
 	IO_REGISTERS_START = 0xFF00 // 128B
 	IO_REGISTERS_END   = 0xFF7F
+	IO_P1              = 0xFF00 // Joypad (R/W)
 	IO_SB              = 0xFF01 // Serial transfer data (R/W)
 	IO_SC              = 0xFF02 // Serial transfer control (R/W)
+	IO_DIV             = 0xFF04 // Divider register (R/W)
+	IO_TIMA            = 0xFF05 // Timer counter (R/W)
+	IO_TMA             = 0xFF06 // Timer modulo (R/W)
+	IO_TAC             = 0xFF07 // Timer control (R/W)
+	IO_IF              = 0xFF0F // Interrupt flag (R/W)
+	IO_NR10            = 0xFF10 // Channel 1 sweep (R/W)
+	IO_NR11            = 0xFF11 // Channel 1 duty & length (R/W)
+	IO_NR12            = 0xFF12 // Channel 1 volume & envelope (R/W)
+	IO_NR13            = 0xFF13 // Channel 1 period low (W)
+	IO_NR14            = 0xFF14 // Channel 1 period high & control (R/W)
+	IO_NR21            = 0xFF16 // Channel 2 duty & length (R/W)
+	IO_NR22            = 0xFF17 // Channel 2 volume & envelope (R/W)
+	IO_NR23            = 0xFF18 // Channel 2 period low (W)
+	IO_NR24            = 0xFF19 // Channel 2 period high & control (R/W)
+	IO_NR30            = 0xFF1A // Channel 3 DAC enable (R/W)
+	IO_NR31            = 0xFF1B // Channel 3 length (W)
+	IO_NR32            = 0xFF1C // Channel 3 output level (R/W)
+	IO_NR33            = 0xFF1D // Channel 3 period low (W)
+	IO_NR34            = 0xFF1E // Channel 3 period high & control (R/W)
+	IO_NR41            = 0xFF20 // Channel 4 length (W)
+	IO_NR42            = 0xFF21 // Channel 4 volume & envelope (R/W)
+	IO_NR43            = 0xFF22 // Channel 4 frequency & randomness (R/W)
+	IO_NR44            = 0xFF23 // Channel 4 control (R/W)
+	IO_NR50            = 0xFF24 // Master volume & VIN panning (R/W)
+	IO_NR51            = 0xFF25 // Sound panning (R/W)
+	IO_NR52            = 0xFF26 // Sound on/off (R/W)
+	WAVE_RAM_START     = 0xFF30 // 16B, channel 3 wave pattern
+	WAVE_RAM_END       = 0xFF3F
+	IO_LCDC            = 0xFF40 // LCD control (R/W)
+	IO_STAT            = 0xFF41 // LCD status (R/W)
+	IO_SCY             = 0xFF42 // Background viewport Y (R/W)
+	IO_SCX             = 0xFF43 // Background viewport X (R/W)
+	IO_LY              = 0xFF44 // LCD Y coordinate (R)
+	IO_LYC             = 0xFF45 // LY compare (R/W)
+	IO_DMA             = 0xFF46 // OAM DMA source address & start (R/W)
+	IO_BGP             = 0xFF47 // Background palette (R/W)
+	IO_OBP0            = 0xFF48 // Object palette 0 (R/W)
+	IO_OBP1            = 0xFF49 // Object palette 1 (R/W)
+	IO_WY              = 0xFF4A // Window Y position (R/W)
+	IO_WX              = 0xFF4B // Window X position + 7 (R/W)
 
 	HRAM_BEGIN = 0xFF80 // 127B
 	HRAM_END   = 0xFFFE
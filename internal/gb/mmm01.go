@@ -0,0 +1,151 @@
+package gb
+
+// mmm01 is the MMM01 memory bank controller, used by multicarts. It starts in
+// an "unmapped" mode running the menu found in the last 32KB of ROM, which
+// configures the outer ROM/RAM bank bits and masks for the selected game.
+// Mapping then locks those bits, and the game sees an MBC1-like controller.
+//
+// reference: https://gbdev.io/pandocs/MMM01.html
+type mmm01 struct {
+	rom []byte
+	ram []byte
+
+	romBanks int // number of 16KB ROM banks
+	ramBanks int // number of 8KB RAM banks
+
+	mapped bool // set by bit 6 of 0x0000-0x1FFF, locks the multicart bits
+
+	ramEnabled bool // 0x0000-0x1FFF bits 0-3
+	ramMask    byte // 0x0000-0x1FFF bits 4-5, RAM bank bits locked in mapped mode
+
+	romBankLo   byte // 0x2000-0x3FFF bits 0-4
+	romBankMid  byte // 0x2000-0x3FFF bits 5-6
+	ramBankLo   byte // 0x4000-0x5FFF bits 0-1
+	ramBankHi   byte // 0x4000-0x5FFF bits 2-3
+	romBankHi   byte // 0x4000-0x5FFF bits 4-5
+	modeLocked  bool // 0x4000-0x5FFF bit 6, disables mode writes
+	mode        byte // 0x6000-0x7FFF bit 0, MBC1 banking mode
+	romMask     byte // 0x6000-0x7FFF bits 2-5, ROM bank bits 1-4 locked in mapped mode
+	multiplexed bool // 0x6000-0x7FFF bit 6, swaps ROM bank hi and RAM bank lo in mode 1
+}
+
+// newMMM01 returns an MMM01 mapping the given ROM, and RAM of the given size
+// in bytes
+func newMMM01(rom []byte, ramSize int) *mmm01 {
+	return &mmm01{
+		rom:      rom,
+		ram:      make([]byte, ramSize),
+		romBanks: romBankCount(rom),
+		ramBanks: (ramSize + RAM_BANK_SIZE - 1) / RAM_BANK_SIZE,
+	}
+}
+
+// outerRomBank returns the ROM bank number bits 5-8, selecting the 512KB
+// region of the current game
+func (m *mmm01) outerRomBank() int {
+	hi := m.romBankHi
+	if m.multiplexed && m.mode == 1 {
+		hi = m.ramBankLo
+	}
+	return int(hi)<<7 | int(m.romBankMid)<<5
+}
+
+// romBank0 returns the ROM bank mapped at 0x0000-0x3FFF
+func (m *mmm01) romBank0() int {
+	if !m.mapped {
+		return (m.romBanks - 2) & 0x1FF
+	}
+	// Locked bank bits are kept, all others read as 0
+	lo := m.romBankLo & (m.romMask << 1)
+	return (m.outerRomBank() | int(lo)) & (m.romBanks - 1)
+}
+
+// romBank1 returns the ROM bank mapped at 0x4000-0x7FFF
+func (m *mmm01) romBank1() int {
+	if !m.mapped {
+		return (m.romBanks - 1) & 0x1FF
+	}
+	lo := m.romBankLo
+	if lo&^(m.romMask<<1) == 0 {
+		lo |= 1
+	}
+	return (m.outerRomBank() | int(lo)) & (m.romBanks - 1)
+}
+
+// ramOffset returns the offset in cartridge RAM of the given address
+func (m *mmm01) ramOffset(addr uint16) int {
+	lo := m.ramBankLo
+	if m.mode == 0 || m.multiplexed {
+		lo &= m.ramMask
+	}
+	if m.multiplexed && m.mode == 1 {
+		lo = m.romBankHi
+	}
+	bank := int(m.ramBankHi<<2 | lo)
+	offset := bank*RAM_BANK_SIZE + int(addr-CARTRIDGE_RAM_START)
+	return offset % len(m.ram)
+}
+
+func (m *mmm01) read(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_00_END:
+		return readRomBank(m.rom, m.romBank0(), addr)
+	case addr <= CARTRIDGE_ROM_01_END:
+		return readRomBank(m.rom, m.romBank1(), addr)
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return 0xFF
+		}
+		return m.ram[m.ramOffset(addr)]
+	}
+	return 0xFF
+}
+
+func (m *mmm01) write(addr uint16, data byte) {
+	switch {
+	case addr <= 0x1FFF:
+		m.ramEnabled = data&0x0F == 0x0A
+		if !m.mapped {
+			m.ramMask = (data >> 4) & 0x03
+			m.mapped = data&0x40 > 0
+		}
+	case addr <= 0x3FFF:
+		locked := m.romMask << 1
+		if !m.mapped {
+			locked = 0
+			m.romBankMid = (data >> 5) & 0x03
+		}
+		m.romBankLo = m.romBankLo&locked | data&0x1F&^locked
+	case addr <= 0x5FFF:
+		locked := m.ramMask
+		if !m.mapped {
+			locked = 0
+			m.ramBankHi = (data >> 2) & 0x03
+			m.romBankHi = (data >> 4) & 0x03
+			m.modeLocked = data&0x40 > 0
+		}
+		m.ramBankLo = m.ramBankLo&locked | data&0x03&^locked
+	case addr <= CARTRIDGE_ROM_01_END:
+		if !m.modeLocked {
+			m.mode = data & 0x01
+		}
+		if !m.mapped {
+			m.romMask = (data >> 2) & 0x0F
+			m.multiplexed = data&0x40 > 0
+		}
+	case addr >= CARTRIDGE_RAM_START && addr <= CARTRIDGE_RAM_END:
+		if m.ramEnabled && len(m.ram) > 0 {
+			m.ram[m.ramOffset(addr)] = data
+		}
+	}
+}
+
+// saveData returns the contents of cartridge RAM
+func (m *mmm01) saveData() []byte {
+	return append([]byte(nil), m.ram...)
+}
+
+// loadSaveData restores cartridge RAM from the given save data
+func (m *mmm01) loadSaveData(data []byte) error {
+	return loadRAM(m.ram, data)
+}
@@ -0,0 +1,160 @@
+package gb
+
+// mmu is the Game Boy's memory management unit. It owns the console's internal
+// memory, and routes each bus access to the device mapped at the accessed
+// address.
+//
+// reference: https://gbdev.io/pandocs/Memory_Map.html
+type mmu struct {
+	cart   mbc     // Cartridge ROM/RAM, behind its memory bank controller
+	ppu    *ppu    // VRAM, OAM and LCD registers
+	dma    *dma    // OAM DMA controller
+	timer  *timer  // DIV, TIMA, TMA and TAC
+	joypad *joypad // P1
+	serial *serial // SB and SC
+	apu    *apu    // Sound registers and wave RAM
+
+	sched *scheduler // Clocks the timer, serial port and PPU, and polls the joypad
+
+	wram [INTERNAL_RAM_END - INTERNAL_RAM_START + 1]byte
+	hram [HRAM_END - HRAM_BEGIN + 1]byte
+
+	intFlag   byte // IF
+	intEnable byte // IE
+}
+
+// newMMU returns an MMU with no cartridge inserted. Devices are attached to it
+// through the GameBoy's bus.
+func newMMU() *mmu {
+	return &mmu{cart: newRomOnly(nil, 0)}
+}
+
+// read reads 1 byte from the device mapped at the given address. Reads from
+// unmapped addresses return 0xFF (open bus), and reads from addresses blocked
+// by OAM DMA return conflicting data.
+func (m *mmu) read(addr uint16) byte {
+	if m.dma.blocks(addr) {
+		return m.dma.conflict(addr)
+	}
+	return m.readBus(addr)
+}
+
+// readBus reads 1 byte from the device mapped at the given address, regardless
+// of OAM DMA
+func (m *mmu) readBus(addr uint16) byte {
+	switch {
+	case addr <= CARTRIDGE_ROM_01_END:
+		return m.cart.read(addr)
+	case addr <= VRAM_END:
+		return m.ppu.readVRAM(addr)
+	case addr <= CARTRIDGE_RAM_END:
+		return m.cart.read(addr)
+	case addr <= INTERNAL_RAM_END:
+		return m.wram[addr-INTERNAL_RAM_START]
+	case addr <= ECHO_RAM_END:
+		return m.wram[addr-ECHO_RAM_START]
+	case addr <= OAM_END:
+		return m.ppu.readOAM(addr)
+	case addr <= UNUSABLE_END:
+		return 0xFF
+	case addr <= IO_REGISTERS_END:
+		return m.readIO(addr)
+	case addr <= HRAM_END:
+		return m.hram[addr-HRAM_BEGIN]
+	default:
+		return m.intEnable
+	}
+}
+
+// write writes 1 byte of data to the device mapped at the given address.
+// Writes to the cartridge ROM are forwarded to the cartridge's mapper, and
+// writes to unmapped addresses, or addresses blocked by OAM DMA, are ignored.
+func (m *mmu) write(addr uint16, data byte) {
+	if m.dma.blocks(addr) {
+		return
+	}
+
+	switch {
+	case addr <= CARTRIDGE_ROM_01_END:
+		m.cart.write(addr, data)
+	case addr <= VRAM_END:
+		m.ppu.writeVRAM(addr, data)
+	case addr <= CARTRIDGE_RAM_END:
+		m.cart.write(addr, data)
+	case addr <= INTERNAL_RAM_END:
+		m.wram[addr-INTERNAL_RAM_START] = data
+	case addr <= ECHO_RAM_END:
+		m.wram[addr-ECHO_RAM_START] = data
+	case addr <= OAM_END:
+		m.ppu.writeOAM(addr, data)
+	case addr <= UNUSABLE_END:
+	case addr <= IO_REGISTERS_END:
+		m.writeIO(addr, data)
+	case addr <= HRAM_END:
+		m.hram[addr-HRAM_BEGIN] = data
+	default:
+		m.intEnable = data
+	}
+}
+
+// readIO reads from the hardware register at the given address
+func (m *mmu) readIO(addr uint16) byte {
+	if addr >= IO_NR10 && addr <= WAVE_RAM_END {
+		// Sound registers, the unused addresses between them, and wave RAM
+		return m.apu.read(addr)
+	}
+
+	switch addr {
+	case IO_P1:
+		m.sched.sync(EVENT_JOYPAD)
+		return m.joypad.read()
+	case IO_SB, IO_SC:
+		m.sched.sync(EVENT_SERIAL)
+		return m.serial.read(addr)
+	case IO_DIV, IO_TIMA, IO_TMA, IO_TAC:
+		m.sched.sync(EVENT_TIMER)
+		return m.timer.read(addr)
+	case IO_IF:
+		// Upper 3 bits are unused, and always read as 1
+		return m.intFlag | 0xE0
+	case IO_LCDC, IO_STAT, IO_SCY, IO_SCX, IO_LY, IO_LYC, IO_BGP, IO_OBP0,
+		IO_OBP1, IO_WY, IO_WX:
+		m.sched.sync(EVENT_PPU)
+		return m.ppu.read(addr)
+	case IO_DMA:
+		return m.dma.value
+	default:
+		// Unused registers are open bus
+		return 0xFF
+	}
+}
+
+// writeIO writes to the hardware register at the given address
+func (m *mmu) writeIO(addr uint16, data byte) {
+	if addr >= IO_NR10 && addr <= WAVE_RAM_END {
+		m.apu.write(addr, data)
+		return
+	}
+
+	switch addr {
+	case IO_P1:
+		m.joypad.write(data)
+	case IO_SB, IO_SC:
+		m.sched.sync(EVENT_SERIAL)
+		m.serial.write(addr, data)
+		m.sched.sync(EVENT_SERIAL)
+	case IO_DIV, IO_TIMA, IO_TMA, IO_TAC:
+		m.sched.sync(EVENT_TIMER)
+		m.timer.write(addr, data)
+		m.sched.sync(EVENT_TIMER)
+	case IO_IF:
+		m.intFlag = data & 0x1F
+	case IO_LCDC, IO_STAT, IO_SCY, IO_SCX, IO_LY, IO_LYC, IO_BGP, IO_OBP0,
+		IO_OBP1, IO_WY, IO_WX:
+		m.sched.sync(EVENT_PPU)
+		m.ppu.write(addr, data)
+		m.sched.sync(EVENT_PPU)
+	case IO_DMA:
+		m.dma.start(data)
+	}
+}
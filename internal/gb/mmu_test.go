@@ -0,0 +1,48 @@
+package gb
+
+import "testing"
+
+// TestMMURouting checks that bus accesses reach the memory region that owns
+// the accessed address
+func TestMMURouting(t *testing.T) {
+	gb := newTestGameBoy(t, 0x3C)
+
+	// ROM is read-only
+	gb.cpuWrite(ENTRY_POINT, 0x00)
+	if got := gb.cpuRead(ENTRY_POINT); got != 0x3C {
+		t.Errorf("ROM write modified ROM: read %#02x, want 0x3c", got)
+	}
+
+	// Echo RAM mirrors internal RAM
+	gb.cpuWrite(0xC123, 0x42)
+	if got := gb.cpuRead(0xE123); got != 0x42 {
+		t.Errorf("echo RAM read %#02x, want 0x42", got)
+	}
+	gb.cpuWrite(0xFDFF, 0x24)
+	if got := gb.cpuRead(0xDDFF); got != 0x24 {
+		t.Errorf("internal RAM read %#02x after echo RAM write, want 0x24", got)
+	}
+
+	// Unmapped reads return open bus
+	gb.cpuWrite(UNUSABLE_START, 0x00)
+	if got := gb.cpuRead(UNUSABLE_START); got != 0xFF {
+		t.Errorf("unusable memory read %#02x, want 0xff", got)
+	}
+	if got := gb.cpuRead(CARTRIDGE_RAM_START); got != 0xFF {
+		t.Errorf("missing cartridge RAM read %#02x, want 0xff", got)
+	}
+
+	// Unused registers are open bus
+	for _, addr := range []uint16{0xFF03, 0xFF08, 0xFF4C, 0xFF7F} {
+		gb.cpuWrite(addr, 0x00)
+		if got := gb.cpuRead(addr); got != 0xFF {
+			t.Errorf("unused register %#04x read %#02x, want 0xff", addr, got)
+		}
+	}
+
+	// Unused IF bits read as 1
+	gb.cpuWrite(IO_IF, 0x00)
+	if got := gb.cpuRead(IO_IF); got != 0xE0 {
+		t.Errorf("IF read %#02x, want 0xe0", got)
+	}
+}
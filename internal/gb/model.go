@@ -0,0 +1,124 @@
+package gb
+
+// Model is a Game Boy hardware model. Each model's boot ROM leaves the CPU
+// registers and some hardware registers in a different state, which games can
+// use to tell the models apart.
+//
+// Only DMG hardware is emulated: the CGB and AGB models set up the registers
+// as those consoles do, but their color features are not emulated.
+type Model int
+
+// Hardware models. MODEL_DMG is the default.
+const (
+	MODEL_DMG  Model = iota // Game Boy
+	MODEL_DMG0              // Early Japanese Game Boy, with an older boot ROM
+	MODEL_MGB               // Game Boy Pocket
+	MODEL_SGB               // Super Game Boy
+	MODEL_SGB2              // Super Game Boy 2
+	MODEL_CGB               // Game Boy Color
+	MODEL_AGB               // Game Boy Advance
+)
+
+// modelNames maps each model to its name
+var modelNames = map[Model]string{
+	MODEL_DMG:  "DMG",
+	MODEL_DMG0: "DMG0",
+	MODEL_MGB:  "MGB",
+	MODEL_SGB:  "SGB",
+	MODEL_SGB2: "SGB2",
+	MODEL_CGB:  "CGB",
+	MODEL_AGB:  "AGB",
+}
+
+// String returns the model's name
+func (m Model) String() string {
+	if name, ok := modelNames[m]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// cgb returns whether the model is a Game Boy Color, or a Game Boy Advance
+// running Game Boy Color games
+func (m Model) cgb() bool {
+	return m == MODEL_CGB || m == MODEL_AGB
+}
+
+// cpuRegisters is the state of the CPU registers after the boot ROM
+type cpuRegisters struct {
+	af, bc, de, hl uint16
+}
+
+// cpuRegisterInit maps each model to its CPU registers after the boot ROM.
+// The flags and B register which depend on the cartridge header are set by
+// 'powerUpRegisters'.
+//
+// reference: https://gbdev.io/pandocs/Power_Up_Sequence.html#cpu-registers
+var cpuRegisterInit = map[Model]cpuRegisters{
+	MODEL_DMG0: {0x0100, 0xFF13, 0x00C1, 0x8403},
+	MODEL_DMG:  {0x0180, 0x0013, 0x00D8, 0x014D},
+	MODEL_MGB:  {0xFF80, 0x0013, 0x00D8, 0x014D},
+	MODEL_SGB:  {0x0100, 0x0014, 0x0000, 0xC060},
+	MODEL_SGB2: {0xFF00, 0x0014, 0x0000, 0xC060},
+	MODEL_CGB:  {0x1180, 0x0000, 0xFF56, 0x000D},
+	MODEL_AGB:  {0x1100, 0x0100, 0xFF56, 0x000D},
+}
+
+// cgbCompatibilityRegisters are the CPU registers after the CGB and AGB boot
+// ROMs start a cartridge without CGB support, in compatibility mode
+var cgbCompatibilityRegisters = cpuRegisters{0x1180, 0x0000, 0x0008, 0x007C}
+
+// powerUpRegisters returns the CPU registers the given model's boot ROM
+// leaves before starting the given cartridge, which may be nil
+func powerUpRegisters(model Model, cart *Cartridge) cpuRegisters {
+	regs, ok := cpuRegisterInit[model]
+	if !ok {
+		regs = cpuRegisterInit[MODEL_DMG]
+	}
+	if cart == nil {
+		return regs
+	}
+
+	switch {
+	case model == MODEL_DMG || model == MODEL_MGB:
+		// The header checksum is left in A when the boot ROM checks it,
+		// with H and C from the subtraction it ends with
+		if cart.Header.HeaderChecksum != 0x00 {
+			regs.af |= uint16(FLAG_H | FLAG_C)
+		}
+	case model.cgb() && cart.Header.CGBFlag&0x80 == 0:
+		// Compatibility mode: B holds the title checksum used to pick a
+		// palette for Nintendo's games
+		compat := cgbCompatibilityRegisters
+		compat.bc |= uint16(titleChecksum(cart)) << 8
+		if model == MODEL_AGB {
+			// The AGB boot ROM ends with INC B
+			b := byte(compat.bc>>8) + 1
+			compat.bc = uint16(b)<<8 | compat.bc&0xFF
+			compat.af &^= 0xFF
+			if b == 0 {
+				compat.af |= uint16(FLAG_Z)
+			}
+			if b&0x0F == 0 {
+				compat.af |= uint16(FLAG_H)
+			}
+		}
+		regs = compat
+	}
+	return regs
+}
+
+// titleChecksum returns the sum of the title bytes of the given cartridge's
+// header if it was published by Nintendo, and 0 otherwise
+func titleChecksum(cart *Cartridge) byte {
+	h := cart.Header
+	if h.OldLicenseeCode != 0x01 && !(h.OldLicenseeCode == 0x33 && h.NewLicenseeCode == "01") {
+		return 0
+	}
+
+	var sum byte
+	for _, b := range cart.rom[HEADER_TITLE_START : HEADER_TITLE_END+1] {
+		sum += b
+	}
+	return sum
+}
@@ -0,0 +1,76 @@
+package gb
+
+import "testing"
+
+// TestPowerUpRegisters checks the CPU registers left by each model's boot ROM,
+// including the flags and B register depending on the cartridge header
+func TestPowerUpRegisters(t *testing.T) {
+	// A Nintendo cartridge without CGB support, titled "AB"
+	rom := newTestRom()
+	copy(rom[HEADER_TITLE_START:], "AB")
+	rom[HEADER_OLD_LICENSEE] = 0x01
+	rom[HEADER_CHECKSUM] = headerChecksum(rom)
+
+	cgbRom := newTestRom()
+	cgbRom[HEADER_CGB_FLAG] = 0x80
+	cgbRom[HEADER_CHECKSUM] = headerChecksum(cgbRom)
+
+	for _, c := range []struct {
+		model          Model
+		rom            []byte
+		af, bc, de, hl uint16
+	}{
+		{MODEL_DMG0, rom, 0x0100, 0xFF13, 0x00C1, 0x8403},
+		{MODEL_DMG, rom, 0x01B0, 0x0013, 0x00D8, 0x014D},
+		{MODEL_MGB, rom, 0xFFB0, 0x0013, 0x00D8, 0x014D},
+		{MODEL_SGB, rom, 0x0100, 0x0014, 0x0000, 0xC060},
+		{MODEL_SGB2, rom, 0xFF00, 0x0014, 0x0000, 0xC060},
+		{MODEL_CGB, rom, 0x1180, 0x8300, 0x0008, 0x007C}, // 'A'+'B' = 0x83
+		{MODEL_AGB, rom, 0x1100, 0x8400, 0x0008, 0x007C},
+		{MODEL_CGB, cgbRom, 0x1180, 0x0000, 0xFF56, 0x000D},
+		{MODEL_AGB, cgbRom, 0x1100, 0x0100, 0xFF56, 0x000D},
+	} {
+		gb := newTestGameBoyFromRom(t, c.rom, WithModel(c.model))
+		cpu := gb.Cpu
+		if cpu.AF.get() != c.af || cpu.BC.get() != c.bc || cpu.DE.get() != c.de || cpu.HL.get() != c.hl {
+			t.Errorf("%s (CGB flag %#02x): AF=%04X BC=%04X DE=%04X HL=%04X, want AF=%04X BC=%04X DE=%04X HL=%04X",
+				c.model, c.rom[HEADER_CGB_FLAG], cpu.AF.get(), cpu.BC.get(), cpu.DE.get(), cpu.HL.get(),
+				c.af, c.bc, c.de, c.hl)
+		}
+	}
+
+	// H and C are clear when the header checksum is 0
+	for v := 0; v < 0x100; v++ {
+		rom[HEADER_VERSION] = byte(v)
+		if headerChecksum(rom) == 0x00 {
+			break
+		}
+	}
+	rom[HEADER_CHECKSUM] = 0x00
+	gb := newTestGameBoyFromRom(t, rom)
+	if got := gb.Cpu.AF.getLo(); got != byte(FLAG_Z) {
+		t.Errorf("DMG with header checksum 0: F = %#02x, want %#02x", got, byte(FLAG_Z))
+	}
+}
+
+// TestPowerUpHardwareRegisters checks the hardware registers which differ
+// between models
+func TestPowerUpHardwareRegisters(t *testing.T) {
+	for _, c := range []struct {
+		model Model
+		addr  uint16
+		want  byte
+	}{
+		{MODEL_DMG, IO_DIV, 0xAB},
+		{MODEL_DMG0, IO_DIV, 0x18},
+		{MODEL_DMG, IO_NR52, 0xF1},
+		{MODEL_SGB, IO_NR52, 0xF0},
+		{MODEL_DMG, IO_SC, 0x7E},
+		{MODEL_CGB, IO_SC, 0x7F},
+	} {
+		gb := newTestGameBoyFromRom(t, newTestRom(), WithModel(c.model))
+		if got := gb.mmu.read(c.addr); got != c.want {
+			t.Errorf("%s: register %#04x = %#02x, want %#02x", c.model, c.addr, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package gb
+
+// Background fetcher steps
+const (
+	FETCH_TILE    = iota // Read the tile number from the tile map
+	FETCH_DATA_LO        // Read the low bitplane of the tile row
+	FETCH_DATA_HI        // Read the high bitplane of the tile row
+	FETCH_PUSH           // Push the row to the BG FIFO, once it is empty
+)
+
+const (
+	FETCH_STEP_DOTS   = 2 // dots taken by each fetcher step before pushing
+	FETCH_START_DOTS  = 6 // dots taken by the discarded first fetch of each line
+	SPRITE_FETCH_DOTS = 6 // dots taken to fetch an object's tile row
+)
+
+// objPixel is a pixel in the object FIFO
+type objPixel struct {
+	color byte // 0 is transparent
+	flags byte // attribute flags of the object it came from
+}
+
+// pixelFIFO is the fetcher/pixel FIFO renderer. Rather than drawing each line
+// at once, it steps through mode 3 one dot at a time: a fetcher reads BG and
+// window tiles into a FIFO, which shifts out one pixel per dot, pausing for
+// fine scrolling, window restarts and object fetches. Mode 3 ends once the
+// last pixel of the line is shifted out, so its length varies as it does on
+// hardware, and register writes made during mode 3 take effect mid-line.
+//
+// reference: https://gbdev.io/pandocs/pixel_fifo.html
+type pixelFIFO struct {
+	p *ppu
+
+	dots int // dots elapsed in mode 3
+
+	bg    [8]byte // BG/window color indexes
+	bgPos int     // position of the next pixel in 'bg'
+	bgLen int     // number of pixels in 'bg'
+
+	obj [8]objPixel // object pixels, aligned with the next 8 screen pixels
+
+	lx      int // screen X of the next pixel
+	discard int // pixels left to discard for SCX fine scrolling
+
+	// Background fetcher
+	wait        int    // dots left before the fetcher starts
+	fetcherStep int    // see FETCH_* steps
+	stepDots    int    // dots spent in the current step
+	fetchX      int    // tile column being fetched
+	mapAddr     uint16 // tile map address being fetched
+	row         int    // tile row being fetched
+	lo, hi      byte   // fetched bitplanes
+	window      bool   // set once the fetcher has switched to the window
+
+	// Object fetching
+	fetched    [MAX_SPRITES_PER_LINE]bool // objects on this line already fetched
+	sprite     int                        // index in ppu.sprites being fetched, or -1
+	spriteDots int                        // dots spent fetching 'sprite'
+	waitedTile int                        // last tile an object fetch waited for, or -1
+}
+
+// newPixelFIFO returns a pixel FIFO renderer for the given PPU
+func newPixelFIFO(p *ppu) *pixelFIFO {
+	return &pixelFIFO{p: p, sprite: -1, waitedTile: -1}
+}
+
+// start resets the renderer for the start of mode 3
+func (f *pixelFIFO) start() {
+	*f = pixelFIFO{
+		p:          f.p,
+		discard:    int(f.p.scx & 0x07),
+		wait:       FETCH_START_DOTS,
+		sprite:     -1,
+		waitedTile: -1,
+	}
+}
+
+// done returns whether the whole line has been drawn
+func (f *pixelFIFO) done() bool {
+	return f.lx >= SCREEN_WIDTH
+}
+
+// step advances the renderer by one dot
+func (f *pixelFIFO) step() {
+	f.dots++
+	if f.wait > 0 {
+		f.wait--
+		return
+	}
+
+	f.checkWindow()
+
+	// An object fetch stalls both the fetcher and the FIFOs
+	if f.sprite < 0 {
+		if f.sprite = f.nextSprite(); f.sprite >= 0 {
+			f.spriteDots = -f.spriteWait()
+		}
+	}
+	if f.sprite >= 0 {
+		f.spriteDots++
+		if f.spriteDots == SPRITE_FETCH_DOTS {
+			f.mergeSprite()
+		}
+		return
+	}
+
+	f.fetch()
+	f.shift()
+}
+
+// nextSprite returns the index in ppu.sprites of an object starting at the
+// current screen X which has not been fetched, or -1 if there is none
+func (f *pixelFIFO) nextSprite() int {
+	p := f.p
+	if p.lcdc&LCDC_OBJ_ENABLE == 0 {
+		return -1
+	}
+	for i, s := range p.sprites {
+		x := int(s.x) - 8
+		if x < 0 {
+			x = 0
+		}
+		if !f.fetched[i] && s.x < SCREEN_WIDTH+8 && x == f.lx {
+			return i
+		}
+	}
+	return -1
+}
+
+// spriteWait returns the number of dots an object fetch waits for the BG
+// fetcher to finish fetching the tile under the object. Only the first object
+// fetched over each tile waits.
+func (f *pixelFIFO) spriteWait() int {
+	x := f.lx + int(f.p.scx)
+	if f.window {
+		x = f.lx - (int(f.p.wx) - 7)
+	}
+
+	if x/8 == f.waitedTile {
+		return 0
+	}
+	f.waitedTile = x / 8
+	if wait := 5 - x%8; wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// mergeSprite merges the fetched object's pixels into the object FIFO. Pixels
+// already holding an opaque object pixel are kept, so objects fetched earlier
+// (at a smaller X, or earlier in OAM) are drawn on top.
+func (f *pixelFIFO) mergeSprite() {
+	s := f.p.sprites[f.sprite]
+	lo, hi := f.p.spriteRow(s)
+
+	for col := 0; col < 8; col++ {
+		slot := int(s.x) - 8 + col - f.lx
+		if slot < 0 {
+			continue
+		}
+		px := col
+		if s.flags&OBJ_X_FLIP > 0 {
+			px = 7 - col
+		}
+		if color := tilePixel(lo, hi, px); color > 0 && f.obj[slot].color == 0 {
+			f.obj[slot] = objPixel{color, s.flags}
+		}
+	}
+
+	f.fetched[f.sprite] = true
+	f.sprite = -1
+	f.spriteDots = 0
+}
+
+// checkWindow restarts the fetcher on the window once the window's left edge
+// is reached, emptying the BG FIFO
+func (f *pixelFIFO) checkWindow() {
+	p := f.p
+	if f.window || p.lcdc&LCDC_WINDOW_ENABLE == 0 || !p.windowTriggered || p.wx > 166 {
+		return
+	}
+	if f.lx < int(p.wx)-7 {
+		return
+	}
+
+	f.window = true
+	f.bgLen = 0
+	f.fetcherStep, f.stepDots, f.fetchX = FETCH_TILE, 0, 0
+	f.waitedTile = -1
+	f.discard = 0
+	if p.wx < 7 {
+		f.discard = 7 - int(p.wx)
+	}
+}
+
+// fetch advances the background fetcher by one dot
+func (f *pixelFIFO) fetch() {
+	p := f.p
+
+	if f.fetcherStep == FETCH_PUSH {
+		if f.bgLen > 0 {
+			return
+		}
+		for x := range f.bg {
+			f.bg[x] = 0
+			// On DMG, clearing LCDC bit 0 blanks both the background and
+			// window
+			if p.lcdc&LCDC_BG_ENABLE > 0 {
+				f.bg[x] = tilePixel(f.lo, f.hi, x)
+			}
+		}
+		f.bgPos, f.bgLen = 0, 8
+		f.fetchX++
+		f.fetcherStep = FETCH_TILE
+		return
+	}
+
+	f.stepDots++
+	if f.stepDots < FETCH_STEP_DOTS {
+		return
+	}
+	f.stepDots = 0
+
+	switch f.fetcherStep {
+	case FETCH_TILE:
+		if f.window {
+			f.row = p.windowLine
+			f.mapAddr = p.bgMap(LCDC_WINDOW_MAP) + uint16(f.row/8*32+f.fetchX)
+		} else {
+			y := int(p.ly+p.scy) & 0xFF
+			x := (int(p.scx)/8 + f.fetchX) & 0x1F
+			f.row = y
+			f.mapAddr = p.bgMap(LCDC_BG_MAP) + uint16(y/8*32+x)
+		}
+	case FETCH_DATA_LO:
+		f.lo, _ = p.bgTileRow(f.mapAddr, f.row%8)
+	case FETCH_DATA_HI:
+		_, f.hi = p.bgTileRow(f.mapAddr, f.row%8)
+	}
+	f.fetcherStep++
+}
+
+// shift shifts a pixel out of the FIFOs to the LCD, mixing the BG/window and
+// object pixels
+func (f *pixelFIFO) shift() {
+	if f.bgLen == 0 {
+		return
+	}
+	p := f.p
+
+	color := f.bg[f.bgPos]
+	f.bgPos++
+	f.bgLen--
+	if f.discard > 0 {
+		f.discard--
+		return
+	}
+
+	obj := f.obj[0]
+	copy(f.obj[:], f.obj[1:])
+	f.obj[7] = objPixel{}
+
+	px := shade(p.bgp, color)
+	if obj.color > 0 && p.lcdc&LCDC_OBJ_ENABLE > 0 &&
+		(obj.flags&OBJ_BG_PRIORITY == 0 || color == 0) {
+		palette := p.obp0
+		if obj.flags&OBJ_PALETTE > 0 {
+			palette = p.obp1
+		}
+		px = shade(palette, obj.color)
+	}
+
+	p.frame[p.ly][f.lx] = px
+	f.lx++
+}
@@ -0,0 +1,326 @@
+package gb
+
+// Screen dimensions, in pixels
+const (
+	SCREEN_WIDTH  = 160
+	SCREEN_HEIGHT = 144
+)
+
+// PPU timing, in dots (T-cycles)
+const (
+	DOTS_PER_LINE   = 456
+	LINES_PER_FRAME = 154
+	DOTS_PER_FRAME  = DOTS_PER_LINE * LINES_PER_FRAME
+
+	OAM_SCAN_DOTS = 80
+	DRAWING_DOTS  = 172 // minimum mode 3 length
+)
+
+// PPU modes, found in STAT bits 0-1
+const (
+	MODE_HBLANK   = 0
+	MODE_VBLANK   = 1
+	MODE_OAM_SCAN = 2
+	MODE_DRAWING  = 3
+)
+
+// LCDC bits
+const (
+	LCDC_BG_ENABLE     = 1 << 0 // BG and window enable
+	LCDC_OBJ_ENABLE    = 1 << 1
+	LCDC_OBJ_SIZE      = 1 << 2 // 0: 8x8, 1: 8x16
+	LCDC_BG_MAP        = 1 << 3 // 0: 0x9800, 1: 0x9C00
+	LCDC_TILE_DATA     = 1 << 4 // 0: 0x8800 (signed), 1: 0x8000 (unsigned)
+	LCDC_WINDOW_ENABLE = 1 << 5
+	LCDC_WINDOW_MAP    = 1 << 6 // 0: 0x9800, 1: 0x9C00
+	LCDC_LCD_ENABLE    = 1 << 7
+)
+
+// STAT bits
+const (
+	STAT_LYC_EQUAL  = 1 << 2 // LYC == LY, read-only
+	STAT_HBLANK_INT = 1 << 3 // Mode 0 interrupt select
+	STAT_VBLANK_INT = 1 << 4 // Mode 1 interrupt select
+	STAT_OAM_INT    = 1 << 5 // Mode 2 interrupt select
+	STAT_LYC_INT    = 1 << 6 // LYC == LY interrupt select
+)
+
+// Frame is a complete LCD image. Each pixel is a 2-bit shade, from 0 (white)
+// to 3 (black).
+type Frame [SCREEN_HEIGHT][SCREEN_WIDTH]byte
+
+// ppu is the Game Boy's pixel processing unit. It owns video RAM and OAM,
+// steps through its modes once per scanline, and draws each line into the
+// frame once the line's drawing mode ends.
+//
+// reference: https://gbdev.io/pandocs/Rendering.html
+type ppu struct {
+	vram [VRAM_END - VRAM_START + 1]byte
+	oam  [OAM_END - OAM_START + 1]byte
+
+	lcdc byte // LCD control
+	stat byte // LCD status, interrupt select bits only
+	scy  byte // Background viewport Y
+	scx  byte // Background viewport X
+	ly   byte // Current line
+	lyc  byte // Line compare
+	bgp  byte // Background palette
+	obp0 byte // Object palette 0
+	obp1 byte // Object palette 1
+	wy   byte // Window Y
+	wx   byte // Window X + 7
+
+	mode byte // current mode, see MODE_* constants
+	dot  int  // dots elapsed in the current line
+
+	statLine bool // STAT interrupt line, requesting on rising edges
+
+	sprites         []sprite // objects on the current line, found by OAM scan
+	windowTriggered bool     // set once LY has matched WY this frame
+	windowLine      int      // window's internal line counter
+
+	fifo *pixelFIFO // pixel FIFO renderer, or nil to draw whole lines at once
+
+	frame  Frame // frame being drawn
+	output Frame // last completed frame
+	frames int   // number of frames completed
+
+	requestInterrupt func(interrupt)
+}
+
+// newPPU returns a PPU requesting interrupts through the given function
+func newPPU(requestInterrupt func(interrupt)) *ppu {
+	return &ppu{
+		mode:             MODE_OAM_SCAN,
+		sprites:          make([]sprite, 0, MAX_SPRITES_PER_LINE),
+		requestInterrupt: requestInterrupt,
+	}
+}
+
+// enabled returns whether the LCD and PPU are on
+func (p *ppu) enabled() bool {
+	return p.lcdc&LCDC_LCD_ENABLE > 0
+}
+
+// tick advances the PPU by the given number of dots
+func (p *ppu) tick(dots int) {
+	if !p.enabled() {
+		return
+	}
+
+	p.dot += dots
+	for {
+		switch p.mode {
+		case MODE_OAM_SCAN:
+			if p.dot < OAM_SCAN_DOTS {
+				return
+			}
+			p.scanOAM()
+			if p.ly == p.wy {
+				p.windowTriggered = true
+			}
+			if p.fifo != nil {
+				p.fifo.start()
+			}
+			p.mode = MODE_DRAWING
+		case MODE_DRAWING:
+			if p.fifo != nil {
+				for !p.fifo.done() && OAM_SCAN_DOTS+p.fifo.dots < p.dot {
+					p.fifo.step()
+				}
+				if !p.fifo.done() {
+					return
+				}
+				if p.fifo.window {
+					p.windowLine++
+				}
+			} else {
+				if p.dot < OAM_SCAN_DOTS+DRAWING_DOTS {
+					return
+				}
+				p.drawLine()
+			}
+			p.mode = MODE_HBLANK
+		case MODE_HBLANK:
+			if p.dot < DOTS_PER_LINE {
+				return
+			}
+			p.dot -= DOTS_PER_LINE
+			p.ly++
+			if p.ly == SCREEN_HEIGHT {
+				p.mode = MODE_VBLANK
+				p.output = p.frame
+				p.frames++
+				p.requestInterrupt(INT_VBLANK)
+			} else {
+				p.mode = MODE_OAM_SCAN
+			}
+		case MODE_VBLANK:
+			if p.dot < DOTS_PER_LINE {
+				return
+			}
+			p.dot -= DOTS_PER_LINE
+			p.ly++
+			if p.ly == LINES_PER_FRAME {
+				p.ly = 0
+				p.mode = MODE_OAM_SCAN
+				p.windowTriggered = false
+				p.windowLine = 0
+			}
+		}
+		p.updateStatLine()
+	}
+}
+
+// next returns the number of dots until the PPU next changes modes. Mode 3
+// lasts at least DRAWING_DOTS, but with the pixel FIFO its end depends on the
+// pixels drawn, so it is checked for on every dot after that.
+func (p *ppu) next() (int, bool) {
+	if !p.enabled() {
+		return 0, false
+	}
+
+	switch p.mode {
+	case MODE_OAM_SCAN:
+		return OAM_SCAN_DOTS - p.dot, true
+	case MODE_DRAWING:
+		if end := OAM_SCAN_DOTS + DRAWING_DOTS; p.dot < end {
+			return end - p.dot, true
+		}
+		return 1, true
+	default:
+		return DOTS_PER_LINE - p.dot, true
+	}
+}
+
+// updateStatLine updates the STAT interrupt line from the current mode and
+// LY, requesting a STAT interrupt when it goes from low to high. As the line
+// is shared by all STAT sources, a source becoming active while another is
+// already active does not request a new interrupt ("STAT blocking").
+func (p *ppu) updateStatLine() {
+	line := p.enabled() && (p.stat&STAT_LYC_INT > 0 && p.ly == p.lyc ||
+		p.stat&STAT_HBLANK_INT > 0 && p.mode == MODE_HBLANK ||
+		p.stat&STAT_VBLANK_INT > 0 && p.mode == MODE_VBLANK ||
+		p.stat&STAT_OAM_INT > 0 && p.mode == MODE_OAM_SCAN)
+
+	if line && !p.statLine {
+		p.requestInterrupt(INT_LCD_STAT)
+	}
+	p.statLine = line
+}
+
+// readVRAM reads from video RAM, which is inaccessible to the CPU while the
+// PPU is drawing
+func (p *ppu) readVRAM(addr uint16) byte {
+	if p.enabled() && p.mode == MODE_DRAWING {
+		return 0xFF
+	}
+	return p.vram[addr-VRAM_START]
+}
+
+// writeVRAM writes to video RAM, unless the PPU is drawing
+func (p *ppu) writeVRAM(addr uint16, data byte) {
+	if p.enabled() && p.mode == MODE_DRAWING {
+		return
+	}
+	p.vram[addr-VRAM_START] = data
+}
+
+// oamBlocked returns whether OAM is inaccessible to the CPU, during OAM scan
+// and drawing
+func (p *ppu) oamBlocked() bool {
+	return p.enabled() && (p.mode == MODE_OAM_SCAN || p.mode == MODE_DRAWING)
+}
+
+// readOAM reads from object attribute memory
+func (p *ppu) readOAM(addr uint16) byte {
+	if p.oamBlocked() {
+		return 0xFF
+	}
+	return p.oam[addr-OAM_START]
+}
+
+// writeOAM writes to object attribute memory
+func (p *ppu) writeOAM(addr uint16, data byte) {
+	if p.oamBlocked() {
+		return
+	}
+	p.oam[addr-OAM_START] = data
+}
+
+// read reads from the LCD register at the given address
+func (p *ppu) read(addr uint16) byte {
+	switch addr {
+	case IO_LCDC:
+		return p.lcdc
+	case IO_STAT:
+		stat := 0x80 | p.stat
+		if p.enabled() {
+			stat |= p.mode
+			if p.ly == p.lyc {
+				stat |= STAT_LYC_EQUAL
+			}
+		}
+		return stat
+	case IO_SCY:
+		return p.scy
+	case IO_SCX:
+		return p.scx
+	case IO_LY:
+		return p.ly
+	case IO_LYC:
+		return p.lyc
+	case IO_BGP:
+		return p.bgp
+	case IO_OBP0:
+		return p.obp0
+	case IO_OBP1:
+		return p.obp1
+	case IO_WY:
+		return p.wy
+	case IO_WX:
+		return p.wx
+	}
+	return 0xFF
+}
+
+// write writes to the LCD register at the given address
+func (p *ppu) write(addr uint16, data byte) {
+	switch addr {
+	case IO_LCDC:
+		wasEnabled := p.enabled()
+		p.lcdc = data
+		switch {
+		case wasEnabled && !p.enabled():
+			// The LCD stays blank while off
+			p.ly, p.dot, p.mode = 0, 0, MODE_HBLANK
+			p.frame = Frame{}
+			p.output = Frame{}
+		case !wasEnabled && p.enabled():
+			p.ly, p.dot, p.mode = 0, 0, MODE_OAM_SCAN
+			p.windowTriggered = false
+			p.windowLine = 0
+		}
+	case IO_STAT:
+		p.stat = data & 0x78
+	case IO_SCY:
+		p.scy = data
+	case IO_SCX:
+		p.scx = data
+	case IO_LY:
+		// Read-only
+	case IO_LYC:
+		p.lyc = data
+	case IO_BGP:
+		p.bgp = data
+	case IO_OBP0:
+		p.obp0 = data
+	case IO_OBP1:
+		p.obp1 = data
+	case IO_WY:
+		p.wy = data
+	case IO_WX:
+		p.wx = data
+	}
+	p.updateStatLine()
+}
@@ -0,0 +1,356 @@
+package gb
+
+import "testing"
+
+// newTestPPU returns an enabled PPU, and the interrupts it has requested
+func newTestPPU() (*ppu, *[]interrupt) {
+	var requested []interrupt
+	p := newPPU(func(i interrupt) {
+		requested = append(requested, i)
+	})
+	p.write(IO_LCDC, LCDC_LCD_ENABLE|LCDC_BG_ENABLE)
+	return p, &requested
+}
+
+// TestPPUTiming checks the PPU mode sequence within a line, and across a frame
+func TestPPUTiming(t *testing.T) {
+	p, requested := newTestPPU()
+
+	modes := []struct {
+		dot  int
+		mode byte
+	}{
+		{0, MODE_OAM_SCAN},
+		{OAM_SCAN_DOTS - 1, MODE_OAM_SCAN},
+		{OAM_SCAN_DOTS, MODE_DRAWING},
+		{OAM_SCAN_DOTS + DRAWING_DOTS - 1, MODE_DRAWING},
+		{OAM_SCAN_DOTS + DRAWING_DOTS, MODE_HBLANK},
+		{DOTS_PER_LINE - 1, MODE_HBLANK},
+	}
+	dot := 0
+	for _, m := range modes {
+		p.tick(m.dot - dot)
+		dot = m.dot
+		if got := p.read(IO_STAT) & 0x03; got != m.mode {
+			t.Errorf("dot %d: mode %d, want %d", m.dot, got, m.mode)
+		}
+	}
+
+	p.tick(1)
+	if p.ly != 1 {
+		t.Errorf("LY = %d after 1 line, want 1", p.ly)
+	}
+
+	p.tick(DOTS_PER_LINE * (SCREEN_HEIGHT - 1))
+	if p.ly != SCREEN_HEIGHT || p.read(IO_STAT)&0x03 != MODE_VBLANK {
+		t.Errorf("LY = %d, mode %d at VBlank, want 144, mode 1", p.ly, p.read(IO_STAT)&0x03)
+	}
+	if len(*requested) != 1 || (*requested)[0] != INT_VBLANK {
+		t.Errorf("interrupts at VBlank = %v, want [VBlank]", *requested)
+	}
+	if p.frames != 1 {
+		t.Errorf("frames = %d, want 1", p.frames)
+	}
+
+	p.tick(DOTS_PER_LINE * (LINES_PER_FRAME - SCREEN_HEIGHT))
+	if p.ly != 0 || p.read(IO_STAT)&0x03 != MODE_OAM_SCAN {
+		t.Errorf("LY = %d, mode %d after 1 frame, want 0, mode 2", p.ly, p.read(IO_STAT)&0x03)
+	}
+}
+
+// TestPPUStatInterrupts checks that STAT interrupts are requested on the
+// rising edge of the shared STAT line
+func TestPPUStatInterrupts(t *testing.T) {
+	p, requested := newTestPPU()
+
+	// LYC=LY coincidence
+	p.write(IO_LYC, 2)
+	p.write(IO_STAT, STAT_LYC_INT)
+	p.tick(DOTS_PER_LINE * 2)
+	if p.read(IO_STAT)&STAT_LYC_EQUAL == 0 {
+		t.Error("LYC=LY flag clear on line 2")
+	}
+	if len(*requested) != 1 || (*requested)[0] != INT_LCD_STAT {
+		t.Errorf("interrupts at LY=LYC = %v, want [STAT]", *requested)
+	}
+
+	// HBlank on the same line is blocked by the LYC source
+	p.write(IO_STAT, STAT_LYC_INT|STAT_HBLANK_INT)
+	p.tick(OAM_SCAN_DOTS + DRAWING_DOTS)
+	if len(*requested) != 1 {
+		t.Errorf("interrupts after blocked HBlank = %v, want [STAT]", *requested)
+	}
+
+	// HBlank on the next line is not
+	*requested = nil
+	p.tick(DOTS_PER_LINE)
+	if len(*requested) != 1 || (*requested)[0] != INT_LCD_STAT {
+		t.Errorf("interrupts at HBlank = %v, want [STAT]", *requested)
+	}
+}
+
+// TestPPUAccessBlocking checks that VRAM and OAM are inaccessible to the CPU
+// while the PPU is using them
+func TestPPUAccessBlocking(t *testing.T) {
+	p, _ := newTestPPU()
+
+	p.writeOAM(OAM_START, 0x42)
+	if got := p.readOAM(OAM_START); got != 0xFF {
+		t.Errorf("OAM read %#02x during OAM scan, want 0xff", got)
+	}
+
+	p.tick(OAM_SCAN_DOTS)
+	p.writeVRAM(VRAM_START, 0x42)
+	if got := p.readVRAM(VRAM_START); got != 0xFF {
+		t.Errorf("VRAM read %#02x while drawing, want 0xff", got)
+	}
+
+	p.tick(DRAWING_DOTS)
+	if got := p.readVRAM(VRAM_START); got != 0x00 {
+		t.Errorf("VRAM read %#02x in HBlank, want 0x00", got)
+	}
+	if got := p.readOAM(OAM_START); got != 0x00 {
+		t.Errorf("OAM read %#02x in HBlank, want 0x00", got)
+	}
+}
+
+// setTile sets every row of the tile at the given VRAM address to the given
+// bitplanes
+func setTile(p *ppu, addr uint16, lo, hi byte) {
+	for row := uint16(0); row < 8; row++ {
+		p.vram[addr-VRAM_START+row*2] = lo
+		p.vram[addr-VRAM_START+row*2+1] = hi
+	}
+}
+
+// setSprite sets the OAM entry with the given index
+func setSprite(p *ppu, index int, y, x, tile, flags byte) {
+	copy(p.oam[index*4:], []byte{y, x, tile, flags})
+}
+
+// drawFrame runs the PPU for a whole frame, calling 'line' before each line is
+// drawn, and returns the frame
+func drawFrame(p *ppu, line func(ly byte)) *Frame {
+	for ly := 0; ly < LINES_PER_FRAME; ly++ {
+		if line != nil {
+			line(p.ly)
+		}
+		p.tick(DOTS_PER_LINE)
+	}
+	return &p.output
+}
+
+// TestPPUBackground checks background scrolling, wrap-around and tile data
+// addressing
+func TestPPUBackground(t *testing.T) {
+	p, _ := newTestPPU()
+	p.write(IO_LCDC, LCDC_LCD_ENABLE|LCDC_BG_ENABLE|LCDC_TILE_DATA)
+	p.write(IO_BGP, 0xE4)
+	setTile(p, 0x8010, 0xFF, 0xFF) // tile 1: color 3
+	p.vram[BG_MAP_1_START-VRAM_START] = 1
+
+	frame := drawFrame(p, nil)
+	if frame[0][7] != 3 || frame[7][0] != 3 || frame[0][8] != 0 || frame[8][0] != 0 {
+		t.Errorf("unscrolled tile drawn incorrectly: %v", frame[0][:10])
+	}
+
+	// Scroll left by 4, wrapping the right edge of the map to the left of the
+	// screen
+	p.write(IO_SCX, 252)
+	p.write(IO_SCY, 1)
+	frame = drawFrame(p, nil)
+	if frame[0][3] != 0 || frame[0][4] != 3 || frame[0][11] != 3 || frame[0][12] != 0 {
+		t.Errorf("scrolled tile drawn incorrectly: %v", frame[0][:14])
+	}
+	if frame[6][4] != 3 || frame[7][4] != 0 {
+		t.Error("vertically scrolled tile drawn incorrectly")
+	}
+
+	// Signed tile data addressing, with tile 1 at 0x9010
+	p.write(IO_LCDC, LCDC_LCD_ENABLE|LCDC_BG_ENABLE)
+	p.write(IO_SCX, 0)
+	p.write(IO_SCY, 0)
+	setTile(p, 0x9010, 0xFF, 0x00) // color 1
+	frame = drawFrame(p, nil)
+	if frame[0][0] != 1 {
+		t.Errorf("signed tile data pixel = %d, want 1", frame[0][0])
+	}
+
+	// Palette
+	p.write(IO_BGP, 0x1B)
+	frame = drawFrame(p, nil)
+	if frame[0][0] != 2 || frame[0][8] != 3 {
+		t.Errorf("palette shades = %d, %d, want 2, 3", frame[0][0], frame[0][8])
+	}
+}
+
+// TestPPUWindow checks window positioning, and that its line counter only
+// advances on lines where it is drawn
+func TestPPUWindow(t *testing.T) {
+	p, _ := newTestPPU()
+	p.write(IO_LCDC, LCDC_LCD_ENABLE|LCDC_BG_ENABLE|LCDC_TILE_DATA|
+		LCDC_WINDOW_ENABLE|LCDC_WINDOW_MAP)
+	p.write(IO_BGP, 0xE4)
+	setTile(p, 0x8010, 0xFF, 0xFF)
+	p.vram[BG_MAP_2_START-VRAM_START] = 1 // window map row 0 is tile 1
+
+	p.write(IO_WY, 10)
+	frame := drawFrame(p, func(ly byte) {
+		// Hide the window for lines 12-15
+		if ly == 12 {
+			p.write(IO_WX, 200)
+		} else if ly == 16 || ly == 0 {
+			p.write(IO_WX, 80+7)
+		}
+	})
+
+	if frame[9][80] != 0 || frame[10][80] != 3 || frame[10][79] != 0 || frame[10][87] != 3 {
+		t.Error("window drawn at the wrong position")
+	}
+	if frame[12][80] != 0 {
+		t.Error("hidden window drawn")
+	}
+	if frame[21][80] != 3 || frame[22][80] != 0 {
+		t.Errorf("window lines 21, 22 = %d, %d, want 3, 0", frame[21][80], frame[22][80])
+	}
+}
+
+// TestPPUSprites checks object flipping, palettes, priority and the per-line
+// object limit
+func TestPPUSprites(t *testing.T) {
+	p, _ := newTestPPU()
+	p.write(IO_LCDC, LCDC_LCD_ENABLE|LCDC_BG_ENABLE|LCDC_TILE_DATA|LCDC_OBJ_ENABLE)
+	p.write(IO_BGP, 0xE4)
+	p.write(IO_OBP0, 0xE4)
+	p.write(IO_OBP1, 0x54)
+	setTile(p, 0x8010, 0xFF, 0xFF) // tile 1: color 3
+	setTile(p, 0x8020, 0x80, 0x00) // tile 2: color 1 in the leftmost column
+	p.vram[BG_MAP_1_START-VRAM_START] = 1
+
+	setSprite(p, 0, 16+20, 8+0, 2, 0)
+	setSprite(p, 1, 16+20, 8+20, 2, OBJ_X_FLIP)
+	setSprite(p, 2, 16+30, 8+50, 1, OBJ_PALETTE)
+	setSprite(p, 3, 16+30, 8+49, 1, 0)
+	setSprite(p, 4, 16+0, 8+0, 1, OBJ_PALETTE|OBJ_BG_PRIORITY)
+	setSprite(p, 5, 16+0, 8+8, 1, OBJ_PALETTE|OBJ_BG_PRIORITY)
+	for i := 0; i < 11; i++ {
+		setSprite(p, 6+i, 16+40, byte(8+i*10), 1, 0)
+	}
+
+	frame := drawFrame(p, nil)
+	if frame[20][0] != 1 || frame[20][1] != 0 {
+		t.Error("object drawn incorrectly")
+	}
+	if frame[20][20] != 0 || frame[20][27] != 1 {
+		t.Error("X flipped object drawn incorrectly")
+	}
+	if frame[30][49] != 3 || frame[30][56] != 3 || frame[30][57] != 1 {
+		t.Error("overlapping objects drawn in the wrong order")
+	}
+	if frame[0][0] != 3 || frame[0][8] != 1 {
+		t.Error("BG priority objects drawn incorrectly")
+	}
+	if frame[40][90] != 3 || frame[40][100] != 0 {
+		t.Error("more than 10 objects drawn on a line")
+	}
+
+	// Tile 2 is the top half of an 8x16 object, tile 3 the bottom
+	p.write(IO_LCDC, p.lcdc|LCDC_OBJ_SIZE)
+	setTile(p, 0x8030, 0x80, 0x00)
+	frame = drawFrame(p, nil)
+	if frame[35][0] != 1 || frame[36][0] != 0 {
+		t.Error("8x16 object drawn incorrectly")
+	}
+}
+
+// mode3Length runs the PPU through its next line, and returns the number of
+// dots it spent in mode 3
+func mode3Length(p *ppu) int {
+	length := 0
+	for dot := 0; dot < DOTS_PER_LINE; dot++ {
+		if p.mode == MODE_DRAWING {
+			length++
+		}
+		p.tick(1)
+	}
+	return length
+}
+
+// TestPixelFIFOTiming checks that the pixel FIFO renderer's mode 3 is extended
+// by fine scrolling, the window and object fetches
+func TestPixelFIFOTiming(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(p *ppu)
+		want  int
+	}{
+		{"plain", func(p *ppu) {}, 172},
+		{"SCX=3", func(p *ppu) { p.write(IO_SCX, 3) }, 175},
+		{"window", func(p *ppu) {
+			p.write(IO_LCDC, p.lcdc|LCDC_WINDOW_ENABLE)
+			p.write(IO_WX, 80+7)
+		}, 178},
+		{"object on tile", func(p *ppu) {
+			setSprite(p, 0, 16, 8+80, 0, 0)
+		}, 183},
+		{"object within tile", func(p *ppu) {
+			setSprite(p, 0, 16, 8+83, 0, 0)
+		}, 180},
+		{"objects on same tile", func(p *ppu) {
+			setSprite(p, 0, 16, 8+80, 0, 0)
+			setSprite(p, 1, 16, 8+84, 0, 0)
+		}, 189},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPPU()
+			p.fifo = newPixelFIFO(p)
+			p.write(IO_LCDC, p.lcdc|LCDC_OBJ_ENABLE)
+			tt.setup(p)
+
+			if got := mode3Length(p); got != tt.want {
+				t.Errorf("mode 3 length = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPixelFIFOFrame checks that the pixel FIFO renderer draws the same frame
+// as the scanline renderer
+func TestPixelFIFOFrame(t *testing.T) {
+	var frames [2]Frame
+	for i := range frames {
+		p, _ := newTestPPU()
+		if i == 1 {
+			p.fifo = newPixelFIFO(p)
+		}
+
+		p.write(IO_LCDC, LCDC_LCD_ENABLE|LCDC_BG_ENABLE|LCDC_TILE_DATA|LCDC_OBJ_ENABLE|
+			LCDC_WINDOW_ENABLE|LCDC_WINDOW_MAP)
+		p.write(IO_BGP, 0xE4)
+		p.write(IO_OBP0, 0xE4)
+		p.write(IO_OBP1, 0x1B)
+		p.write(IO_SCX, 13)
+		p.write(IO_SCY, 200)
+		p.write(IO_WX, 100)
+		p.write(IO_WY, 60)
+		for tile := uint16(0); tile < 4; tile++ {
+			setTile(p, 0x8000+tile*16, byte(0x3C<<tile), byte(0xA5>>tile))
+		}
+		for i := range p.vram[BG_MAP_1_START-VRAM_START:] {
+			p.vram[BG_MAP_1_START-VRAM_START+i] = byte(i*7) & 0x03
+		}
+		for i := 0; i < 40; i++ {
+			setSprite(p, i, byte(i*4), byte(i*5), byte(i&3), byte(i*0x10)&0xF0)
+		}
+
+		frames[i] = *drawFrame(p, nil)
+	}
+
+	for y := range frames[0] {
+		if frames[0][y] != frames[1][y] {
+			t.Fatalf("line %d differs:\nscanline: %v\nFIFO:     %v", y, frames[0][y], frames[1][y])
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package gb
+
+// MAX_SPRITES_PER_LINE is the number of objects the PPU can draw on one line
+const MAX_SPRITES_PER_LINE = 10
+
+// Object attribute flags, found in byte 3 of each OAM entry
+const (
+	OBJ_PALETTE     = 1 << 4 // 0: OBP0, 1: OBP1
+	OBJ_X_FLIP      = 1 << 5
+	OBJ_Y_FLIP      = 1 << 6
+	OBJ_BG_PRIORITY = 1 << 7 // BG and window colors 1-3 are drawn over the object
+)
+
+// sprite is an object found in OAM
+type sprite struct {
+	y, x  byte // screen position + 16, + 8
+	tile  byte
+	flags byte
+	index int // position in OAM
+}
+
+// shade returns the shade the given palette maps the given color index to
+func shade(palette byte, color byte) byte {
+	return (palette >> (color * 2)) & 0x03
+}
+
+// tilePixel returns the color index of the pixel at the given column in a row
+// of tile data, given as its two bitplanes
+func tilePixel(lo, hi byte, x int) byte {
+	bit := 7 - x
+	return (hi>>bit&1)<<1 | lo>>bit&1
+}
+
+// vramAt reads video RAM at the given address on behalf of the PPU
+func (p *ppu) vramAt(addr uint16) byte {
+	return p.vram[addr-VRAM_START]
+}
+
+// bgTileRow returns the bitplanes of the given row of the BG/window tile at
+// the given tile map address, using the tile data area selected by LCDC
+func (p *ppu) bgTileRow(mapAddr uint16, row int) (lo, hi byte) {
+	tile := p.vramAt(mapAddr)
+
+	var addr uint16
+	if p.lcdc&LCDC_TILE_DATA > 0 {
+		addr = CHARACTER_RAM_START + uint16(tile)*16
+	} else {
+		addr = uint16(0x9000 + int(int8(tile))*16)
+	}
+	addr += uint16(row * 2)
+	return p.vramAt(addr), p.vramAt(addr + 1)
+}
+
+// bgMap returns the start address of the BG tile map selected by the given
+// LCDC bit
+func (p *ppu) bgMap(bit byte) uint16 {
+	if p.lcdc&bit > 0 {
+		return BG_MAP_2_START
+	}
+	return BG_MAP_1_START
+}
+
+// spriteHeight returns the height of objects, as selected by LCDC
+func (p *ppu) spriteHeight() int {
+	if p.lcdc&LCDC_OBJ_SIZE > 0 {
+		return 16
+	}
+	return 8
+}
+
+// scanOAM selects the objects drawn on the current line: the first 10 in OAM
+// that overlap the line
+func (p *ppu) scanOAM() {
+	p.sprites = p.sprites[:0]
+	height := p.spriteHeight()
+
+	for i := 0; i < len(p.oam) && len(p.sprites) < MAX_SPRITES_PER_LINE; i += 4 {
+		s := sprite{p.oam[i], p.oam[i+1], p.oam[i+2], p.oam[i+3], i / 4}
+		top := int(s.y) - 16
+		if int(p.ly) >= top && int(p.ly) < top+height {
+			p.sprites = append(p.sprites, s)
+		}
+	}
+}
+
+// spriteRow returns the bitplanes of the row of the given object drawn on the
+// current line
+func (p *ppu) spriteRow(s sprite) (lo, hi byte) {
+	height := p.spriteHeight()
+	row := int(p.ly) - (int(s.y) - 16)
+	if s.flags&OBJ_Y_FLIP > 0 {
+		row = height - 1 - row
+	}
+
+	tile := s.tile
+	if height == 16 {
+		tile &= 0xFE
+	}
+	addr := CHARACTER_RAM_START + uint16(tile)*16 + uint16(row*2)
+	return p.vramAt(addr), p.vramAt(addr + 1)
+}
+
+// drawLine draws the current line into the frame: the background, then the
+// window over it, then objects
+func (p *ppu) drawLine() {
+	var colors [SCREEN_WIDTH]byte // BG/window color indexes
+	line := &p.frame[p.ly]
+
+	// On DMG, clearing LCDC bit 0 blanks both the background and window
+	if p.lcdc&LCDC_BG_ENABLE > 0 {
+		p.drawBackground(&colors)
+		p.drawWindow(&colors)
+	}
+	for x, color := range colors {
+		line[x] = shade(p.bgp, color)
+	}
+
+	if p.lcdc&LCDC_OBJ_ENABLE > 0 {
+		p.drawSprites(line, &colors)
+	}
+}
+
+// drawBackground draws the background's color indexes for the current line,
+// scrolled by SCX/SCY and wrapping around the 256x256 tile map
+func (p *ppu) drawBackground(colors *[SCREEN_WIDTH]byte) {
+	tileMap := p.bgMap(LCDC_BG_MAP)
+	y := int(p.ly+p.scy) & 0xFF
+
+	for x := range colors {
+		bgX := (x + int(p.scx)) & 0xFF
+		mapAddr := tileMap + uint16(y/8*32+bgX/8)
+		lo, hi := p.bgTileRow(mapAddr, y%8)
+		colors[x] = tilePixel(lo, hi, bgX%8)
+	}
+}
+
+// drawWindow draws the window's color indexes for the current line. The
+// window keeps its own line counter, which only advances on lines where the
+// window is drawn.
+func (p *ppu) drawWindow(colors *[SCREEN_WIDTH]byte) {
+	if p.lcdc&LCDC_WINDOW_ENABLE == 0 || !p.windowTriggered || p.wx > 166 {
+		return
+	}
+
+	tileMap := p.bgMap(LCDC_WINDOW_MAP)
+	y := p.windowLine
+	for x := int(p.wx) - 7; x < SCREEN_WIDTH; x++ {
+		if x < 0 {
+			continue
+		}
+		winX := x - (int(p.wx) - 7)
+		mapAddr := tileMap + uint16(y/8*32+winX/8)
+		lo, hi := p.bgTileRow(mapAddr, y%8)
+		colors[x] = tilePixel(lo, hi, winX%8)
+	}
+	p.windowLine++
+}
+
+// drawSprites draws the objects selected for the current line over the
+// background. Where objects overlap, the one with the smaller X coordinate is
+// drawn on top, then the one earlier in OAM.
+func (p *ppu) drawSprites(line *[SCREEN_WIDTH]byte, colors *[SCREEN_WIDTH]byte) {
+	var drawn [SCREEN_WIDTH]bool // pixels already covered by a higher priority object
+
+	for _, s := range p.spritesByPriority() {
+		lo, hi := p.spriteRow(s)
+		palette := p.obp0
+		if s.flags&OBJ_PALETTE > 0 {
+			palette = p.obp1
+		}
+
+		for col := 0; col < 8; col++ {
+			x := int(s.x) - 8 + col
+			if x < 0 || x >= SCREEN_WIDTH || drawn[x] {
+				continue
+			}
+
+			px := col
+			if s.flags&OBJ_X_FLIP > 0 {
+				px = 7 - col
+			}
+			color := tilePixel(lo, hi, px)
+			if color == 0 {
+				// Transparent
+				continue
+			}
+
+			drawn[x] = true
+			if s.flags&OBJ_BG_PRIORITY > 0 && colors[x] != 0 {
+				continue
+			}
+			line[x] = shade(palette, color)
+		}
+	}
+}
+
+// spritesByPriority returns the objects selected for the current line, from
+// highest to lowest drawing priority
+func (p *ppu) spritesByPriority() []sprite {
+	sorted := make([]sprite, len(p.sprites))
+	copy(sorted, p.sprites)
+
+	// Insertion sort by X, stable so that OAM order breaks ties
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && sorted[j].x < sorted[j-1].x; j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+	return sorted
+}
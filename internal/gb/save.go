@@ -0,0 +1,105 @@
+package gb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SAVE_FLUSH_INTERVAL is how often, in machine cycles, changes to
+// battery-backed cartridge RAM are written to the save file
+const SAVE_FLUSH_INTERVAL = CYCLES_PER_SECOND
+
+// clockBacked is implemented by memory bank controllers whose save data ends
+// with the state of a real-time clock. That state changes as time passes, but
+// only needs writing when the game sets the clock: otherwise, the saved state
+// advanced by the time passed since still gives the current time.
+type clockBacked interface {
+	// clockSaveSize returns the size of the clock state at the end of the
+	// save data
+	clockSaveSize() int
+
+	// clockSet returns whether the game has set the clock since the last
+	// call
+	clockSet() bool
+}
+
+// savePath returns the path of the save file for the ROM at the given path,
+// i.e. the ROM path with its extension replaced by ".sav"
+func savePath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sav"
+}
+
+// loadSave restores battery-backed cartridge RAM from the save file, if the
+// cartridge has a battery and a save file exists
+func (gb *GameBoy) loadSave() error {
+	cart, ok := gb.mmu.cart.(batteryBacked)
+	if !ok || !gb.Cartridge.Header.HasBattery() {
+		return nil
+	}
+
+	data, err := os.ReadFile(gb.savePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read save file: %w", err)
+	}
+
+	if err := cart.loadSaveData(data); err != nil {
+		return fmt.Errorf("%s: %w", gb.savePath, err)
+	}
+	gb.savedData = data
+	return nil
+}
+
+// flushSave writes battery-backed cartridge RAM to the save file, if it has
+// changed since it was last loaded or written. Real-time clock state is only
+// compared when the game has set the clock, as it changes as time passes.
+func (gb *GameBoy) flushSave() error {
+	gb.lastFlush = gb.Cpu.cycles
+
+	cart, ok := gb.mmu.cart.(batteryBacked)
+	if !ok || gb.Cartridge == nil || !gb.Cartridge.Header.HasBattery() {
+		return nil
+	}
+
+	data := cart.saveData()
+	if !saveChanged(cart, data, gb.savedData) {
+		return nil
+	}
+
+	// Write to a temporary file first, so a failed write never leaves a
+	// truncated save behind
+	tmp := gb.savePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write save file: %w", err)
+	}
+	if err := os.Rename(tmp, gb.savePath); err != nil {
+		return fmt.Errorf("could not write save file: %w", err)
+	}
+	gb.savedData = data
+	return nil
+}
+
+// saveChanged returns whether the given save data needs writing over the data
+// last loaded or written
+func saveChanged(cart batteryBacked, data, saved []byte) bool {
+	clock, ok := cart.(clockBacked)
+	if !ok {
+		return !bytes.Equal(data, saved)
+	}
+
+	// The flag is cleared whichever way the data is compared, so a clock set
+	// before this write is not written again
+	set := clock.clockSet()
+	if len(data) != len(saved) {
+		return true
+	}
+	ram := len(data) - clock.clockSaveSize()
+	return set || !bytes.Equal(data[:ram], saved[:ram])
+}
@@ -0,0 +1,163 @@
+package gb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newSaveTestRom returns a ROM of the given cartridge type with 8KB of RAM,
+// running a program that writes 0x42 to the start of cartridge RAM
+func newSaveTestRom(cartType byte) []byte {
+	rom := newTestRom(
+		0x3E, 0x0A, // LD A,0x0A
+		0xEA, 0x00, 0x00, // LD (0x0000),A
+		0x3E, 0x42, // LD A,0x42
+		0xEA, 0x00, 0xA0, // LD (0xA000),A
+	)
+	rom[HEADER_CARTRIDGE_TYPE] = cartType
+	rom[HEADER_RAM_SIZE] = 0x02 // 8KB
+	rom[HEADER_CHECKSUM] = headerChecksum(rom)
+	return rom
+}
+
+// newSaveTestGameBoy returns a powered-on GameBoy running the ROM at the
+// given path
+func newSaveTestGameBoy(t *testing.T, romPath string, opts ...Option) *GameBoy {
+	t.Helper()
+
+	gb, err := New(romPath, false, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gb.initPowerUpSequence()
+	return gb
+}
+
+// TestBatterySave checks that battery-backed RAM is written to the save file,
+// and restored when the ROM is loaded again
+func TestBatterySave(t *testing.T) {
+	tests := []struct {
+		name     string
+		cartType byte
+		saveSize int
+	}{
+		{"MBC1+RAM+BATTERY", 0x03, 8 * 1024},
+		{"MBC3+TIMER+RAM+BATTERY", 0x10, 8*1024 + RTC_SAVE_SIZE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			romPath := filepath.Join(dir, "game.gb")
+			if err := os.WriteFile(romPath, newSaveTestRom(tt.cartType), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			gb := newSaveTestGameBoy(t, romPath)
+			for i := 0; i < 4; i++ {
+				gb.Cpu.execNextInst()
+			}
+			if err := gb.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "game.sav"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != tt.saveSize {
+				t.Fatalf("save size = %d, want %d", len(data), tt.saveSize)
+			}
+			if data[0] != 0x42 {
+				t.Errorf("saved RAM[0] = %#02x, want 0x42", data[0])
+			}
+
+			gb = newSaveTestGameBoy(t, romPath)
+			gb.cpuWrite(0x0000, 0x0A)
+			if got := gb.cpuRead(0xA000); got != 0x42 {
+				t.Errorf("restored RAM[0] = %#02x, want 0x42", got)
+			}
+		})
+	}
+}
+
+// TestNoBatterySave checks that cartridges without a battery don't write save
+// files
+func TestNoBatterySave(t *testing.T) {
+	dir := t.TempDir()
+	romPath := filepath.Join(dir, "game.gb")
+	if err := os.WriteFile(romPath, newSaveTestRom(0x02), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gb := newSaveTestGameBoy(t, romPath)
+	for i := 0; i < 4; i++ {
+		gb.Cpu.execNextInst()
+	}
+	if err := gb.flushSave(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "game.sav")); !os.IsNotExist(err) {
+		t.Errorf("save file written for cartridge without battery (err = %v)", err)
+	}
+}
+
+// TestRTCSaveFlush checks that real-time clock saves are only rewritten when
+// RAM changes or the game sets the clock, not as time passes
+func TestRTCSaveFlush(t *testing.T) {
+	dir := t.TempDir()
+	romPath := filepath.Join(dir, "game.gb")
+	savePath := filepath.Join(dir, "game.sav")
+	if err := os.WriteFile(romPath, newSaveTestRom(0x10), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &testClock{now: time.Unix(1_000_000, 0)}
+	gb := newSaveTestGameBoy(t, romPath, WithClock(clock))
+	for i := 0; i < 4; i++ {
+		gb.Cpu.execNextInst()
+	}
+
+	// The clock is set before the first save is written
+	gb.cpuWrite(0x4000, RTC_M)
+	gb.cpuWrite(0xA000, 0x05)
+	if err := gb.flushSave(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(savePath); err != nil {
+		t.Fatal("first save not written")
+	}
+
+	written := func() bool {
+		t.Helper()
+		os.Remove(savePath)
+		if err := gb.flushSave(); err != nil {
+			t.Fatal(err)
+		}
+		_, err := os.Stat(savePath)
+		return err == nil
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if written() {
+		t.Error("save rewritten as time passed")
+	}
+
+	gb.cpuWrite(0x4000, RTC_M)
+	gb.cpuWrite(0xA000, 0x10)
+	if !written() {
+		t.Error("save not rewritten after setting the clock")
+	}
+
+	gb.cpuWrite(0x4000, 0x00)
+	gb.cpuWrite(0xA001, 0x24)
+	if !written() {
+		t.Error("save not rewritten after writing RAM")
+	}
+	if written() {
+		t.Error("save rewritten without changes")
+	}
+}
@@ -0,0 +1,87 @@
+package gb
+
+// Scheduled devices, in the order their events fire when due at the same time
+const (
+	EVENT_TIMER  = iota // TIMA reload and timer interrupt, or APU frame sequencer step
+	EVENT_SERIAL        // serial transfer complete
+	EVENT_PPU           // PPU mode change
+	EVENT_JOYPAD        // input poll
+	EVENT_COUNT
+)
+
+// event is a device clocked by the scheduler, and the time of its next event
+type event struct {
+	tick func(dots int)     // advances the device by the given number of dots
+	next func() (int, bool) // returns the dots until the device's next event, if any
+	at   int                // T-cycle the next event fires at
+	due  bool               // whether an event is scheduled
+	last int                // T-cycle the device was last advanced to
+}
+
+// scheduler keeps the console's time, in T-cycles (dots). Rather than ticking
+// every device on every cycle, devices with no work to do between events are
+// advanced lazily: only when their next event is due (e.g. the timer
+// overflowing, the PPU changing modes or a serial transfer completing), or
+// when the CPU accesses their registers. Each device is then brought up to
+// the current time in one call, and asked when its next event is.
+type scheduler struct {
+	now    int // T-cycles since power on
+	events [EVENT_COUNT]event
+}
+
+// newScheduler returns a scheduler with no devices attached
+func newScheduler() *scheduler {
+	return &scheduler{}
+}
+
+// attach attaches a device to the scheduler, advanced with the given 'tick'
+// function, and asking for its next event with the given 'next' function
+func (s *scheduler) attach(e int, tick func(dots int), next func() (int, bool)) {
+	s.events[e] = event{tick: tick, next: next, last: s.now}
+	s.sync(e)
+}
+
+// sync advances the given device to the current time, and schedules its next
+// event. Devices must be synced before their state is read or changed.
+func (s *scheduler) sync(e int) {
+	ev := &s.events[e]
+	if ev.tick == nil {
+		return
+	}
+
+	if dots := s.now - ev.last; dots > 0 {
+		ev.tick(dots)
+	}
+	ev.last = s.now
+
+	dots, ok := ev.next()
+	ev.at, ev.due = s.now+dots, ok
+}
+
+// advance advances the time by the given number of dots, firing the events
+// due in that time in order
+func (s *scheduler) advance(dots int) {
+	end := s.now + dots
+	for {
+		e := s.nextDue(end)
+		if e < 0 {
+			break
+		}
+		s.now = s.events[e].at
+		s.sync(e)
+	}
+	s.now = end
+}
+
+// nextDue returns the earliest event due by the given time, or -1 if there
+// is none
+func (s *scheduler) nextDue(end int) int {
+	next := -1
+	for e := range s.events {
+		ev := &s.events[e]
+		if ev.due && ev.at <= end && (next < 0 || ev.at < s.events[next].at) {
+			next = e
+		}
+	}
+	return next
+}
@@ -0,0 +1,85 @@
+package gb
+
+import "testing"
+
+// TestScheduler checks that devices are only advanced when their events are
+// due or they are synced, and that events fire in time order
+func TestScheduler(t *testing.T) {
+	s := newScheduler()
+	var fired []int
+	var ticked [EVENT_COUNT]int
+	for _, c := range []struct {
+		e      int
+		period int
+	}{
+		{EVENT_TIMER, 12},
+		{EVENT_SERIAL, 8},
+		{EVENT_PPU, 12},
+	} {
+		c := c
+		s.attach(c.e, func(dots int) {
+			ticked[c.e] += dots
+			if ticked[c.e]%c.period == 0 {
+				fired = append(fired, c.e)
+			}
+		}, func() (int, bool) {
+			return c.period - ticked[c.e]%c.period, true
+		})
+	}
+
+	s.advance(24)
+	want := []int{EVENT_SERIAL, EVENT_TIMER, EVENT_PPU, EVENT_SERIAL, EVENT_TIMER, EVENT_SERIAL, EVENT_PPU}
+	if len(fired) != len(want) {
+		t.Fatalf("events fired = %v, want %v", fired, want)
+	}
+	for i := range want {
+		if fired[i] != want[i] {
+			t.Fatalf("events fired = %v, want %v", fired, want)
+		}
+	}
+
+	s.advance(4)
+	if ticked[EVENT_TIMER] != 24 {
+		t.Errorf("timer advanced %d dots before its event, want 24", ticked[EVENT_TIMER])
+	}
+	s.sync(EVENT_TIMER)
+	if ticked[EVENT_TIMER] != 28 {
+		t.Errorf("timer advanced %d dots after syncing, want 28", ticked[EVENT_TIMER])
+	}
+}
+
+// TestRunAPIs checks the number of T-cycles run by 'Step', 'RunCycles' and
+// 'RunFrame'
+func TestRunAPIs(t *testing.T) {
+	gb := newTestGameBoy(t,
+		0x00,       // NOP
+		0x18, 0xFE, // JR -2
+	)
+
+	if got := gb.Step(); got != 4 {
+		t.Errorf("NOP took %d T-cycles, want 4", got)
+	}
+	if got := gb.RunCycles(100); got != 108 {
+		t.Errorf("RunCycles(100) ran %d T-cycles, want 108", got)
+	}
+
+	// The LCD is on after power up. Once in step with the PPU, each frame
+	// takes DOTS_PER_FRAME to within an instruction.
+	gb.RunFrame()
+	frames, start := gb.ppu.frames, gb.sched.now
+	gb.RunFrame()
+	if got := gb.ppu.frames - frames; got != 1 {
+		t.Errorf("RunFrame completed %d frames, want 1", got)
+	}
+	if got := gb.sched.now - start; got < DOTS_PER_FRAME-12 || got > DOTS_PER_FRAME+12 {
+		t.Errorf("RunFrame ran %d T-cycles, want %d", got, DOTS_PER_FRAME)
+	}
+
+	// With the LCD off, a frame's worth of T-cycles is run instead
+	gb.cpuWrite(IO_LCDC, 0x00)
+	start = gb.sched.now
+	gb.RunFrame()
+	if got := gb.sched.now - start; got < DOTS_PER_FRAME || got > DOTS_PER_FRAME+12 {
+		t.Errorf("RunFrame with the LCD off ran %d T-cycles, want %d", got, DOTS_PER_FRAME)
+	}
+}
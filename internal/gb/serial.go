@@ -0,0 +1,194 @@
+package gb
+
+import "sync"
+
+// SC bits
+const (
+	SC_CLOCK_SELECT = 1 << 0 // 0: external clock, 1: internal clock
+	SC_TRANSFER     = 1 << 7 // Set to start a transfer, cleared once complete
+)
+
+// SERIAL_BIT_CYCLES is the number of machine cycles taken to shift one bit
+// using the internal 8192 Hz clock
+const SERIAL_BIT_CYCLES = CYCLES_PER_SECOND / 8192
+
+// LinkPeer is the device at the other end of the link cable
+type LinkPeer interface {
+	// Transfer exchanges a byte with the peer, for a transfer clocked by
+	// this console. It is given the byte shifted out of SB, and returns the
+	// byte shifted in.
+	Transfer(out byte) (in byte)
+}
+
+// DisconnectedPeer is a LinkPeer for an empty link port. Every bit shifted in
+// reads 1.
+type DisconnectedPeer struct{}
+
+func (DisconnectedPeer) Transfer(out byte) byte {
+	return 0xFF
+}
+
+// CapturePeer is a disconnected LinkPeer which records every byte sent to it.
+// Test ROMs print their results this way.
+type CapturePeer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (p *CapturePeer) Transfer(out byte) byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.data = append(p.data, out)
+	return 0xFF
+}
+
+// Bytes returns the bytes sent so far
+func (p *CapturePeer) Bytes() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]byte(nil), p.data...)
+}
+
+// Len returns the number of bytes sent so far
+func (p *CapturePeer) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.data)
+}
+
+// String returns the bytes sent so far, as text
+func (p *CapturePeer) String() string {
+	return string(p.Bytes())
+}
+
+// serial is the Game Boy's serial port. Setting SC bit 7 starts a transfer:
+// the 8 bits of SB are shifted out to the link peer, most significant first,
+// while the peer's bits are shifted in. With the internal clock, this console
+// clocks the transfer at 8192 Hz. With the external clock, the transfer waits
+// for the peer to clock it. The serial interrupt is requested once all 8 bits
+// are shifted.
+//
+// reference: https://gbdev.io/pandocs/Serial_Data_Transfer_(Link_Cable).html
+type serial struct {
+	sb byte // serial transfer data
+	sc byte // serial transfer control
+
+	peer LinkPeer
+
+	in     byte // byte being shifted in from the peer
+	bits   int  // bits left to shift in the active transfer
+	cycles int  // machine cycles until the next bit is shifted
+
+	requestInterrupt func(interrupt)
+}
+
+// newSerial returns a disconnected serial port, requesting interrupts through
+// the given function
+func newSerial(requestInterrupt func(interrupt)) *serial {
+	return &serial{peer: DisconnectedPeer{}, requestInterrupt: requestInterrupt}
+}
+
+// transferring returns whether a transfer clocked by this console is active
+func (s *serial) transferring() bool {
+	return s.sc&SC_TRANSFER > 0 && s.sc&SC_CLOCK_SELECT > 0
+}
+
+// tick advances an internally clocked transfer by the given number of machine
+// cycles
+func (s *serial) tick(cycles int) {
+	for s.transferring() && cycles > 0 {
+		if cycles < s.cycles {
+			s.cycles -= cycles
+			return
+		}
+		cycles -= s.cycles
+		s.cycles = SERIAL_BIT_CYCLES
+		s.shift()
+	}
+}
+
+// next returns the number of machine cycles until an internally clocked
+// transfer completes. Bits shifted in between are caught up with when SB is
+// read.
+func (s *serial) next() (int, bool) {
+	if !s.transferring() {
+		return 0, false
+	}
+	return s.cycles + (s.bits-1)*SERIAL_BIT_CYCLES, true
+}
+
+// shift shifts one bit of the active transfer, completing it after the 8th
+func (s *serial) shift() {
+	s.bits--
+	s.sb = s.sb<<1 | (s.in>>s.bits)&1
+	if s.bits == 0 {
+		s.sc &^= SC_TRANSFER
+		s.requestInterrupt(INT_SERIAL)
+	}
+}
+
+// start starts an internally clocked transfer, exchanging SB with the peer
+func (s *serial) start() {
+	s.in = s.peer.Transfer(s.sb)
+	s.bits = 8
+	s.cycles = SERIAL_BIT_CYCLES
+}
+
+// resolve sets the byte shifted in by the active internally clocked transfer,
+// for peers which only learn it after the transfer has started. Bits already
+// shifted in are corrected.
+func (s *serial) resolve(in byte) {
+	if !s.transferring() {
+		return
+	}
+
+	shifted := 8 - s.bits
+	mask := byte(1<<shifted - 1)
+	s.sb = s.sb&^mask | (in>>s.bits)&mask
+	s.in = in
+}
+
+// clockExternal is called by a peer clocking a transfer, exchanging a whole
+// byte. Returns the byte shifted out, and false if this console has no
+// externally clocked transfer waiting, in which case nothing is exchanged.
+func (s *serial) clockExternal(in byte) (out byte, ok bool) {
+	if s.sc&SC_TRANSFER == 0 || s.sc&SC_CLOCK_SELECT > 0 {
+		return 0xFF, false
+	}
+
+	out = s.sb
+	s.in = in
+	s.bits = 8
+	for s.bits > 0 {
+		s.shift()
+	}
+	return out, true
+}
+
+// read reads from the serial register at the given address
+func (s *serial) read(addr uint16) byte {
+	switch addr {
+	case IO_SB:
+		return s.sb
+	case IO_SC:
+		// Bits 1-6 are unused, and always read as 1
+		return s.sc | 0x7E
+	}
+	return 0xFF
+}
+
+// write writes to the serial register at the given address
+func (s *serial) write(addr uint16, data byte) {
+	switch addr {
+	case IO_SB:
+		s.sb = data
+	case IO_SC:
+		s.sc = data & (SC_TRANSFER | SC_CLOCK_SELECT)
+		if s.transferring() {
+			s.start()
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package gb
+
+import "testing"
+
+// echoPeer is a LinkPeer which sends back the complement of each byte
+type echoPeer struct{}
+
+func (echoPeer) Transfer(out byte) byte {
+	return ^out
+}
+
+// TestSerialInternalClock checks that an internally clocked transfer shifts 8
+// bits at 8192 Hz, then requests the serial interrupt
+func TestSerialInternalClock(t *testing.T) {
+	var interrupts int
+	s := newSerial(func(i interrupt) { interrupts++ })
+	s.peer = echoPeer{}
+
+	s.write(IO_SB, 0x35)
+	s.write(IO_SC, SC_TRANSFER|SC_CLOCK_SELECT)
+
+	s.tick(8*SERIAL_BIT_CYCLES - 1)
+	if s.read(IO_SC)&SC_TRANSFER == 0 || interrupts != 0 {
+		t.Fatal("transfer completed early")
+	}
+	// The last bit of 0x35 remains, followed by the first 7 bits of 0xca
+	if got := s.read(IO_SB); got != 0xE5 {
+		t.Errorf("SB = %#02x after 7 bits, want 0xe5", got)
+	}
+
+	s.tick(1)
+	if s.read(IO_SC) != 0x7F || interrupts != 1 {
+		t.Errorf("SC = %#02x, %d interrupts after transfer, want 0x7f, 1", s.read(IO_SC), interrupts)
+	}
+	if got := s.read(IO_SB); got != 0xCA {
+		t.Errorf("SB = %#02x after transfer, want 0xca", got)
+	}
+}
+
+// TestSerialExternalClock checks that an externally clocked transfer waits for
+// the peer, and that disconnected peers shift in 0xFF
+func TestSerialExternalClock(t *testing.T) {
+	var interrupts int
+	s := newSerial(func(i interrupt) { interrupts++ })
+
+	if _, ok := s.clockExternal(0x12); ok {
+		t.Error("external clock exchanged a byte without a transfer waiting")
+	}
+
+	s.write(IO_SB, 0x42)
+	s.write(IO_SC, SC_TRANSFER)
+	s.tick(100 * SERIAL_BIT_CYCLES)
+	if s.read(IO_SC)&SC_TRANSFER == 0 || interrupts != 0 {
+		t.Fatal("externally clocked transfer completed without a clock")
+	}
+
+	out, ok := s.clockExternal(0x12)
+	if !ok || out != 0x42 || s.read(IO_SB) != 0x12 || interrupts != 1 {
+		t.Errorf("external transfer sent %#02x (%v), SB = %#02x, %d interrupts, want 0x42 (true), 0x12, 1",
+			out, ok, s.read(IO_SB), interrupts)
+	}
+
+	s.write(IO_SC, SC_TRANSFER|SC_CLOCK_SELECT)
+	s.tick(8 * SERIAL_BIT_CYCLES)
+	if got := s.read(IO_SB); got != 0xFF {
+		t.Errorf("SB = %#02x after disconnected transfer, want 0xff", got)
+	}
+}
+
+// TestCapturePeer checks that the capture peer records bytes sent by a ROM
+func TestCapturePeer(t *testing.T) {
+	peer := new(CapturePeer)
+	rom := newTestRom(
+		0x3E, 'o', // LD A,'o'
+		0xE0, 0x01, // LDH (SB),A
+		0x3E, 0x81, // LD A,0x81
+		0xE0, 0x02, // LDH (SC),A
+		0x3E, 'k', // LD A,'k'
+		0xE0, 0x01, // LDH (SB),A
+		0x3E, 0x81, // LD A,0x81
+		0xE0, 0x02, // LDH (SC),A
+	)
+	gb := newTestGameBoyFromRom(t, rom, WithLinkPeer(peer))
+
+	for i := 0; i < 8; i++ {
+		gb.Cpu.execNextInst()
+	}
+	if got := peer.String(); got != "ok" {
+		t.Errorf("captured %q, want \"ok\"", got)
+	}
+}
@@ -0,0 +1,175 @@
+package gb
+
+// TAC bits
+const (
+	TAC_CLOCK_SELECT = 0x03 // Selects the TIMA frequency, see timerBits
+	TAC_ENABLE       = 1 << 2
+)
+
+// APU_DIV_BIT is the bit of the internal counter (DIV bit 4) which steps the
+// APU's frame sequencer on its falling edge
+const APU_DIV_BIT = 1 << 12
+
+// timerBits maps TAC clock select values to the bit of the internal counter
+// which increments TIMA on its falling edge
+var timerBits = [4]uint16{
+	1 << 9, // 4096 Hz
+	1 << 3, // 262144 Hz
+	1 << 5, // 65536 Hz
+	1 << 7, // 16384 Hz
+}
+
+// timer is the Game Boy's timer. A 16-bit counter increments every T-cycle,
+// with its upper 8 bits visible as DIV. TIMA increments whenever the counter
+// bit selected by TAC (ANDed with the TAC enable bit) falls from 1 to 0, so
+// writes to DIV and TAC can increment TIMA too. When TIMA overflows, it reads
+// 0 for one machine cycle before being reloaded from TMA and requesting the
+// timer interrupt. Writing TIMA during that cycle cancels the reload.
+//
+// reference: https://gbdev.io/pandocs/Timer_Obscure_Behaviour.html
+type timer struct {
+	counter uint16 // internal counter, DIV is the upper 8 bits
+	tima    byte   // timer counter
+	tma     byte   // timer modulo
+	tac     byte   // timer control
+
+	overflowed bool // TIMA overflowed in the last machine cycle, reload pending
+	reloading  bool // TIMA was reloaded from TMA in the last machine cycle
+
+	requestInterrupt func(interrupt)
+	clockAPU         func() // called on falling edges of APU_DIV_BIT, if set
+}
+
+// newTimer returns a timer requesting interrupts through the given function
+func newTimer(requestInterrupt func(interrupt)) *timer {
+	return &timer{requestInterrupt: requestInterrupt}
+}
+
+// tick advances the timer by the given number of machine cycles
+func (t *timer) tick(cycles int) {
+	for cycles > 0 {
+		t.reloading = false
+		n := 1
+		if t.overflowed {
+			t.overflowed = false
+			t.reloading = true
+			t.tima = t.tma
+			t.requestInterrupt(INT_TIMER)
+		} else if n = t.untilTransition(); n > cycles {
+			n = cycles
+		}
+		t.setCounter(t.counter + uint16(n*4))
+		cycles -= n
+	}
+}
+
+// untilTransition returns the number of machine cycles until a counter bit
+// watched for falling edges next changes. The counter can skip ahead by as
+// much without missing an edge.
+func (t *timer) untilTransition() int {
+	n := cyclesUntilMultiple(t.counter, APU_DIV_BIT)
+	if t.tac&TAC_ENABLE > 0 {
+		if m := cyclesUntilMultiple(t.counter, timerBits[t.tac&TAC_CLOCK_SELECT]); m < n {
+			n = m
+		}
+	}
+	return n
+}
+
+// next returns the number of machine cycles until the timer next has to act
+// on time: to reload TIMA and request the timer interrupt, or to clock the
+// APU. TIMA increments in between are caught up with when it is read.
+func (t *timer) next() (int, bool) {
+	if t.overflowed {
+		return 1, true
+	}
+
+	n, ok := 0, false
+	if t.clockAPU != nil {
+		n, ok = cyclesUntilMultiple(t.counter, APU_DIV_BIT*2), true
+	}
+	if t.tac&TAC_ENABLE > 0 {
+		// Falling edges come every period, the first at the next multiple
+		period := timerBits[t.tac&TAC_CLOCK_SELECT] * 2
+		overflow := cyclesUntilMultiple(t.counter, period) + int(0xFF-t.tima)*int(period/4)
+		if !ok || overflow+1 < n {
+			n, ok = overflow+1, true
+		}
+	}
+	return n, ok
+}
+
+// cyclesUntilMultiple returns the number of machine cycles until the counter
+// next reaches a multiple of the given power of 2
+func cyclesUntilMultiple(counter, multiple uint16) int {
+	return int(multiple-counter%multiple) / 4
+}
+
+// input returns the signal TIMA is incremented on the falling edge of
+func (t *timer) input() bool {
+	return t.tac&TAC_ENABLE > 0 && t.counter&timerBits[t.tac&TAC_CLOCK_SELECT] > 0
+}
+
+// setCounter sets the internal counter, incrementing TIMA on a falling edge,
+// and clocking the APU on a falling edge of APU_DIV_BIT
+func (t *timer) setCounter(counter uint16) {
+	old, oldDiv := t.input(), t.counter&APU_DIV_BIT > 0
+	t.counter = counter
+	if old && !t.input() {
+		t.incrementTIMA()
+	}
+	if oldDiv && t.counter&APU_DIV_BIT == 0 && t.clockAPU != nil {
+		t.clockAPU()
+	}
+}
+
+// incrementTIMA increments TIMA, scheduling the reload from TMA on overflow
+func (t *timer) incrementTIMA() {
+	t.tima++
+	if t.tima == 0 {
+		t.overflowed = true
+	}
+}
+
+// read reads from the timer register at the given address
+func (t *timer) read(addr uint16) byte {
+	switch addr {
+	case IO_DIV:
+		return byte(t.counter >> 8)
+	case IO_TIMA:
+		return t.tima
+	case IO_TMA:
+		return t.tma
+	case IO_TAC:
+		// Upper 5 bits are unused, and always read as 1
+		return t.tac | 0xF8
+	}
+	return 0xFF
+}
+
+// write writes to the timer register at the given address
+func (t *timer) write(addr uint16, data byte) {
+	switch addr {
+	case IO_DIV:
+		// Any write resets the whole counter
+		t.setCounter(0)
+	case IO_TIMA:
+		// Writes in the reload cycle are overwritten by TMA, and writes in
+		// the cycle before cancel the reload
+		if !t.reloading {
+			t.tima = data
+			t.overflowed = false
+		}
+	case IO_TMA:
+		t.tma = data
+		if t.reloading {
+			t.tima = data
+		}
+	case IO_TAC:
+		old := t.input()
+		t.tac = data & 0x07
+		if old && !t.input() {
+			t.incrementTIMA()
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package gb
+
+import "testing"
+
+// newTestTimer returns a timer, and a count of the interrupts it has requested
+func newTestTimer() (*timer, *int) {
+	var interrupts int
+	t := newTimer(func(i interrupt) {
+		if i == INT_TIMER {
+			interrupts++
+		}
+	})
+	return t, &interrupts
+}
+
+// TestTimerFrequencies checks the number of machine cycles per TIMA increment
+// for each TAC clock select value
+func TestTimerFrequencies(t *testing.T) {
+	for tac, cycles := range []int{256, 4, 16, 64} {
+		timer, _ := newTestTimer()
+		timer.write(IO_TAC, TAC_ENABLE|byte(tac))
+
+		timer.tick(cycles - 1)
+		if timer.tima != 0 {
+			t.Errorf("TAC %d: TIMA = %d after %d cycles, want 0", tac, timer.tima, cycles-1)
+		}
+		timer.tick(1)
+		if timer.tima != 1 {
+			t.Errorf("TAC %d: TIMA = %d after %d cycles, want 1", tac, timer.tima, cycles)
+		}
+	}
+
+	timer, _ := newTestTimer()
+	timer.tick(256)
+	if got := timer.read(IO_DIV); got != 4 {
+		t.Errorf("DIV = %d after 256 cycles, want 4", got)
+	}
+}
+
+// TestTimerOverflow checks that TIMA is reloaded from TMA one machine cycle
+// after overflowing, and that writing TIMA in that cycle cancels the reload
+func TestTimerOverflow(t *testing.T) {
+	timer, interrupts := newTestTimer()
+	timer.write(IO_TMA, 0x42)
+	timer.write(IO_TIMA, 0xFF)
+	timer.write(IO_TAC, TAC_ENABLE|0x01)
+
+	timer.tick(4)
+	if timer.tima != 0x00 || *interrupts != 0 {
+		t.Errorf("TIMA = %#02x, %d interrupts after overflow, want 0x00, 0", timer.tima, *interrupts)
+	}
+	timer.tick(1)
+	if timer.tima != 0x42 || *interrupts != 1 {
+		t.Errorf("TIMA = %#02x, %d interrupts after reload, want 0x42, 1", timer.tima, *interrupts)
+	}
+
+	// Writes in the reload cycle are ignored
+	timer.write(IO_TIMA, 0x10)
+	if timer.tima != 0x42 {
+		t.Errorf("TIMA = %#02x after write in reload cycle, want 0x42", timer.tima)
+	}
+
+	// Writes in the overflow cycle cancel the reload
+	timer.write(IO_TIMA, 0xFF)
+	timer.tick(4)
+	timer.write(IO_TIMA, 0x10)
+	timer.tick(1)
+	if timer.tima != 0x10 || *interrupts != 1 {
+		t.Errorf("TIMA = %#02x, %d interrupts after cancelled reload, want 0x10, 1", timer.tima, *interrupts)
+	}
+}
+
+// TestTimerDIVWrite checks that resetting DIV increments TIMA when the
+// selected counter bit was set
+func TestTimerDIVWrite(t *testing.T) {
+	timer, _ := newTestTimer()
+	timer.write(IO_TAC, TAC_ENABLE|0x02) // bit 5
+
+	timer.tick(8) // counter = 32, bit 5 set
+	timer.write(IO_DIV, 0x00)
+	if timer.tima != 1 || timer.read(IO_DIV) != 0 {
+		t.Errorf("TIMA = %d, DIV = %d after DIV write, want 1, 0", timer.tima, timer.read(IO_DIV))
+	}
+
+	timer.tick(4) // counter = 16, bit 5 clear
+	timer.write(IO_DIV, 0x00)
+	if timer.tima != 1 {
+		t.Errorf("TIMA = %d after DIV write with bit clear, want 1", timer.tima)
+	}
+}
@@ -16,6 +16,12 @@ func halfCarryOccurs(b1, b2 byte) bool {
 	return ((n1 + n2) & 0x10) == 0x10
 }
 
+// halfBorrowOccurs returns whether a borrow from bit 4 occurs when subtracting
+// the second given byte from the first
+func halfBorrowOccurs(b1, b2 byte) bool {
+	return (b1 & 0xf) < (b2 & 0xf)
+}
+
 // halfCarryOccurs16 returns whether a carry occurs in the high byte, from bit
 // 11 to 12, when adding together the two given words. This function is used
 // when determining whether to set the half-carry flags for opcodes 0x09, 0x19,
@@ -24,8 +30,8 @@ func halfCarryOccurs(b1, b2 byte) bool {
 // ref: (https://newbedev.com/game-boy-half-carry-flag-and-16-bit-instructions-especially-opcode-0xe8)
 //	"ADD HL, rr: H from bit 11, C from bit 15 (flags from high byte op)"
 func halfCarryOccurs16(w1, w2 uint16) bool {
-	b1 := byte(w1 >> 8)
-	b2 := byte(w2 >> 8)
+	n1 := w1 & 0xfff
+	n2 := w2 & 0xfff
 
-	return halfCarryOccurs(b1, b2)
+	return ((n1 + n2) & 0x1000) == 0x1000
 }
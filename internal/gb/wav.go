@@ -0,0 +1,153 @@
+package gb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// WAV_HEADER_SIZE is the size of the RIFF, "fmt " and "data" chunk headers
+// preceding the samples in WAV files written by WAVWriter
+const WAV_HEADER_SIZE = 44
+
+// WAVWriter is an AudioSink writing samples to a 16-bit PCM stereo WAV file.
+// The chunk sizes in the header are filled in by 'Close'.
+//
+// reference: http://soundfile.sapp.org/doc/WaveFormat/
+type WAVWriter struct {
+	w          io.WriteSeeker
+	buf        *bufio.Writer
+	sampleRate int
+	samples    int   // number of stereo samples written
+	err        error // first write error, returned by 'Close'
+}
+
+// NewWAVWriter writes a WAV header for the given sample rate, in Hz, to the
+// given writer, and returns a WAVWriter writing samples after it
+func NewWAVWriter(w io.WriteSeeker, sampleRate int) (*WAVWriter, error) {
+	if sampleRate <= 0 {
+		sampleRate = DEFAULT_SAMPLE_RATE
+	}
+
+	wav := &WAVWriter{w: w, buf: bufio.NewWriter(w), sampleRate: sampleRate}
+	if _, err := wav.buf.Write(wav.header()); err != nil {
+		return nil, err
+	}
+	return wav, nil
+}
+
+// header returns the WAV header for the samples written so far
+func (wav *WAVWriter) header() []byte {
+	const channels, bitsPerSample = 2, 16
+	blockAlign := channels * bitsPerSample / 8
+	dataSize := uint32(wav.samples * blockAlign)
+
+	h := make([]byte, WAV_HEADER_SIZE)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], WAV_HEADER_SIZE-8+dataSize)
+	copy(h[8:], "WAVE")
+	copy(h[12:], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16) // fmt chunk size
+	binary.LittleEndian.PutUint16(h[20:], 1)  // PCM
+	binary.LittleEndian.PutUint16(h[22:], channels)
+	binary.LittleEndian.PutUint32(h[24:], uint32(wav.sampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(wav.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:], bitsPerSample)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], dataSize)
+	return h
+}
+
+// WriteSample writes a stereo sample, clipped to the range -1 to 1
+func (wav *WAVWriter) WriteSample(left, right float32) {
+	if wav.err != nil {
+		return
+	}
+
+	var frame [4]byte
+	binary.LittleEndian.PutUint16(frame[0:], uint16(pcm16(left)))
+	binary.LittleEndian.PutUint16(frame[2:], uint16(pcm16(right)))
+	if _, err := wav.buf.Write(frame[:]); err != nil {
+		wav.err = err
+		return
+	}
+	wav.samples++
+}
+
+// pcm16 converts a sample from -1 to 1 to a 16-bit PCM value
+func pcm16(sample float32) int16 {
+	if sample > 1 {
+		sample = 1
+	} else if sample < -1 {
+		sample = -1
+	}
+	return int16(sample * 32767)
+}
+
+// Close flushes the samples written, and fills in the header's chunk sizes. It
+// does not close the underlying writer.
+func (wav *WAVWriter) Close() error {
+	if wav.err != nil {
+		return wav.err
+	}
+	if err := wav.buf.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := wav.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := wav.w.Write(wav.header()); err != nil {
+		return err
+	}
+	_, err := wav.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// RecordWAV powers on the console and runs it headlessly for the given
+// duration of emulated time, recording its audio to a 16-bit PCM stereo WAV
+// file at the given path. Use 'WithAudioChannels' to record only some of the
+// sound channels.
+func (gb *GameBoy) RecordWAV(path string, duration time.Duration, sampleRate int) error {
+	gb.initPowerUpSequence()
+	return recordWAV(path, duration, sampleRate, gb.mmu.apu, gb.run)
+}
+
+// recordWAV records the audio produced by the given APU to a WAV file at the
+// given path, while 'run' runs its console for the given duration of
+// emulated time
+func recordWAV(path string, duration time.Duration, sampleRate int, apu *apu, run func(cycles int)) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create WAV file: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("could not write WAV file: %w", closeErr)
+		}
+	}()
+
+	wav, err := NewWAVWriter(f, sampleRate)
+	if err != nil {
+		return fmt.Errorf("could not write WAV file: %w", err)
+	}
+	apu.setSink(wav, wav.sampleRate)
+	defer apu.setSink(nil, 0)
+
+	run(int(math.Ceil(duration.Seconds() * CYCLES_PER_SECOND)))
+
+	if err := wav.Close(); err != nil {
+		return fmt.Errorf("could not write WAV file: %w", err)
+	}
+	return nil
+}
+
+// run runs the console for at least the given number of machine cycles
+func (gb *GameBoy) run(cycles int) {
+	gb.RunCycles(cycles * 4)
+}
@@ -0,0 +1,103 @@
+package gb
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWAVWriter checks the header and samples written by WAVWriter
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	wav, err := NewWAVWriter(f, 22050)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wav.WriteSample(0, 1)
+	wav.WriteSample(-2, 0.5)
+	if err := wav.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != WAV_HEADER_SIZE+8 {
+		t.Fatalf("file size = %d, want %d", len(data), WAV_HEADER_SIZE+8)
+	}
+	u16 := func(i int) uint16 { return binary.LittleEndian.Uint16(data[i:]) }
+	u32 := func(i int) uint32 { return binary.LittleEndian.Uint32(data[i:]) }
+	for _, c := range []struct {
+		name      string
+		got, want uint32
+	}{
+		{"RIFF size", u32(4), 36 + 8},
+		{"format", uint32(u16(20)), 1},
+		{"channels", uint32(u16(22)), 2},
+		{"sample rate", u32(24), 22050},
+		{"byte rate", u32(28), 22050 * 4},
+		{"bits per sample", uint32(u16(34)), 16},
+		{"data size", u32(40), 8},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %d, want %d", c.name, c.got, c.want)
+		}
+	}
+
+	want := []int16{0, 32767, -32767, 16383}
+	for i, w := range want {
+		if got := int16(u16(WAV_HEADER_SIZE + i*2)); got != w {
+			t.Errorf("sample value %d = %d, want %d", i, got, w)
+		}
+	}
+}
+
+// TestRecordWAV checks that recording only some sound channels leaves the
+// others out of the recording
+func TestRecordWAV(t *testing.T) {
+	rom := newTestRom(
+		0x3E, 0x00, 0xE0, 0x12, // LD A,0x00; LDH (NR12),A
+		0x3E, 0xF0, 0xE0, 0x17, // LD A,0xF0; LDH (NR22),A
+		0x3E, 0x80, 0xE0, 0x16, // LD A,0x80; LDH (NR21),A
+		0x3E, 0x87, 0xE0, 0x19, // LD A,0x87; LDH (NR24),A
+		0x18, 0xFE, // JR -2
+	)
+
+	for _, c := range []struct {
+		channels []int
+		silent   bool
+	}{
+		{[]int{2}, false},
+		{[]int{1, 3, 4}, true},
+	} {
+		gb := newTestGameBoyFromRom(t, rom, WithAudioChannels(c.channels...))
+		path := filepath.Join(t.TempDir(), "test.wav")
+		if err := gb.RecordWAV(path, 100*time.Millisecond, 8000); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(data), WAV_HEADER_SIZE+800*4; got != want {
+			t.Errorf("channels %v: file size = %d, want %d", c.channels, got, want)
+		}
+		silent := true
+		for _, b := range data[WAV_HEADER_SIZE:] {
+			silent = silent && b == 0
+		}
+		if silent != c.silent {
+			t.Errorf("channels %v: silent = %v, want %v", c.channels, silent, c.silent)
+		}
+	}
+}